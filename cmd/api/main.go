@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"time"
 )
 
 func main() {
@@ -16,6 +17,14 @@ func main() {
 		panic(err)
 	}
 
+	err = sqlconnect.BuildSearchIndex()
+	if err != nil {
+		panic(err)
+	}
+
+	go sqlconnect.PurgeTrashPeriodically(time.Hour)
+	go sqlconnect.SendAnnouncementsPeriodically(time.Minute)
+
 	//rl := mw.NewRateLimiter(5, time.Minute)
 	//hpp := mw.HPPOptions{
 	//	CheckQuery:          true,
@@ -24,13 +33,19 @@ func main() {
 	//	Whitelist:           []string{"sortBy", "sortOrder", "class", "age", "name"},
 	//}
 
-	jwtMiddleware := mw.MiddlewaresExcludeRoute(mw.JWTMiddleware, "/execs/login", "/execs/forgotpassword", "/execs/resetpassword/reset")
-	secureMux := jwtMiddleware(mw.SecurityHeaders(router.MainRouter()))
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"))
+	if err != nil {
+		idempotencyTTL = 24 * time.Hour
+	}
+	idempotency := mw.MiddlewaresExcludeRoute(mw.NewIdempotencyStore(idempotencyTTL).Middleware, "/execs/login")
+
+	jwtMiddleware := mw.MiddlewaresExcludeRoute(mw.JWTMiddleware, "/execs/login", "/execs/forgotpassword", "/execs/resetpassword/reset", "/calendar/", "/files/")
+	secureMux := jwtMiddleware(mw.ParentScope(idempotency(mw.SecurityHeaders(router.MainRouter()))))
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%s", os.Getenv("API_PORT")),
 		Handler: secureMux,
 	}
 
-	fmt.Printf("Starting server on port %d\n", os.Getenv("API_PORT"))
+	fmt.Printf("Starting server on port %s\n", os.Getenv("API_PORT"))
 	server.ListenAndServe()
 }
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func GetAcademicYearsHandler(w http.ResponseWriter, r *http.Request) {
+	years, page, err := sqlc.GetAllAcademicYears(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.AcademicYear{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = years
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(years))
+		for _, y := range years {
+			projected = append(projected, utils.ProjectFields(y, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(years),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	year, err := sqlc.FindAcademicYearById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(year.Version))
+	json.NewEncoder(w).Encode(year)
+}
+
+func AddAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedYears, err := sqlc.SaveAcademicYears(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.AcademicYear `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedYears),
+		Data:   addedYears,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	year, err := sqlc.PatchAcademicYearById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(year.Version))
+	json.NewEncoder(w).Encode(year)
+}
+
+func DeleteAcademicYearHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteAcademicYearById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetTermsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	terms, err := sqlc.GetTerms(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeTerms(w, terms)
+}
+
+// ReplaceTermsHandler — четверти учебного года целиком: массив {number, name, startDate, endDate}
+func ReplaceTermsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var terms []mod.Term
+	err = json.NewDecoder(r.Body).Decode(&terms)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	terms, err = sqlc.ReplaceTerms(id, terms)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeTerms(w, terms)
+}
+
+func writeTerms(w http.ResponseWriter, terms []mod.Term) {
+	response := struct {
+		Status string     `json:"status"`
+		Count  int        `json:"count"`
+		Data   []mod.Term `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(terms),
+		Data:   terms,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetYearEnrollmentsHandler — зачисления учебного года; ?classId= и ?status= сужают список
+func GetYearEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var classID *int
+	if v := r.URL.Query().Get("classId"); v != "" {
+		c, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid classId", http.StatusBadRequest)
+			return
+		}
+		classID = &c
+	}
+
+	enrollments, err := sqlc.GetYearEnrollments(id, classID, r.URL.Query().Get("status"))
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeEnrollments(w, enrollments)
+}
+
+// GetStudentEnrollmentsHandler — история классов студента по учебным годам
+func GetStudentEnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	enrollments, err := sqlc.GetStudentEnrollments(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeEnrollments(w, enrollments)
+}
+
+func writeEnrollments(w http.ResponseWriter, enrollments []mod.Enrollment) {
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []mod.Enrollment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(enrollments),
+		Data:   enrollments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PromoteStudentsHandler — перевод студентов в учебный год {id}; тело {fromYearId, retain, finalGrade} необязательно.
+// ?dryRun=true возвращает разницу без изменений
+func PromoteStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req mod.PromotionRequest
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := sqlc.PromoteStudents(r.Context(), id, req, r.URL.Query().Get("dryRun") == "true")
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Data   mod.PromotionResult `json:"data"`
+	}{
+		Status: "success",
+		Data:   result,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
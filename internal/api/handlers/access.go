@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"errors"
+	"net/http"
+)
+
+var errNoUser = errors.New("no user in token")
+
+// requestUserID — ID учётной записи из JWT (в claims число приходит как float64)
+func requestUserID(r *http.Request) (int, error) {
+	id, ok := r.Context().Value(utils.ContextKey("userId")).(float64)
+	if !ok {
+		return 0, errNoUser
+	}
+	return int(id), nil
+}
+
+// requestTeacher — ограничение доступа к журналу: nil для admin и manager, ID карточки учителя для роли teacher
+func requestTeacher(r *http.Request) (*int, error) {
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	_, err := utils.AuthorizeUser(role, "admin", "manager")
+	if err == nil {
+		return nil, nil
+	}
+	_, err = utils.AuthorizeUser(role, "teacher")
+	if err != nil {
+		return nil, err
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		return nil, err
+	}
+	teacherID, err := sqlc.TeacherIdForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	return &teacherID, nil
+}
+
+// authorizeTeacher — requestTeacher с ответом клиенту: 401 для чужих ролей, 403 для учётной записи без карточки учителя
+func authorizeTeacher(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	teacherID, err := requestTeacher(r)
+	if err != nil {
+		if errors.Is(err, utils.ErrForbidden) {
+			writeError(w, err, http.StatusForbidden)
+		} else {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		}
+		return nil, false
+	}
+	return teacherID, true
+}
+
+// authorizeGuardian — ID карточки опекуна для роли parent: 401 для чужих ролей, 403 для учётной записи без опекуна
+func authorizeGuardian(w http.ResponseWriter, r *http.Request) (int, bool) {
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	_, err := utils.AuthorizeUser(role, "parent")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	guardianID, err := sqlc.GuardianIdForUser(userID)
+	if err != nil {
+		writeError(w, err, http.StatusForbidden)
+		return 0, false
+	}
+	return guardianID, true
+}
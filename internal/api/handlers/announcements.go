@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// GetAnnouncementsHandler — доска объявлений: admin и manager видят все объявления, остальные — адресованные им и действующие
+func GetAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	execID, all, ok := announcementReader(w, r)
+	if !ok {
+		return
+	}
+	announcementsHandler(w, r, execID, all)
+}
+
+func GetAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	execID, all, ok := announcementReader(w, r)
+	if !ok {
+		return
+	}
+	announcementHandler(w, r, execID, all)
+}
+
+// MarkAnnouncementReadHandler — POST /announcements/{id}/read: отметка о прочтении текущим пользователем
+func MarkAnnouncementReadHandler(w http.ResponseWriter, r *http.Request) {
+	execID, all, ok := announcementReader(w, r)
+	if !ok {
+		return
+	}
+	markAnnouncementReadHandler(w, r, execID, all)
+}
+
+// GetParentAnnouncementsHandler — объявления для родителя: вся школа и классы его детей
+func GetParentAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	execID, ok := parentReader(w, r)
+	if !ok {
+		return
+	}
+	announcementsHandler(w, r, execID, false)
+}
+
+func GetParentAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	execID, ok := parentReader(w, r)
+	if !ok {
+		return
+	}
+	announcementHandler(w, r, execID, false)
+}
+
+func MarkParentAnnouncementReadHandler(w http.ResponseWriter, r *http.Request) {
+	execID, ok := parentReader(w, r)
+	if !ok {
+		return
+	}
+	markAnnouncementReadHandler(w, r, execID, false)
+}
+
+func announcementsHandler(w http.ResponseWriter, r *http.Request, execID int, all bool) {
+	announcements, page, err := sqlc.GetAnnouncements(r, execID, all)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Announcement{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = announcements
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(announcements))
+		for _, a := range announcements {
+			projected = append(projected, utils.ProjectFields(a, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(announcements),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func announcementHandler(w http.ResponseWriter, r *http.Request, execID int, all bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := sqlc.FindAnnouncement(id, execID, all)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(announcement.Version))
+	json.NewEncoder(w).Encode(announcement)
+}
+
+func markAnnouncementReadHandler(w http.ResponseWriter, r *http.Request, execID int, all bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	announcement, err := sqlc.MarkAnnouncementRead(id, execID, all)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(announcement)
+}
+
+// AddAnnouncementsHandler — объявления публикуют администрация и менеджеры
+func AddAnnouncementsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedAnnouncements, err := sqlc.SaveAnnouncements(r, userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.Announcement `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedAnnouncements),
+		Data:   addedAnnouncements,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	announcement, err := sqlc.PatchAnnouncementById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(announcement.Version))
+	json.NewEncoder(w).Encode(announcement)
+}
+
+func DeleteAnnouncementHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteAnnouncementById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAnnouncementReceiptsHandler — GET /announcements/{id}/reads: размер аудитории и кто прочитал
+func GetAnnouncementReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	receipts, err := sqlc.GetAnnouncementReceipts(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Data   mod.AnnouncementReceipts `json:"data"`
+	}{
+		Status: "success",
+		Data:   receipts,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// announcementReader — ID учётной записи для отметок о прочтении; all — admin и manager видят все объявления
+func announcementReader(w http.ResponseWriter, r *http.Request) (int, bool, bool) {
+	userID, err := requestUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false, false
+	}
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	_, err = utils.AuthorizeUser(role, "admin", "manager")
+	return userID, err == nil, true
+}
+
+// parentReader — ID учётной записи родителя, связанной с карточкой опекуна
+func parentReader(w http.ResponseWriter, r *http.Request) (int, bool) {
+	_, ok := authorizeGuardian(w, r)
+	if !ok {
+		return 0, false
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, false
+	}
+	return userID, true
+}
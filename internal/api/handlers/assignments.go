@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func GetAssignmentsHandler(w http.ResponseWriter, r *http.Request) {
+	assignments, page, err := sqlc.GetAllAssignments(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Assignment{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = assignments
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(assignments))
+		for _, a := range assignments {
+			projected = append(projected, utils.ProjectFields(a, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assignments),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	assignment, err := sqlc.FindAssignmentById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(assignment.Version))
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func AddAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	added, err := sqlc.SaveAssignments(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []mod.Assignment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(added),
+		Data:   added,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	assignment, err := sqlc.PatchAssignmentById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(assignment.Version))
+	json.NewEncoder(w).Encode(assignment)
+}
+
+func DeleteAssignmentHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteAssignmentById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
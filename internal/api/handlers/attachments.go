@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/internal/storage"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GetStudentAttachmentsHandler — GET /students/{id}/attachments?kind=photo|document
+func GetStudentAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ownerAttachmentsHandler(w, r, "student")
+}
+
+// AddStudentAttachmentHandler — POST /students/{id}/attachments: multipart поля file и kind
+func AddStudentAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	addAttachmentHandler(w, r, "student")
+}
+
+func GetTeacherAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ownerAttachmentsHandler(w, r, "teacher")
+}
+
+func AddTeacherAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	addAttachmentHandler(w, r, "teacher")
+}
+
+func GetExecAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	ownerAttachmentsHandler(w, r, "exec")
+}
+
+func AddExecAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	addAttachmentHandler(w, r, "exec")
+}
+
+func ownerAttachmentsHandler(w http.ResponseWriter, r *http.Request, ownerType string) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeAttachments(w, r, ownerType, id, false) {
+		return
+	}
+
+	attachments, err := sqlc.GetAttachments(ownerType, id, r.URL.Query().Get("kind"))
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []mod.Attachment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(attachments),
+		Data:   attachments,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func addAttachmentHandler(w http.ResponseWriter, r *http.Request, ownerType string) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	if !authorizeAttachments(w, r, ownerType, id, true) {
+		return
+	}
+
+	file, closer, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	defer closer.Close()
+
+	var uploadedBy *int
+	if userID, err := requestUserID(r); err == nil {
+		uploadedBy = &userID
+	}
+	attachment, err := sqlc.SaveAttachment(ownerType, id, r.FormValue("kind"), file, uploadedBy)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Data   mod.Attachment `json:"data"`
+	}{
+		Status: "success",
+		Data:   attachment,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAttachmentHandler — GET /attachments/{id}: сведения о файле
+func GetAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := authorizedAttachment(w, r, false)
+	if !ok {
+		return
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Data   mod.Attachment `json:"data"`
+	}{
+		Status: "success",
+		Data:   attachment,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetAttachmentContentHandler — GET /attachments/{id}/content: содержимое файла
+func GetAttachmentContentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := authorizedAttachment(w, r, false)
+	if !ok {
+		return
+	}
+	serveStoredFile(w, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size)
+}
+
+// CreateAttachmentURLHandler — POST /attachments/{id}/url?ttl=1h: ссылка на скачивание без токена
+func CreateAttachmentURLHandler(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := authorizedAttachment(w, r, false)
+	if !ok {
+		return
+	}
+	var ttl time.Duration
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		var err error
+		ttl, err = time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "ttl must be a duration such as 15m or 24h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	link, err := sqlc.AttachmentURL(attachment.ID, ttl)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Data   mod.SignedURL `json:"data"`
+	}{
+		Status: "success",
+		Data:   link,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	attachment, ok := authorizedAttachment(w, r, true)
+	if !ok {
+		return
+	}
+
+	err := sqlc.DeleteAttachment(attachment.ID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetSignedFileHandler — GET /files/{id}?expires=...&signature=...: скачивание по подписанной ссылке, без JWT
+func GetSignedFileHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := sqlc.FindSignedAttachment(id, r.URL.Query().Get("expires"), r.URL.Query().Get("signature"))
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-store")
+	serveStoredFile(w, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size)
+}
+
+// authorizedAttachment — вложение из пути /attachments/{id}, если у пользователя есть доступ к его владельцу
+func authorizedAttachment(w http.ResponseWriter, r *http.Request, write bool) (mod.Attachment, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return mod.Attachment{}, false
+	}
+	attachment, err := sqlc.FindAttachment(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return mod.Attachment{}, false
+	}
+	if !authorizeAttachments(w, r, attachment.OwnerType, attachment.OwnerID, write) {
+		return mod.Attachment{}, false
+	}
+	return attachment, true
+}
+
+// authorizeAttachments — доступ к файлам владельца: учётной записи — admin и она сама,
+// учителя — admin, manager и сам учитель, студента — admin и manager, а на чтение ещё и его учителя
+func authorizeAttachments(w http.ResponseWriter, r *http.Request, ownerType string, ownerID int, write bool) bool {
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	userID, err := requestUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+
+	switch ownerType {
+	case "exec":
+		if _, err := utils.AuthorizeUser(role, "admin"); err == nil || userID == ownerID {
+			return true
+		}
+	case "teacher":
+		if _, err := utils.AuthorizeUser(role, "admin", "manager"); err == nil {
+			return true
+		}
+		if _, err := utils.AuthorizeUser(role, "teacher"); err == nil {
+			teacherID, err := sqlc.TeacherIdForUser(userID)
+			if err == nil && teacherID == ownerID {
+				return true
+			}
+		}
+	case "student":
+		teacherID, ok := authorizeTeacher(w, r)
+		if !ok {
+			return false
+		}
+		if teacherID == nil {
+			return true
+		}
+		if !write {
+			err = sqlc.CheckTeachesStudent(ownerID, teacherID)
+			if err != nil {
+				writeError(w, err, http.StatusInternalServerError)
+				return false
+			}
+			return true
+		}
+	}
+	writeError(w, utils.ErrorHandler(utils.ErrForbidden, "No access to these attachments"), http.StatusForbidden)
+	return false
+}
+
+// readUpload — файл из multipart поля file не больше storage.MaxFileSize (с запасом на заголовки формы);
+// closer закрывает файл после сохранения
+func readUpload(w http.ResponseWriter, r *http.Request) (storage.File, io.Closer, error) {
+	maxSize := storage.MaxFileSize()
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return storage.File{}, nil, utils.ErrorHandler(utils.ErrFileTooLarge, fmt.Sprintf("File is larger than %d MB", maxSize>>20))
+		}
+		return storage.File{}, nil, fmt.Errorf("missing file field: %w", err)
+	}
+	return storage.File{Name: header.Filename, ContentType: partContentType(header), Body: file}, file, nil
+}
+
+func partContentType(header *multipart.FileHeader) string {
+	mediaType, _, err := mime.ParseMediaType(header.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// serveStoredFile — отдаёт файл из хранилища с исходным именем; изображения показываются в браузере, остальное скачивается
+func serveStoredFile(w http.ResponseWriter, key, fileName, contentType string, size int64) {
+	f, err := storage.Default().Open(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "File not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Error reading file", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") && contentType != "image/svg+xml" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	io.Copy(w, f)
+}
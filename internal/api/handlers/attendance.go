@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultAbsenceThreshold — порог доли пропусков в процентах для /attendance/absences без ?threshold=
+const defaultAbsenceThreshold = 10
+
+// SubmitClassAttendanceHandler — отметка всего класса за день (?date=, по умолчанию сегодня): массив {studentId, status, reason}
+func SubmitClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var entries []mod.AttendanceEntry
+	err = json.NewDecoder(r.Body).Decode(&entries)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var userID *int
+	if uid, err := requestUserID(r); err == nil {
+		userID = &uid
+	}
+	records, err := sqlc.SubmitClassAttendance(r.Context(), id, dateParam(r), entries, teacherID, userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeAttendance(w, records)
+}
+
+// GetClassAttendanceHandler — отметки класса за день (?date=, по умолчанию сегодня)
+func GetClassAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	records, err := sqlc.GetClassAttendance(id, dateParam(r), teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeAttendance(w, records)
+}
+
+func writeAttendance(w http.ResponseWriter, records []mod.Attendance) {
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []mod.Attendance `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(records),
+		Data:   records,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetClassAttendanceSummaryHandler — посещаемость класса в процентах за период (?from=, ?to=)
+func GetClassAttendanceSummaryHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	summary, err := sqlc.GetClassAttendanceSummary(id, from, to, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Data   mod.ClassAttendance `json:"data"`
+	}{
+		Status: "success",
+		Data:   summary,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetStudentAttendanceHandler — посещаемость студента за период (?from=, ?to=)
+func GetStudentAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	attendance, err := sqlc.GetStudentAttendance(id, from, to, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Data   mod.StudentAttendance `json:"data"`
+	}{
+		Status: "success",
+		Data:   attendance,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAttendanceListHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	records, page, err := sqlc.GetAllAttendance(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Attendance{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = records
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(records))
+		for _, a := range records {
+			projected = append(projected, utils.ProjectFields(a, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(records),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	record, err := sqlc.FindAttendanceById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(record.Version))
+	json.NewEncoder(w).Encode(record)
+}
+
+// PatchAttendanceHandler — исправление отметки администрацией (status, reason)
+func PatchAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	var userID *int
+	if uid, err := requestUserID(r); err == nil {
+		userID = &uid
+	}
+	record, err := sqlc.PatchAttendanceById(r.Context(), id, patch, expectedVersion, userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(record.Version))
+	json.NewEncoder(w).Encode(record)
+}
+
+// GetAbsencesHandler — студенты с долей пропусков не ниже ?threshold= процентов за период (?from=, ?to=, ?classId=)
+func GetAbsencesHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	threshold := float64(defaultAbsenceThreshold)
+	if param := r.URL.Query().Get("threshold"); param != "" {
+		threshold, err = strconv.ParseFloat(param, 64)
+		if err != nil || threshold < 0 || threshold > 100 {
+			writeError(w, utils.ErrorHandler(utils.ErrInvalidFilter, "threshold must be a percentage from 0 to 100"), http.StatusBadRequest)
+			return
+		}
+	}
+	classID := 0
+	if param := r.URL.Query().Get("classId"); param != "" {
+		classID, err = strconv.Atoi(param)
+		if err != nil {
+			writeError(w, utils.ErrorHandler(utils.ErrInvalidFilter, "Invalid classId "+param), http.StatusBadRequest)
+			return
+		}
+	}
+
+	students, err := sqlc.GetAbsenceList(from, to, threshold, classID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status    string                  `json:"status"`
+		Count     int                     `json:"count"`
+		From      string                  `json:"from"`
+		To        string                  `json:"to"`
+		Threshold float64                 `json:"threshold"`
+		Data      []mod.AttendanceSummary `json:"data"`
+	}{
+		Status:    "success",
+		Count:     len(students),
+		From:      from,
+		To:        to,
+		Threshold: threshold,
+		Data:      students,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// dateParam — ?date= как есть (проверяется в sqlconnect); без параметра — сегодня
+func dateParam(r *http.Request) string {
+	if date := r.URL.Query().Get("date"); date != "" {
+		return date
+	}
+	return time.Now().Format(time.DateOnly)
+}
+
+// dateRangeParams — период ?from=&to= (YYYY-MM-DD); по умолчанию с 1 сентября текущего учебного года по сегодня
+func dateRangeParams(r *http.Request) (string, string, error) {
+	now := time.Now()
+	year := now.Year()
+	if now.Month() < time.September {
+		year--
+	}
+	from := time.Date(year, time.September, 1, 0, 0, 0, 0, time.Local).Format(time.DateOnly)
+	to := now.Format(time.DateOnly)
+
+	for _, p := range []struct {
+		name  string
+		value *string
+	}{{"from", &from}, {"to", &to}} {
+		raw := r.URL.Query().Get(p.name)
+		if raw == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, raw); err != nil {
+			return "", "", utils.ErrorHandler(utils.ErrInvalidFilter, p.name+" must be YYYY-MM-DD")
+		}
+		*p.value = raw
+	}
+	if from > to {
+		return "", "", utils.ErrorHandler(utils.ErrInvalidFilter, "from must not be after to")
+	}
+	return from, to, nil
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetCalendarHandler — публичная подписка на расписание /calendar/{token}.ics; доступ по токену, без JWT
+func GetCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), ".ics")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	name, events, err := sqlc.CalendarForToken(token)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	err = utils.WriteICal(&buf, name, events)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="timetable.ics"`)
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write(buf.Bytes())
+}
+
+func AddTeacherFeedHandler(w http.ResponseWriter, r *http.Request) {
+	addFeedHandler(w, r, sqlc.CreateTeacherFeed)
+}
+
+func AddClassFeedHandler(w http.ResponseWriter, r *http.Request) {
+	addFeedHandler(w, r, sqlc.CreateClassFeed)
+}
+
+// addFeedHandler — новая ссылка на календарь; токен виден только в этом ответе
+func addFeedHandler(w http.ResponseWriter, r *http.Request, create func(id int, requester *int, createdBy *int) (mod.CalendarFeed, error)) {
+	requester, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var createdBy *int
+	if uid, err := requestUserID(r); err == nil {
+		createdBy = &uid
+	}
+	feed, err := create(id, requester, createdBy)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Data   mod.CalendarFeed `json:"data"`
+	}{
+		Status: "success",
+		Data:   feed,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetTeacherFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	feedsHandler(w, r, sqlc.GetTeacherFeeds)
+}
+
+func GetClassFeedsHandler(w http.ResponseWriter, r *http.Request) {
+	feedsHandler(w, r, sqlc.GetClassFeeds)
+}
+
+func feedsHandler(w http.ResponseWriter, r *http.Request, get func(id int, requester *int) ([]mod.CalendarFeed, error)) {
+	requester, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	feeds, err := get(id, requester)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.CalendarFeed `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(feeds),
+		Data:   feeds,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeCalendarFeedHandler — отзыв ссылки на календарь
+func RevokeCalendarFeedHandler(w http.ResponseWriter, r *http.Request) {
+	requester, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.RevokeCalendarFeed(id, requester)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	holidays, page, err := sqlc.GetAllHolidays(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   []mod.Holiday   `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(holidays),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   holidays,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func AddHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedHolidays, err := sqlc.SaveHolidays(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []mod.Holiday `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedHolidays),
+		Data:   addedHolidays,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteHolidayById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func GetClassesHandler(w http.ResponseWriter, r *http.Request) {
+	classList, page, err := sqlc.GetAllClasses(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Class{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = classList
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(classList))
+		for _, c := range classList {
+			projected = append(projected, utils.ProjectFields(c, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(classList),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	class, err := sqlc.FindClassById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(class.Version))
+	json.NewEncoder(w).Encode(class)
+}
+
+func AddClassHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedClasses, err := sqlc.SaveClasses(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string      `json:"status"`
+		Count  int         `json:"count"`
+		Data   []mod.Class `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedClasses),
+		Data:   addedClasses,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchClassHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	class, err := sqlc.PatchClassById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(class.Version))
+	json.NewEncoder(w).Encode(class)
+}
+
+func DeleteClassHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteClassById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetStudentsByClassHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	students, err := sqlc.FindStudentsByClassId(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   []mod.Student `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(students),
+		Data:   students,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// writeError — отправляет ошибку клиенту; ошибки валидации уходят в JSON с перечнем полей
+func writeError(w http.ResponseWriter, err error, status int) {
+	var validationErr *utils.ValidationError
+	if errors.As(err, &validationErr) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		response := struct {
+			Status string             `json:"status"`
+			Errors []utils.FieldError `json:"errors"`
+		}{
+			Status: "fail",
+			Errors: validationErr.Errors,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+	status, _ = utils.ErrorStatus(err, status)
+	http.Error(w, err.Error(), status)
+}
+
+// writeBulkResults — ответ 207 Multi-Status для bulk-запроса в режиме mode=partial
+func writeBulkResults(w http.ResponseWriter, results []utils.BulkItemResult) {
+	succeeded := 0
+	for _, res := range results {
+		if res.Status < http.StatusBadRequest {
+			succeeded++
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusMultiStatus)
+	response := struct {
+		Status    string                 `json:"status"`
+		Succeeded int                    `json:"succeeded"`
+		Failed    int                    `json:"failed"`
+		Results   []utils.BulkItemResult `json:"results"`
+	}{
+		Status:    "partial",
+		Succeeded: succeeded,
+		Failed:    len(results) - succeeded,
+		Results:   results,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -15,18 +15,23 @@ import (
 
 func GetExecsHandler(w http.ResponseWriter, r *http.Request) {
 
-	ExecList, err := sqlc.GetAllExecs(r)
+	ExecList, page, err := sqlc.GetAllExecs(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	response := struct {
-		Status string        `json:"status"`
-		Count  int           `json:"count"`
-		Data   []models.Exec `json:"data"`
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   []models.Exec   `json:"data"`
 	}{
 		Status: "success",
 		Count:  len(ExecList),
+		Total:  page.Total,
+		Links:  page.Links,
 		Data:   ExecList,
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -43,14 +48,27 @@ func GetExecHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+	asOf, historical, err := utils.ParseAsOf(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
 	var exec models.Exec
 
-	exec, err = sqlc.FindExecById(err, id, exec)
+	if historical {
+		exec, err = sqlc.ExecAsOf(id, asOf)
+	} else {
+		exec, err = sqlc.FindExecById(err, id, exec)
+	}
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if !historical {
+		w.Header().Set("ETag", utils.ETag(exec.Version))
+	}
 	json.NewEncoder(w).Encode(exec)
 }
 
@@ -65,6 +83,7 @@ func AddExecHandler(w http.ResponseWriter, r *http.Request) {
 
 	addedExecs, err := sqlc.SaveExecs(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -103,12 +122,20 @@ func PatchExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	existingExec, err := sqlc.PatchExecById(err, id, updates)
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	existingExec, err := sqlc.PatchExecById(r.Context(), err, id, updates, expectedVersion)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(existingExec.Version))
 	json.NewEncoder(w).Encode(existingExec)
 
 }
@@ -130,7 +157,7 @@ func DeleteExecHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlc.DeleteExecById(err, id)
+	err = sqlc.DeleteExecById(r.Context(), err, id)
 	if err != nil {
 		return
 	}
@@ -246,7 +273,7 @@ func UpdatePasswordHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := sqlc.UpdatePasswordById(userId, req)
+	token, err := sqlc.UpdatePasswordById(r.Context(), userId, req)
 
 	http.SetCookie(w, &http.Cookie{
 		Name:     "Bearer",
@@ -315,7 +342,7 @@ func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
 	hashedToken := sha256.Sum256([]byte(tokenBytes))
 	hash := hex.EncodeToString(hashedToken[:])
 
-	err = sqlc.PasswordResetDB(req.NewPassword, hash)
+	err = sqlc.PasswordResetDB(r.Context(), req.NewPassword, hash)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
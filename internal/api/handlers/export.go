@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+type exportFunc func(r *http.Request, open func() utils.ExportWriter) error
+
+func ExportStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	exportHandler(w, r, "students", sqlc.ExportStudents, "admin", "manager")
+}
+
+func ExportTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	exportHandler(w, r, "teachers", sqlc.ExportTeachers, "admin", "manager")
+}
+
+func ExportExecsHandler(w http.ResponseWriter, r *http.Request) {
+	exportHandler(w, r, "execs", sqlc.ExportExecs, "admin")
+}
+
+// exportHandler — ?format=csv|ndjson|xlsx, фильтры и sortBy как у списка.
+// Заголовки ответа отправляются только после успешного запроса к базе; ошибка посреди потока лишь обрывает его
+func exportHandler(w http.ResponseWriter, r *http.Request, name string, export exportFunc, roles ...string) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), roles...)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	contentType, ext, err := utils.ExportFormat(format)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	started := false
+	err = export(r, func() utils.ExportWriter {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+"-"+time.Now().Format("20060102")+"."+ext+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		return utils.NewExportWriter(format, w)
+	})
+	if err != nil {
+		if started {
+			log.Println("Export of", name, "aborted:", err)
+			return
+		}
+		writeError(w, err, http.StatusInternalServerError)
+	}
+}
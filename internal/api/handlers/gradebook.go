@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func GetAssessmentsHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+
+	assessmentList, page, err := sqlc.GetAllAssessments(r, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Assessment{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = assessmentList
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(assessmentList))
+		for _, a := range assessmentList {
+			projected = append(projected, utils.ProjectFields(a, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(assessmentList),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := sqlc.FindAssessmentById(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(assessment.Version))
+	json.NewEncoder(w).Encode(assessment)
+}
+
+func AddAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+
+	addedAssessments, err := sqlc.SaveAssessments(r, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Count  int              `json:"count"`
+		Data   []mod.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedAssessments),
+		Data:   addedAssessments,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	assessment, err := sqlc.PatchAssessmentById(id, patch, expectedVersion, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(assessment.Version))
+	json.NewEncoder(w).Encode(assessment)
+}
+
+func DeleteAssessmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteAssessmentById(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetAssessmentGradesHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	grades, err := sqlc.GetAssessmentGrades(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeGrades(w, http.StatusOK, grades)
+}
+
+// SaveGradesHandler — ввод оценок за работу для всего класса: массив {studentId, score, comment}
+func SaveGradesHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var entries []mod.GradeEntry
+	err = json.NewDecoder(r.Body).Decode(&entries)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	grades, err := sqlc.SaveGrades(id, entries, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeGrades(w, http.StatusOK, grades)
+}
+
+func writeGrades(w http.ResponseWriter, status int, grades []mod.Grade) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := struct {
+		Status string      `json:"status"`
+		Count  int         `json:"count"`
+		Data   []mod.Grade `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(grades),
+		Data:   grades,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetStudentGradesHandler — оценки студента по предметам и четвертям (?term=)
+func GetStudentGradesHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	term, err := termParam(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	grades, err := sqlc.GetStudentGrades(id, term, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Data   mod.StudentGrades `json:"data"`
+	}{
+		Status: "success",
+		Data:   grades,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetClassGradebookHandler — журнал класса (?subject=, ?term=)
+func GetClassGradebookHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	term, err := termParam(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	subject := strings.TrimSpace(r.URL.Query().Get("subject"))
+
+	book, err := sqlc.GetClassGradebook(id, subject, term, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string        `json:"status"`
+		Count  int           `json:"count"`
+		Data   mod.Gradebook `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(book.Rows),
+		Data:   book,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// termParam — ?term= от 1 до 4; без параметра — 0 (все четверти)
+func termParam(r *http.Request) (int, error) {
+	param := r.URL.Query().Get("term")
+	if param == "" {
+		return 0, nil
+	}
+	term, err := strconv.Atoi(param)
+	if err != nil || term < 1 || term > 4 {
+		return 0, utils.ErrorHandler(utils.ErrInvalidFilter, "Invalid term "+param)
+	}
+	return term, nil
+}
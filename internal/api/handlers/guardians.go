@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// GetGuardiansHandler — контакты родителей видят только администрация и менеджеры
+func GetGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	guardians, page, err := sqlc.GetAllGuardians(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Guardian{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = guardians
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(guardians))
+		for _, g := range guardians {
+			projected = append(projected, utils.ProjectFields(g, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardians),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	guardian, err := sqlc.FindGuardianById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(guardian.Version))
+	json.NewEncoder(w).Encode(guardian)
+}
+
+func AddGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedGuardians, err := sqlc.SaveGuardians(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []mod.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedGuardians),
+		Data:   addedGuardians,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	guardian, err := sqlc.PatchGuardianById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(guardian.Version))
+	json.NewEncoder(w).Encode(guardian)
+}
+
+func DeleteGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteGuardianById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStudentGuardiansHandler — опекуны студента; учителю — только студентов своих классов
+func GetStudentGuardiansHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	guardians, err := sqlc.GetStudentGuardians(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []mod.Guardian `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(guardians),
+		Data:   guardians,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// LinkStudentGuardianHandler — PUT /students/{id}/guardians/{guardianId}
+func LinkStudentGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentGuardianHandler(w, r, sqlc.LinkStudentGuardian)
+}
+
+// UnlinkStudentGuardianHandler — DELETE /students/{id}/guardians/{guardianId}
+func UnlinkStudentGuardianHandler(w http.ResponseWriter, r *http.Request) {
+	studentGuardianHandler(w, r, sqlc.UnlinkStudentGuardian)
+}
+
+func studentGuardianHandler(w http.ResponseWriter, r *http.Request, change func(studentID, guardianID int) error) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	studentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	guardianID, err := strconv.Atoi(r.PathValue("guardianId"))
+	if err != nil {
+		http.Error(w, "Invalid guardian ID", http.StatusBadRequest)
+		return
+	}
+
+	err = change(studentID, guardianID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
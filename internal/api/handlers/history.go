@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func GetStudentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	historyHandler(w, r, sqlc.GetStudentHistory, "admin", "manager")
+}
+
+func GetTeacherHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	historyHandler(w, r, sqlc.GetTeacherHistory, "admin", "manager")
+}
+
+func GetExecHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	historyHandler(w, r, sqlc.GetExecHistory, "admin")
+}
+
+func GetAttendanceHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	historyHandler(w, r, sqlc.GetAttendanceHistory, "admin", "manager")
+}
+
+// historyHandler — журнал изменений записи {id}, новые изменения первыми
+func historyHandler(w http.ResponseWriter, r *http.Request, get func(id int) ([]mod.HistoryEntry, error), roles ...string) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), roles...)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := get(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.HistoryEntry `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(entries),
+		Data:   entries,
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func GetHomeworkListHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+
+	homeworkList, page, err := sqlc.GetAllHomework(r, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Homework{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = homeworkList
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(homeworkList))
+		for _, h := range homeworkList {
+			projected = append(projected, utils.ProjectFields(h, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(homeworkList),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	homework, err := sqlc.FindHomeworkById(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(homework.Version))
+	json.NewEncoder(w).Encode(homework)
+}
+
+func AddHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+
+	addedHomework, err := sqlc.SaveHomework(r, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string         `json:"status"`
+		Count  int            `json:"count"`
+		Data   []mod.Homework `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedHomework),
+		Data:   addedHomework,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	homework, err := sqlc.PatchHomeworkById(id, patch, expectedVersion, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(homework.Version))
+	json.NewEncoder(w).Encode(homework)
+}
+
+func DeleteHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteHomeworkById(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddHomeworkAttachmentHandler — файл к заданию: multipart поле file
+func AddHomeworkAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	file, closer, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	defer closer.Close()
+
+	attachment, err := sqlc.AddHomeworkAttachment(id, file, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string           `json:"status"`
+		Data   mod.HomeworkFile `json:"data"`
+	}{
+		Status: "success",
+		Data:   attachment,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetHomeworkAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := strconv.Atoi(r.PathValue("attachmentId"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := sqlc.FindHomeworkAttachment(id, attachmentID, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	serveStoredFile(w, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size)
+}
+
+func DeleteHomeworkAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	attachmentID, err := strconv.Atoi(r.PathValue("attachmentId"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteHomeworkAttachment(id, attachmentID, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetHomeworkSubmissionsHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	submissions, err := sqlc.GetHomeworkSubmissions(id, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                   `json:"status"`
+		Count  int                      `json:"count"`
+		Data   []mod.HomeworkSubmission `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(submissions),
+		Data:   submissions,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// SaveSubmissionHandler — работа студента, принесённая учителю: multipart поля file и comment
+func SaveSubmissionHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, studentID, ok := submissionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	file, closer, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	defer closer.Close()
+	var comment *string
+	if c := r.FormValue("comment"); c != "" {
+		comment = &c
+	}
+
+	submission, err := sqlc.SaveSubmission(id, studentID, file, comment, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeSubmission(w, submission)
+}
+
+func GetSubmissionFileHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, studentID, ok := submissionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := sqlc.FindSubmission(id, studentID, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	serveStoredFile(w, submission.StorageKey, submission.FileName, submission.ContentType, submission.Size)
+}
+
+// SaveSubmissionFeedbackHandler — отзыв учителя о работе: {score, feedback}
+func SaveSubmissionFeedbackHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, studentID, ok := submissionIDs(w, r)
+	if !ok {
+		return
+	}
+
+	var fb mod.SubmissionFeedback
+	err := json.NewDecoder(r.Body).Decode(&fb)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	submission, err := sqlc.SaveSubmissionFeedback(id, studentID, fb, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writeSubmission(w, submission)
+}
+
+// LinkHomeworkGradebookHandler — переносит баллы задания в журнал; тело {term, weight} необязательно
+func LinkHomeworkGradebookHandler(w http.ResponseWriter, r *http.Request) {
+	teacherID, ok := authorizeTeacher(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var req mod.HomeworkGradebook
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	assessment, err := sqlc.LinkHomeworkGradebook(id, req, teacherID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string         `json:"status"`
+		Data   mod.Assessment `json:"data"`
+	}{
+		Status: "success",
+		Data:   assessment,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func submissionIDs(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	studentID, err := strconv.Atoi(r.PathValue("studentId"))
+	if err != nil {
+		http.Error(w, "Invalid student ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return id, studentID, true
+}
+
+func writeSubmission(w http.ResponseWriter, submission mod.HomeworkSubmission) {
+	response := struct {
+		Status string                 `json:"status"`
+		Data   mod.HomeworkSubmission `json:"data"`
+	}{
+		Status: "success",
+		Data:   submission,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
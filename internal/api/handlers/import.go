@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"WebProject/internal/jobs"
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+const (
+	// importAsyncRows — файлы длиннее обрабатываются фоновой задачей даже без ?async=true
+	importAsyncRows = 500
+)
+
+type importFunc func(ctx context.Context, rows [][]string, opts sqlc.ImportOptions) (mod.ImportResult, error)
+
+func ImportStudentsHandler(w http.ResponseWriter, r *http.Request) {
+	importHandler(w, r, "students.import", sqlc.ImportStudents)
+}
+
+func ImportTeachersHandler(w http.ResponseWriter, r *http.Request) {
+	importHandler(w, r, "teachers.import", sqlc.ImportTeachers)
+}
+
+// importHandler — принимает CSV/XLSX (multipart поле file или сырое тело),
+// ?dryRun=true только проверяет строки, ?async=true запускает фоновую задачу с опросом через /jobs/{id}
+func importHandler(w http.ResponseWriter, r *http.Request, jobType string, run importFunc) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, utils.MaxSpreadsheetSize)
+	data, mappingParam, err := readImportUpload(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := utils.ReadSpreadsheet(data)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	opts := sqlc.ImportOptions{DryRun: r.URL.Query().Get("dryRun") == "true"}
+	if mappingParam != "" {
+		err = json.Unmarshal([]byte(mappingParam), &opts.Mapping)
+		if err != nil {
+			http.Error(w, "Invalid mapping, expected {\"Column\": \"field\"}", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if r.URL.Query().Get("async") == "true" || len(rows)-1 > importAsyncRows {
+		owner := fmt.Sprint(r.Context().Value(utils.ContextKey("userId")))
+		job := jobs.Default.Start(jobType, owner, func(j *jobs.Job) (interface{}, error) {
+			opts.Progress = j.Progress
+			// задача переживает запрос: нужен только автор изменений, не отмена
+			return run(context.WithoutCancel(r.Context()), rows, opts)
+		})
+		view := job.View()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/jobs/"+view.ID)
+		w.WriteHeader(http.StatusAccepted)
+		response := struct {
+			Status string    `json:"status"`
+			Data   jobs.View `json:"data"`
+		}{
+			Status: "accepted",
+			Data:   view,
+		}
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	result, err := run(r.Context(), rows, opts)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	status := "success"
+	w.Header().Set("Content-Type", "application/json")
+	if result.Failed > 0 {
+		status = "fail"
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	response := struct {
+		Status string           `json:"status"`
+		Data   mod.ImportResult `json:"data"`
+	}{
+		Status: status,
+		Data:   result,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// readImportUpload — содержимое файла и необязательное сопоставление столбцов (поле или параметр mapping)
+func readImportUpload(r *http.Request) ([]byte, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, "", fmt.Errorf("cannot read body: %w", err)
+		}
+		return data, r.URL.Query().Get("mapping"), nil
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, "", fmt.Errorf("missing file field: %w", err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot read file: %w", err)
+	}
+	mapping := r.FormValue("mapping")
+	if mapping == "" {
+		mapping = r.URL.Query().Get("mapping")
+	}
+	return data, mapping, nil
+}
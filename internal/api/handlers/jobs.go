@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"WebProject/internal/jobs"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// GetJobHandler — состояние фоновой задачи; видна только запустившему её пользователю и admin
+func GetJobHandler(w http.ResponseWriter, r *http.Request) {
+	job, ok := jobs.Default.Get(r.PathValue("id"))
+	userId := fmt.Sprint(r.Context().Value(utils.ContextKey("userId")))
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	if !ok || (job.Owner() != userId && role != "admin") {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string    `json:"status"`
+		Data   jobs.View `json:"data"`
+	}{
+		Status: "success",
+		Data:   job.View(),
+	}
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// GetChildrenHandler — дети родителя, вошедшего в кабинет
+func GetChildrenHandler(w http.ResponseWriter, r *http.Request) {
+	guardianID, ok := authorizeGuardian(w, r)
+	if !ok {
+		return
+	}
+
+	children, err := sqlc.GetGuardianChildren(guardianID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.ChildProfile `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(children),
+		Data:   children,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetChildHandler(w http.ResponseWriter, r *http.Request) {
+	guardianID, ok := authorizeGuardian(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	child, err := sqlc.FindGuardianChild(guardianID, id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string           `json:"status"`
+		Data   mod.ChildProfile `json:"data"`
+	}{
+		Status: "success",
+		Data:   child,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetChildGradesHandler — оценки ребёнка (?term=)
+func GetChildGradesHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorizeChild(w, r)
+	if !ok {
+		return
+	}
+	term, err := termParam(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	grades, err := sqlc.GetStudentGrades(id, term, nil)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string            `json:"status"`
+		Data   mod.StudentGrades `json:"data"`
+	}{
+		Status: "success",
+		Data:   grades,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetChildAttendanceHandler — посещаемость ребёнка (?from=, ?to=)
+func GetChildAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorizeChild(w, r)
+	if !ok {
+		return
+	}
+	from, to, err := dateRangeParams(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	attendance, err := sqlc.GetStudentAttendance(id, from, to, nil)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string                `json:"status"`
+		Data   mod.StudentAttendance `json:"data"`
+	}{
+		Status: "success",
+		Data:   attendance,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// authorizeChild — ID студента из пути, если он связан с родителем, вошедшим в кабинет
+func authorizeChild(w http.ResponseWriter, r *http.Request) (int, bool) {
+	guardianID, ok := authorizeGuardian(w, r)
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return 0, false
+	}
+
+	err = sqlc.CheckGuardianChild(guardianID, id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return 0, false
+	}
+	return id, true
+}
+
+// GetChildHomeworkHandler — задания класса ребёнка с его работами, отзывами и баллами
+func GetChildHomeworkHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := authorizeChild(w, r)
+	if !ok {
+		return
+	}
+
+	homework, err := sqlc.GetStudentHomework(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []mod.ChildHomework `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(homework),
+		Data:   homework,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetChildSubmissionFileHandler(w http.ResponseWriter, r *http.Request) {
+	id, homeworkID, ok := authorizeChildHomework(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := sqlc.FindSubmission(homeworkID, id, nil)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	serveStoredFile(w, submission.StorageKey, submission.FileName, submission.ContentType, submission.Size)
+}
+
+func GetChildHomeworkAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	_, homeworkID, ok := authorizeChildHomework(w, r)
+	if !ok {
+		return
+	}
+	attachmentID, err := strconv.Atoi(r.PathValue("attachmentId"))
+	if err != nil {
+		http.Error(w, "Invalid attachment ID", http.StatusBadRequest)
+		return
+	}
+
+	attachment, err := sqlc.FindHomeworkAttachment(homeworkID, attachmentID, nil)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	serveStoredFile(w, attachment.StorageKey, attachment.FileName, attachment.ContentType, attachment.Size)
+}
+
+// authorizeChildHomework — authorizeChild и ID задания, выданного классу ребёнка
+func authorizeChildHomework(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	id, ok := authorizeChild(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	homeworkID, err := strconv.Atoi(r.PathValue("homeworkId"))
+	if err != nil {
+		http.Error(w, "Invalid homework ID", http.StatusBadRequest)
+		return 0, 0, false
+	}
+
+	err = sqlc.CheckStudentHomework(id, homeworkID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return 0, 0, false
+	}
+	return id, homeworkID, true
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"WebProject/internal/search"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// SearchHandler — поиск студентов и учителей по имени и email; учётные записи видны только admin и manager
+func SearchHandler(w http.ResponseWriter, r *http.Request) {
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len([]rune(q)) < 2 {
+		http.Error(w, "Query must be at least 2 characters", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 200)
+	}
+
+	types := []string{search.TypeStudent, search.TypeTeacher}
+	grouped := map[string][]search.Result{
+		"students": {},
+		"teachers": {},
+	}
+	role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+	if _, err := utils.AuthorizeUser(role, "admin", "manager"); err == nil {
+		types = append(types, search.TypeExec)
+		grouped["execs"] = []search.Result{}
+	}
+
+	results := search.Default.Search(q, limit, types...)
+	for _, res := range results {
+		group := res.Type + "s"
+		grouped[group] = append(grouped[group], res)
+	}
+
+	response := struct {
+		Status string                     `json:"status"`
+		Query  string                     `json:"query"`
+		Count  int                        `json:"count"`
+		Data   map[string][]search.Result `json:"data"`
+	}{
+		Status: "success",
+		Query:  q,
+		Count:  len(results),
+		Data:   grouped,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -15,19 +15,38 @@ import (
 
 func GetStudentsHandler(w http.ResponseWriter, r *http.Request) {
 
-	StudentList, err := sqlc.GetAllStudents(r)
+	StudentList, page, err := sqlc.GetAllStudents(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	fields, err := utils.ParseFields(r, mod.Student{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = StudentList
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(StudentList))
+		for _, s := range StudentList {
+			projected = append(projected, utils.ProjectFields(s, fields))
+		}
+		data = projected
+	}
+
 	response := struct {
-		Status string        `json:"status"`
-		Count  int           `json:"count"`
-		Data   []mod.Student `json:"data"`
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
 	}{
 		Status: "success",
 		Count:  len(StudentList),
-		Data:   StudentList,
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -43,14 +62,39 @@ func GetStudentHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+	fields, err := utils.ParseFields(r, mod.Student{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	asOf, historical, err := utils.ParseAsOf(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+
 	var Student mod.Student
 
-	err = sqlc.FindStudentById(err, id, Student)
+	if historical {
+		Student, err = sqlc.StudentAsOf(id, asOf)
+	} else {
+		Student, err = sqlc.FindStudentById(err, id, Student)
+	}
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	// историческое состояние нельзя использовать для If-Match
+	if !historical {
+		w.Header().Set("ETag", utils.ETag(Student.Version))
+	}
+	if len(fields) > 0 {
+		json.NewEncoder(w).Encode(utils.ProjectFields(Student, fields))
+		return
+	}
 	json.NewEncoder(w).Encode(Student)
 }
 
@@ -61,8 +105,24 @@ func AddStudentHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	partial, err := utils.IsPartialMode(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		results, err := sqlc.SaveStudentsPartial(r)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeBulkResults(w, results)
+		return
+	}
+
 	addedStudents, err := sqlc.SaveStudents(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -110,13 +170,21 @@ func UpdateStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedStudentDB, err := sqlc.UpdateStudentById(err, id, updatedStudent)
+	expectedVersion, err := utils.RequireIfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	updatedStudentDB, err := sqlc.UpdateStudentById(r.Context(), err, id, updatedStudent, expectedVersion)
 
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(updatedStudentDB.Version))
 	json.NewEncoder(w).Encode(updatedStudentDB)
 
 }
@@ -135,19 +203,31 @@ func PatchStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
 		return
 	}
 
-	existingStudent, err := sqlc.PatchStudentById(err, id, updates)
+	expectedVersion, err := utils.RequireIfMatchVersion(r)
 	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	existingStudent, err := sqlc.PatchStudentById(r.Context(), err, id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(existingStudent.Version))
 	json.NewEncoder(w).Encode(existingStudent)
 
 }
@@ -158,15 +238,35 @@ func PatchStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	var updates []map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	partial, err := utils.IsPartialMode(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		results, err := sqlc.PatchAllStudentsPartial(r.Context(), patch)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeBulkResults(w, results)
 		return
 	}
 
-	err = sqlc.PatchAllStudents(err, updates)
+	err = sqlc.PatchAllStudents(r.Context(), err, patch)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -181,7 +281,7 @@ func DeleteStudentHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	path := strings.TrimPrefix(r.URL.Path, "/Students")
+	path := strings.TrimPrefix(r.URL.Path, "/students")
 	path = strings.Trim(path, "/")
 	if path == "" {
 		http.Error(w, "Invalid path", http.StatusBadRequest)
@@ -193,8 +293,9 @@ func DeleteStudentHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlc.DeleteStudentById(err, id)
+	err = sqlc.DeleteStudentById(r.Context(), err, id)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -225,8 +326,19 @@ func DeleteStudentsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedIdsFromBd, err := sqlc.DeleteStudents(err, ids)
+	partial, err := utils.IsPartialMode(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		writeBulkResults(w, sqlc.DeleteStudentsPartial(r.Context(), ids))
+		return
+	}
+
+	deletedIdsFromBd, err := sqlc.DeleteStudents(r.Context(), err, ids)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -5,6 +5,7 @@ import (
 	sqlc "WebProject/internal/repos/sqlconnect"
 	"WebProject/pkg/utils"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,19 +13,30 @@ import (
 
 func GetTeachersHandler(w http.ResponseWriter, r *http.Request) {
 
-	teacherList, err := sqlc.GetAllTeachers(r)
+	teacherList, page, err := sqlc.GetAllTeachers(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := teachersResponseData(r, teacherList)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	response := struct {
-		Status string        `json:"status"`
-		Count  int           `json:"count"`
-		Data   []mod.Teacher `json:"data"`
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
 	}{
 		Status: "success",
 		Count:  len(teacherList),
-		Data:   teacherList,
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -40,10 +52,34 @@ func GetTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
+	asOf, historical, err := utils.ParseAsOf(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
 	var teacher mod.Teacher
 
-	err = sqlc.FindTeacherById(err, id, teacher)
+	if historical {
+		teacher, err = sqlc.TeacherAsOf(id, asOf)
+	} else {
+		teacher, err = sqlc.FindTeacherById(err, id, teacher)
+	}
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	data, err := teachersResponseData(r, []mod.Teacher{teacher})
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	if !historical {
+		w.Header().Set("ETag", utils.ETag(teacher.Version))
+	}
+	if list, ok := data.([]map[string]interface{}); ok {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list[0])
 		return
 	}
 
@@ -57,8 +93,24 @@ func AddTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	partial, err := utils.IsPartialMode(r)
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		results, err := sqlc.SaveTeachersPartial(r)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeBulkResults(w, results)
+		return
+	}
+
 	addedTeachers, err := sqlc.SaveTeachers(r)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -95,13 +147,21 @@ func UpdateTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	updatedTeacherDB, err := sqlc.UpdateTeacherById(err, id, updatedTeacher)
+	expectedVersion, err := utils.RequireIfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	updatedTeacherDB, err := sqlc.UpdateTeacherById(r.Context(), err, id, updatedTeacher, expectedVersion)
 
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(updatedTeacherDB.Version))
 	json.NewEncoder(w).Encode(updatedTeacherDB)
 
 }
@@ -120,19 +180,31 @@ func PatchTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var updates map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
 		return
 	}
 
-	existingTeacher, err := sqlc.PatchTeacherById(err, id, updates)
+	expectedVersion, err := utils.RequireIfMatchVersion(r)
 	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	existingTeacher, err := sqlc.PatchTeacherById(r.Context(), err, id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(existingTeacher.Version))
 	json.NewEncoder(w).Encode(existingTeacher)
 
 }
@@ -143,15 +215,35 @@ func PatchTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	var updates []map[string]interface{}
-	err = json.NewDecoder(r.Body).Decode(&updates)
+	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
 		return
 	}
 
-	err = sqlc.PatchAllTeachers(err, updates)
+	partial, err := utils.IsPartialMode(r)
 	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		results, err := sqlc.PatchAllTeachersPartial(r.Context(), patch)
+		if err != nil {
+			writeError(w, err, http.StatusInternalServerError)
+			return
+		}
+		writeBulkResults(w, results)
+		return
+	}
+
+	err = sqlc.PatchAllTeachers(r.Context(), err, patch)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -177,8 +269,9 @@ func DeleteTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = sqlc.DeleteTeacherById(err, id)
+	err = sqlc.DeleteTeacherById(r.Context(), err, id)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -209,8 +302,19 @@ func DeleteTeachersHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	deletedIdsFromBd, err := sqlc.DeleteTeachers(err, ids)
+	partial, err := utils.IsPartialMode(r)
 	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	if partial {
+		writeBulkResults(w, sqlc.DeleteTeachersPartial(r.Context(), ids))
+		return
+	}
+
+	deletedIdsFromBd, err := sqlc.DeleteTeachers(r.Context(), err, ids)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
 
@@ -239,21 +343,71 @@ func GetStudentsByTeacherHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	students, err := sqlc.FindStudentsByTeacherId(w, err, id)
+	groups, err := sqlc.FindStudentsByTeacherId(id)
 	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
 		return
 	}
+	total := 0
+	for _, g := range groups {
+		total += len(g.Students)
+	}
 
 	resp := struct {
-		Status string        `json:"status"`
-		Count  int           `json:"count"`
-		Data   []mod.Student `json:"data"`
+		Status  string              `json:"status"`
+		Count   int                 `json:"count"`
+		Classes int                 `json:"classes"`
+		Data    []mod.ClassStudents `json:"data"`
 	}{
-		Status: "success",
-		Count:  len(students),
-		Data:   students,
+		Status:  "success",
+		Count:   total,
+		Classes: len(groups),
+		Data:    groups,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
+
+// teachersResponseData — применяет ?fields и ?include=students к списку учителей
+// Студенты подгружаются одним набором запросов для всех учителей страницы
+func teachersResponseData(r *http.Request, teachers []mod.Teacher) (interface{}, error) {
+	fields, err := utils.ParseFields(r, mod.Teacher{})
+	if err != nil {
+		return nil, err
+	}
+	include, err := utils.ParseInclude(r, "students")
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 && len(include) == 0 {
+		return teachers, nil
+	}
+
+	var studentsByTeacher map[int][]mod.ClassStudents
+	if include["students"] {
+		ids := make([]int, 0, len(teachers))
+		for _, t := range teachers {
+			ids = append(ids, t.ID)
+		}
+		studentsByTeacher, err = sqlc.FindStudentsByTeachers(ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := make([]map[string]interface{}, 0, len(teachers))
+	for _, t := range teachers {
+		item := utils.ProjectFields(t, fields)
+		if include["students"] {
+			// плоский список студентов всех классов учителя, как в GET /teachers/{id}/students без группировки
+			students := []mod.Student{}
+			for _, g := range studentsByTeacher[t.ID] {
+				students = append(students, g.Students...)
+			}
+			item["students"] = students
+		}
+		data = append(data, item)
+	}
+	return data, nil
+}
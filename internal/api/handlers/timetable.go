@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	mod "WebProject/internal/models"
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+func GetLessonsHandler(w http.ResponseWriter, r *http.Request) {
+	lessons, page, err := sqlc.GetAllLessons(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Lesson{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = lessons
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(lessons))
+		for _, l := range lessons {
+			projected = append(projected, utils.ProjectFields(l, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(lessons),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetLessonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	lesson, err := sqlc.FindLessonById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(lesson.Version))
+	json.NewEncoder(w).Encode(lesson)
+}
+
+func AddLessonHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	addedLessons, err := sqlc.SaveLessons(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string       `json:"status"`
+		Count  int          `json:"count"`
+		Data   []mod.Lesson `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedLessons),
+		Data:   addedLessons,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func PatchLessonHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "Cannot read body", http.StatusBadRequest)
+		return
+	}
+	patch, err := utils.NewPatch(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		writeError(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	expectedVersion, err := utils.IfMatchVersion(r)
+	if err != nil {
+		writeError(w, err, http.StatusPreconditionFailed)
+		return
+	}
+
+	lesson, err := sqlc.PatchLessonById(id, patch, expectedVersion)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(lesson.Version))
+	json.NewEncoder(w).Encode(lesson)
+}
+
+func DeleteLessonHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteLessonById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetSubstitutionsHandler(w http.ResponseWriter, r *http.Request) {
+	substitutions, page, err := sqlc.GetAllSubstitutions(r)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	fields, err := utils.ParseFields(r, mod.Substitution{})
+	if err != nil {
+		writeError(w, err, http.StatusBadRequest)
+		return
+	}
+	var data interface{} = substitutions
+	if len(fields) > 0 {
+		projected := make([]map[string]interface{}, 0, len(substitutions))
+		for _, s := range substitutions {
+			projected = append(projected, utils.ProjectFields(s, fields))
+		}
+		data = projected
+	}
+
+	response := struct {
+		Status string          `json:"status"`
+		Count  int             `json:"count"`
+		Total  *int            `json:"total,omitempty"`
+		Links  utils.PageLinks `json:"links"`
+		Data   interface{}     `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(substitutions),
+		Total:  page.Total,
+		Links:  page.Links,
+		Data:   data,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func GetSubstitutionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	substitution, err := sqlc.FindSubstitutionById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(substitution.Version))
+	json.NewEncoder(w).Encode(substitution)
+}
+
+// AddSubstitutionHandler — замены на время отсутствия учителя: массив {lessonId, date, substituteTeacherId, reason}
+func AddSubstitutionHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var userID *int
+	if uid, err := requestUserID(r); err == nil {
+		userID = &uid
+	}
+	addedSubstitutions, err := sqlc.SaveSubstitutions(r, userID)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	response := struct {
+		Status string             `json:"status"`
+		Count  int                `json:"count"`
+		Data   []mod.Substitution `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(addedSubstitutions),
+		Data:   addedSubstitutions,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func DeleteSubstitutionHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	err = sqlc.DeleteSubstitutionById(id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetPeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	periods, err := sqlc.GetPeriods()
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writePeriods(w, periods)
+}
+
+// ReplacePeriodsHandler — новое расписание звонков целиком: массив {number, startTime, endTime}
+func ReplacePeriodsHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var periods []mod.Period
+	err = json.NewDecoder(r.Body).Decode(&periods)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	periods, err = sqlc.ReplacePeriods(periods)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+	writePeriods(w, periods)
+}
+
+func writePeriods(w http.ResponseWriter, periods []mod.Period) {
+	response := struct {
+		Status string       `json:"status"`
+		Count  int          `json:"count"`
+		Data   []mod.Period `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(periods),
+		Data:   periods,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetTeacherTimetableHandler — расписание учителя; ?week= — неделя с датами и заменами
+func GetTeacherTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	timetableHandler(w, r, sqlc.GetTeacherTimetable)
+}
+
+// GetClassTimetableHandler — расписание класса; ?week= — неделя с датами и заменами
+func GetClassTimetableHandler(w http.ResponseWriter, r *http.Request) {
+	timetableHandler(w, r, sqlc.GetClassTimetable)
+}
+
+func timetableHandler(w http.ResponseWriter, r *http.Request, get func(id int, week string) ([]mod.TimetableSlot, error)) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	slots, err := get(id, r.URL.Query().Get("week"))
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Status string              `json:"status"`
+		Count  int                 `json:"count"`
+		Data   []mod.TimetableSlot `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(slots),
+		Data:   slots,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
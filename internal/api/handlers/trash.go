@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	sqlc "WebProject/internal/repos/sqlconnect"
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+func GetTrashHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	kind := r.URL.Query().Get("type")
+	if kind != "" && kind != "students" && kind != "teachers" {
+		http.Error(w, "type must be students or teachers", http.StatusBadRequest)
+		return
+	}
+
+	trash, err := sqlc.GetTrash(kind)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	response := struct {
+		Status string      `json:"status"`
+		Count  int         `json:"count"`
+		Data   interface{} `json:"data"`
+	}{
+		Status: "success",
+		Count:  len(trash.Students) + len(trash.Teachers),
+		Data:   trash,
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func RestoreStudentHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	student, err := sqlc.RestoreStudentById(r.Context(), id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(student.Version))
+	json.NewEncoder(w).Encode(student)
+}
+
+func RestoreTeacherHandler(w http.ResponseWriter, r *http.Request) {
+	_, err := utils.AuthorizeUser(r.Context().Value(utils.ContextKey("role")).(string), "admin", "manager")
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	teacher, err := sqlc.RestoreTeacherById(r.Context(), id)
+	if err != nil {
+		writeError(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", utils.ETag(teacher.Version))
+	json.NewEncoder(w).Encode(teacher)
+}
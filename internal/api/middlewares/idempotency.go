@@ -0,0 +1,166 @@
+package middlewares
+
+import (
+	"WebProject/pkg/utils"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxIdempotentBody — наибольшее тело POST с Idempotency-Key, которое читается в память (с запасом на импорт и файлы)
+const maxIdempotentBody = 32 << 20
+
+// idempotencyEntry — сохранённый ответ на POST с заголовком Idempotency-Key
+type idempotencyEntry struct {
+	requestHash string
+	done        bool
+	status      int
+	header      http.Header
+	body        []byte
+	expiresAt   time.Time
+}
+
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	ttl     time.Duration
+}
+
+// NewIdempotencyStore — хранилище ключей идемпотентности; просроченные ключи удаляются раз в минуту
+func NewIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	s := &idempotencyStore{entries: make(map[string]*idempotencyEntry), ttl: ttl}
+	go s.cleanup()
+	return s
+}
+
+func (s *idempotencyStore) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		now := time.Now()
+		s.mu.Lock()
+		for k, e := range s.entries {
+			if e.done && now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Middleware — повторный POST с тем же ключом и телом получает сохранённый ответ (без Set-Cookie),
+// тот же ключ с другим телом или параллельный запрос с тем же ключом — 409. Ключи хранятся отдельно для каждого пользователя
+func (s *idempotencyStore) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		userID := r.Context().Value(utils.ContextKey("userId"))
+		// без JWT ключи разных клиентов попали бы в одно пространство — такие запросы не сохраняем
+		if r.Method != http.MethodPost || key == "" || userID == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > 255 {
+			http.Error(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Cannot read body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.Sum256(append([]byte(r.Method+" "+r.URL.RequestURI()+"\n"), body...))
+		requestHash := hex.EncodeToString(hash[:])
+		storeKey := fmt.Sprintf("%v|%s", userID, key)
+
+		s.mu.Lock()
+		entry, ok := s.entries[storeKey]
+		if ok && entry.done && time.Now().After(entry.expiresAt) {
+			delete(s.entries, storeKey)
+			ok = false
+		}
+		if ok {
+			s.mu.Unlock()
+			switch {
+			case entry.requestHash != requestHash:
+				http.Error(w, "Idempotency-Key was already used with a different request", http.StatusConflict)
+			case !entry.done:
+				http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			default:
+				for k, v := range entry.header {
+					w.Header()[k] = v
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(entry.status)
+				w.Write(entry.body)
+			}
+			return
+		}
+		entry = &idempotencyEntry{requestHash: requestHash}
+		s.entries[storeKey] = entry
+		s.mu.Unlock()
+
+		// если обработчик запаниковал, ключ освобождается, иначе он навсегда остался бы "в процессе"
+		completed := false
+		defer func() {
+			if !completed {
+				s.mu.Lock()
+				delete(s.entries, storeKey)
+				s.mu.Unlock()
+			}
+		}()
+
+		rec := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		completed = true
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		// ошибки сервера не сохраняем, чтобы клиент мог повторить запрос
+		if rec.status >= http.StatusInternalServerError {
+			delete(s.entries, storeKey)
+			return
+		}
+		entry.done = true
+		entry.status = rec.status
+		entry.header = w.Header().Clone()
+		// повтор не должен заново выдавать cookie с токеном
+		entry.header.Del("Set-Cookie")
+		entry.body = rec.body.Bytes()
+		entry.expiresAt = time.Now().Add(s.ttl)
+	})
+}
+
+// recordingWriter — пишет ответ клиенту и одновременно копирует его для повторной отдачи
+type recordingWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
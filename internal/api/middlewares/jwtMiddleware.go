@@ -28,6 +28,7 @@ func JWTMiddleware(next http.Handler) http.Handler {
 
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
 		}
 
 		if parsedToken.Valid {
@@ -44,10 +45,9 @@ func JWTMiddleware(next http.Handler) http.Handler {
 		}
 
 		ctx := context.WithValue(r.Context(), utils.ContextKey("role"), claims["role"].(string))
-		ctx.Value("role")
-		ctx = context.WithValue(r.Context(), utils.ContextKey("expiresAt"), claims["exp"])
-		ctx = context.WithValue(r.Context(), utils.ContextKey("username"), claims["username"])
-		ctx = context.WithValue(r.Context(), utils.ContextKey("userId"), claims["userId"])
+		ctx = context.WithValue(ctx, utils.ContextKey("expiresAt"), claims["exp"])
+		ctx = context.WithValue(ctx, utils.ContextKey("username"), claims["username"])
+		ctx = context.WithValue(ctx, utils.ContextKey("userId"), claims["userId"])
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"WebProject/pkg/utils"
+	"net/http"
+	"strings"
+)
+
+// ParentScope — учётной записи родителя открыт только родительский кабинет /parent/, выход и смена своего пароля.
+// Остальные маршруты (в том числе списки без проверки роли) для неё закрыты
+func ParentScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role, _ := r.Context().Value(utils.ContextKey("role")).(string)
+		if role == "parent" && !parentAllowed(r.URL.Path) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func parentAllowed(path string) bool {
+	return strings.HasPrefix(path, "/parent/") || path == "/execs/logout" ||
+		(strings.HasPrefix(path, "/execs/") && strings.HasSuffix(path, "/updatepassword"))
+}
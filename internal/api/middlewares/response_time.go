@@ -17,7 +17,7 @@ func ResponseTimeMiddleware(next http.Handler) http.Handler {
 		fmt.Printf("Method: %s, URL: %s, StatusCode: %d, Duration: %s\n",
 			r.Method, r.URL, rw.status, duration.String())
 
-		fmt.Println("Sent Response\n")
+		fmt.Println("Sent Response")
 	})
 
 }
@@ -0,0 +1,22 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func AcademicRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /academic-years", hnd.GetAcademicYearsHandler)
+	mux.HandleFunc("POST /academic-years", hnd.AddAcademicYearHandler)
+	mux.HandleFunc("GET /academic-years/{id}", hnd.GetAcademicYearHandler)
+	mux.HandleFunc("PATCH /academic-years/{id}", hnd.PatchAcademicYearHandler)
+	mux.HandleFunc("DELETE /academic-years/{id}", hnd.DeleteAcademicYearHandler)
+	mux.HandleFunc("GET /academic-years/{id}/terms", hnd.GetTermsHandler)
+	mux.HandleFunc("PUT /academic-years/{id}/terms", hnd.ReplaceTermsHandler)
+	mux.HandleFunc("GET /academic-years/{id}/enrollments", hnd.GetYearEnrollmentsHandler)
+	mux.HandleFunc("POST /academic-years/{id}/promote", hnd.PromoteStudentsHandler)
+
+	return mux
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func AnnouncementsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /announcements", hnd.GetAnnouncementsHandler)
+	mux.HandleFunc("POST /announcements", hnd.AddAnnouncementsHandler)
+	mux.HandleFunc("GET /announcements/{id}", hnd.GetAnnouncementHandler)
+	mux.HandleFunc("PATCH /announcements/{id}", hnd.PatchAnnouncementHandler)
+	mux.HandleFunc("DELETE /announcements/{id}", hnd.DeleteAnnouncementHandler)
+	mux.HandleFunc("POST /announcements/{id}/read", hnd.MarkAnnouncementReadHandler)
+	mux.HandleFunc("GET /announcements/{id}/reads", hnd.GetAnnouncementReceiptsHandler)
+
+	return mux
+}
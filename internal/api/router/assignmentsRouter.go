@@ -0,0 +1,19 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func AssignmentsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /assignments", hnd.GetAssignmentsHandler)
+	mux.HandleFunc("POST /assignments", hnd.AddAssignmentHandler)
+
+	mux.HandleFunc("GET /assignments/{id}", hnd.GetAssignmentHandler)
+	mux.HandleFunc("PATCH /assignments/{id}", hnd.PatchAssignmentHandler)
+	mux.HandleFunc("DELETE /assignments/{id}", hnd.DeleteAssignmentHandler)
+
+	return mux
+}
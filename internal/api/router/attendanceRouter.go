@@ -0,0 +1,19 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func AttendanceRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /attendance", hnd.GetAttendanceListHandler)
+	mux.HandleFunc("GET /attendance/absences", hnd.GetAbsencesHandler)
+
+	mux.HandleFunc("GET /attendance/{id}", hnd.GetAttendanceHandler)
+	mux.HandleFunc("PATCH /attendance/{id}", hnd.PatchAttendanceHandler)
+	mux.HandleFunc("GET /attendance/{id}/history", hnd.GetAttendanceHistoryHandler)
+
+	return mux
+}
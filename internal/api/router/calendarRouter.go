@@ -0,0 +1,19 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func CalendarRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /calendar/{file}", hnd.GetCalendarHandler)
+	mux.HandleFunc("DELETE /calendar-feeds/{id}", hnd.RevokeCalendarFeedHandler)
+
+	mux.HandleFunc("GET /holidays", hnd.GetHolidaysHandler)
+	mux.HandleFunc("POST /holidays", hnd.AddHolidayHandler)
+	mux.HandleFunc("DELETE /holidays/{id}", hnd.DeleteHolidayHandler)
+
+	return mux
+}
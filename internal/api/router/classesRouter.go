@@ -0,0 +1,27 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func ClassesRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /classes", hnd.GetClassesHandler)
+	mux.HandleFunc("POST /classes", hnd.AddClassHandler)
+
+	mux.HandleFunc("GET /classes/{id}", hnd.GetClassHandler)
+	mux.HandleFunc("PATCH /classes/{id}", hnd.PatchClassHandler)
+	mux.HandleFunc("DELETE /classes/{id}", hnd.DeleteClassHandler)
+	mux.HandleFunc("GET /classes/{id}/students", hnd.GetStudentsByClassHandler)
+	mux.HandleFunc("GET /classes/{id}/gradebook", hnd.GetClassGradebookHandler)
+	mux.HandleFunc("GET /classes/{id}/attendance", hnd.GetClassAttendanceHandler)
+	mux.HandleFunc("PUT /classes/{id}/attendance", hnd.SubmitClassAttendanceHandler)
+	mux.HandleFunc("GET /classes/{id}/attendance/summary", hnd.GetClassAttendanceSummaryHandler)
+	mux.HandleFunc("GET /classes/{id}/timetable", hnd.GetClassTimetableHandler)
+	mux.HandleFunc("GET /classes/{id}/calendar-feeds", hnd.GetClassFeedsHandler)
+	mux.HandleFunc("POST /classes/{id}/calendar-feeds", hnd.AddClassFeedHandler)
+
+	return mux
+}
@@ -9,11 +9,15 @@ func ExecsRouter() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /execs", hnd.GetExecsHandler)
+	mux.HandleFunc("GET /execs/export", hnd.ExportExecsHandler)
 	mux.HandleFunc("POST /execs", hnd.AddExecHandler)
 
 	mux.HandleFunc("GET /execs/{id}", hnd.GetExecHandler)
 	mux.HandleFunc("PATCH /execs/{id}", hnd.PatchExecHandler)
 	mux.HandleFunc("DELETE /execs/{id}", hnd.DeleteExecHandler)
+	mux.HandleFunc("GET /execs/{id}/history", hnd.GetExecHistoryHandler)
+	mux.HandleFunc("GET /execs/{id}/attachments", hnd.GetExecAttachmentsHandler)
+	mux.HandleFunc("POST /execs/{id}/attachments", hnd.AddExecAttachmentHandler)
 
 	mux.HandleFunc("POST /execs/login", hnd.LoginHandler)
 	mux.HandleFunc("POST /execs/logout", hnd.LogoutHandler)
@@ -0,0 +1,18 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func FilesRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /attachments/{id}", hnd.GetAttachmentHandler)
+	mux.HandleFunc("GET /attachments/{id}/content", hnd.GetAttachmentContentHandler)
+	mux.HandleFunc("POST /attachments/{id}/url", hnd.CreateAttachmentURLHandler)
+	mux.HandleFunc("DELETE /attachments/{id}", hnd.DeleteAttachmentHandler)
+	mux.HandleFunc("GET /files/{id}", hnd.GetSignedFileHandler)
+
+	return mux
+}
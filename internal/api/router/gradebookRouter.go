@@ -0,0 +1,21 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func GradebookRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /assessments", hnd.GetAssessmentsHandler)
+	mux.HandleFunc("POST /assessments", hnd.AddAssessmentHandler)
+
+	mux.HandleFunc("GET /assessments/{id}", hnd.GetAssessmentHandler)
+	mux.HandleFunc("PATCH /assessments/{id}", hnd.PatchAssessmentHandler)
+	mux.HandleFunc("DELETE /assessments/{id}", hnd.DeleteAssessmentHandler)
+	mux.HandleFunc("GET /assessments/{id}/grades", hnd.GetAssessmentGradesHandler)
+	mux.HandleFunc("PUT /assessments/{id}/grades", hnd.SaveGradesHandler)
+
+	return mux
+}
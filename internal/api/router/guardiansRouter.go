@@ -0,0 +1,18 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func GuardiansRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /guardians", hnd.GetGuardiansHandler)
+	mux.HandleFunc("POST /guardians", hnd.AddGuardianHandler)
+	mux.HandleFunc("GET /guardians/{id}", hnd.GetGuardianHandler)
+	mux.HandleFunc("PATCH /guardians/{id}", hnd.PatchGuardianHandler)
+	mux.HandleFunc("DELETE /guardians/{id}", hnd.DeleteGuardianHandler)
+
+	return mux
+}
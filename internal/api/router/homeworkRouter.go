@@ -0,0 +1,28 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func HomeworkRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /homework", hnd.GetHomeworkListHandler)
+	mux.HandleFunc("POST /homework", hnd.AddHomeworkHandler)
+
+	mux.HandleFunc("GET /homework/{id}", hnd.GetHomeworkHandler)
+	mux.HandleFunc("PATCH /homework/{id}", hnd.PatchHomeworkHandler)
+	mux.HandleFunc("DELETE /homework/{id}", hnd.DeleteHomeworkHandler)
+	mux.HandleFunc("POST /homework/{id}/attachments", hnd.AddHomeworkAttachmentHandler)
+	mux.HandleFunc("GET /homework/{id}/attachments/{attachmentId}", hnd.GetHomeworkAttachmentHandler)
+	mux.HandleFunc("DELETE /homework/{id}/attachments/{attachmentId}", hnd.DeleteHomeworkAttachmentHandler)
+	mux.HandleFunc("POST /homework/{id}/gradebook", hnd.LinkHomeworkGradebookHandler)
+
+	mux.HandleFunc("GET /homework/{id}/submissions", hnd.GetHomeworkSubmissionsHandler)
+	mux.HandleFunc("PUT /homework/{id}/submissions/{studentId}", hnd.SaveSubmissionHandler)
+	mux.HandleFunc("GET /homework/{id}/submissions/{studentId}/file", hnd.GetSubmissionFileHandler)
+	mux.HandleFunc("PUT /homework/{id}/submissions/{studentId}/feedback", hnd.SaveSubmissionFeedbackHandler)
+
+	return mux
+}
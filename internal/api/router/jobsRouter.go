@@ -0,0 +1,14 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func JobsRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /jobs/{id}", hnd.GetJobHandler)
+
+	return mux
+}
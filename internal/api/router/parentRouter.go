@@ -0,0 +1,23 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func ParentRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /parent/children", hnd.GetChildrenHandler)
+	mux.HandleFunc("GET /parent/children/{id}", hnd.GetChildHandler)
+	mux.HandleFunc("GET /parent/children/{id}/grades", hnd.GetChildGradesHandler)
+	mux.HandleFunc("GET /parent/children/{id}/attendance", hnd.GetChildAttendanceHandler)
+	mux.HandleFunc("GET /parent/children/{id}/homework", hnd.GetChildHomeworkHandler)
+	mux.HandleFunc("GET /parent/children/{id}/homework/{homeworkId}/attachments/{attachmentId}", hnd.GetChildHomeworkAttachmentHandler)
+	mux.HandleFunc("GET /parent/children/{id}/homework/{homeworkId}/submission/file", hnd.GetChildSubmissionFileHandler)
+	mux.HandleFunc("GET /parent/announcements", hnd.GetParentAnnouncementsHandler)
+	mux.HandleFunc("GET /parent/announcements/{id}", hnd.GetParentAnnouncementHandler)
+	mux.HandleFunc("POST /parent/announcements/{id}/read", hnd.MarkParentAnnouncementReadHandler)
+
+	return mux
+}
@@ -9,7 +9,37 @@ func MainRouter() *http.ServeMux {
 	tRout := TeacherRouter()
 	sRout := StudentsRouter()
 	eRout := ExecsRouter()
+	searchRout := SearchRouter()
+	jobsRout := JobsRouter()
+	trashRout := TrashRouter()
+	classesRout := ClassesRouter()
+	assignmentsRout := AssignmentsRouter()
+	gradebookRout := GradebookRouter()
+	attendanceRout := AttendanceRouter()
+	timetableRout := TimetableRouter()
+	calendarRout := CalendarRouter()
+	academicRout := AcademicRouter()
+	guardiansRout := GuardiansRouter()
+	parentRout := ParentRouter()
+	homeworkRout := HomeworkRouter()
+	filesRout := FilesRouter()
+	announcementsRout := AnnouncementsRouter()
 
+	filesRout.Handle("/", announcementsRout)
+	homeworkRout.Handle("/", filesRout)
+	parentRout.Handle("/", homeworkRout)
+	guardiansRout.Handle("/", parentRout)
+	academicRout.Handle("/", guardiansRout)
+	calendarRout.Handle("/", academicRout)
+	timetableRout.Handle("/", calendarRout)
+	attendanceRout.Handle("/", timetableRout)
+	gradebookRout.Handle("/", attendanceRout)
+	assignmentsRout.Handle("/", gradebookRout)
+	classesRout.Handle("/", assignmentsRout)
+	trashRout.Handle("/", classesRout)
+	jobsRout.Handle("/", trashRout)
+	searchRout.Handle("/", jobsRout)
+	eRout.Handle("/", searchRout)
 	sRout.Handle("/", eRout)
 	tRout.Handle("/", sRout)
 
@@ -9,13 +9,25 @@ func StudentsRouter() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /students", hnd.GetStudentsHandler)
+	mux.HandleFunc("GET /students/export", hnd.ExportStudentsHandler)
 	mux.HandleFunc("GET /students/{id}", hnd.GetStudentHandler)
 	mux.HandleFunc("POST /students", hnd.AddStudentHandler)
+	mux.HandleFunc("POST /students/import", hnd.ImportStudentsHandler)
 	mux.HandleFunc("PUT /students/{id}", hnd.UpdateStudentHandler)
 	mux.HandleFunc("PATCH /students", hnd.PatchStudentsHandler)
 	mux.HandleFunc("PATCH /students/{id}", hnd.PatchStudentHandler)
 	mux.HandleFunc("DELETE /students", hnd.DeleteStudentsHandler)
 	mux.HandleFunc("DELETE /students/{id}", hnd.DeleteStudentHandler)
+	mux.HandleFunc("POST /students/{id}/restore", hnd.RestoreStudentHandler)
+	mux.HandleFunc("GET /students/{id}/history", hnd.GetStudentHistoryHandler)
+	mux.HandleFunc("GET /students/{id}/grades", hnd.GetStudentGradesHandler)
+	mux.HandleFunc("GET /students/{id}/attendance", hnd.GetStudentAttendanceHandler)
+	mux.HandleFunc("GET /students/{id}/enrollments", hnd.GetStudentEnrollmentsHandler)
+	mux.HandleFunc("GET /students/{id}/guardians", hnd.GetStudentGuardiansHandler)
+	mux.HandleFunc("PUT /students/{id}/guardians/{guardianId}", hnd.LinkStudentGuardianHandler)
+	mux.HandleFunc("DELETE /students/{id}/guardians/{guardianId}", hnd.UnlinkStudentGuardianHandler)
+	mux.HandleFunc("GET /students/{id}/attachments", hnd.GetStudentAttachmentsHandler)
+	mux.HandleFunc("POST /students/{id}/attachments", hnd.AddStudentAttachmentHandler)
 	
 	return mux
 }
@@ -9,14 +9,23 @@ func TeacherRouter() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("GET /teachers", hnd.GetTeachersHandler)
+	mux.HandleFunc("GET /teachers/export", hnd.ExportTeachersHandler)
 	mux.HandleFunc("GET /teachers/{id}", hnd.GetTeacherHandler)
 	mux.HandleFunc("POST /teachers", hnd.AddTeacherHandler)
+	mux.HandleFunc("POST /teachers/import", hnd.ImportTeachersHandler)
 	mux.HandleFunc("PUT /teachers/{id}", hnd.UpdateTeacherHandler)
 	mux.HandleFunc("PATCH /teachers", hnd.PatchTeachersHandler)
 	mux.HandleFunc("PATCH /teachers/{id}", hnd.PatchTeacherHandler)
 	mux.HandleFunc("DELETE /teachers", hnd.DeleteTeachersHandler)
 	mux.HandleFunc("DELETE /teachers/{id}", hnd.DeleteTeacherHandler)
+	mux.HandleFunc("POST /teachers/{id}/restore", hnd.RestoreTeacherHandler)
+	mux.HandleFunc("GET /teachers/{id}/history", hnd.GetTeacherHistoryHandler)
 	mux.HandleFunc("GET /teachers/{id}/students", hnd.GetStudentsByTeacherHandler)
+	mux.HandleFunc("GET /teachers/{id}/timetable", hnd.GetTeacherTimetableHandler)
+	mux.HandleFunc("GET /teachers/{id}/calendar-feeds", hnd.GetTeacherFeedsHandler)
+	mux.HandleFunc("POST /teachers/{id}/calendar-feeds", hnd.AddTeacherFeedHandler)
+	mux.HandleFunc("GET /teachers/{id}/attachments", hnd.GetTeacherAttachmentsHandler)
+	mux.HandleFunc("POST /teachers/{id}/attachments", hnd.AddTeacherAttachmentHandler)
 
 	return mux
 }
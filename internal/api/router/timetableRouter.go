@@ -0,0 +1,27 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func TimetableRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /lessons", hnd.GetLessonsHandler)
+	mux.HandleFunc("POST /lessons", hnd.AddLessonHandler)
+
+	mux.HandleFunc("GET /lessons/{id}", hnd.GetLessonHandler)
+	mux.HandleFunc("PATCH /lessons/{id}", hnd.PatchLessonHandler)
+	mux.HandleFunc("DELETE /lessons/{id}", hnd.DeleteLessonHandler)
+
+	mux.HandleFunc("GET /substitutions", hnd.GetSubstitutionsHandler)
+	mux.HandleFunc("POST /substitutions", hnd.AddSubstitutionHandler)
+	mux.HandleFunc("GET /substitutions/{id}", hnd.GetSubstitutionHandler)
+	mux.HandleFunc("DELETE /substitutions/{id}", hnd.DeleteSubstitutionHandler)
+
+	mux.HandleFunc("GET /periods", hnd.GetPeriodsHandler)
+	mux.HandleFunc("PUT /periods", hnd.ReplacePeriodsHandler)
+
+	return mux
+}
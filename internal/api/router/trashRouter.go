@@ -0,0 +1,14 @@
+package router
+
+import (
+	hnd "WebProject/internal/api/handlers"
+	"net/http"
+)
+
+func TrashRouter() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /trash", hnd.GetTrashHandler)
+
+	return mux
+}
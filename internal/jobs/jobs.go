@@ -0,0 +1,149 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// View — состояние фоновой задачи для ответа клиенту
+type View struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Status     string      `json:"status"`
+	Stage      string      `json:"stage,omitempty"`
+	Processed  int         `json:"processed"`
+	Total      int         `json:"total"`
+	Percent    int         `json:"percent"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+}
+
+// Job — фоновая задача (импорт и т.п.) с прогрессом, который опрашивает клиент
+type Job struct {
+	mu    sync.Mutex
+	owner string
+	view  View
+}
+
+// Owner — пользователь, запустивший задачу
+func (j *Job) Owner() string {
+	return j.owner
+}
+
+// Progress — обновляет этап и количество обработанных элементов
+func (j *Job) Progress(stage string, processed, total int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.view.Stage = stage
+	j.view.Processed = processed
+	j.view.Total = total
+}
+
+// View — копия текущего состояния задачи
+func (j *Job) View() View {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	v := j.view
+	if v.Total > 0 {
+		v.Percent = v.Processed * 100 / v.Total
+	}
+	if v.Status == StatusCompleted {
+		v.Percent = 100
+	}
+	return v
+}
+
+func (j *Job) finish(result interface{}, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.view.FinishedAt = &now
+	j.view.Result = result
+	j.view.Status = StatusCompleted
+	if err != nil {
+		j.view.Status = StatusFailed
+		j.view.Error = err.Error()
+	}
+}
+
+// Store — задачи в памяти процесса; завершённые удаляются через ttl
+type Store struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+	ttl  time.Duration
+}
+
+// Default — общее хранилище задач приложения
+var Default = NewStore(24 * time.Hour)
+
+func NewStore(ttl time.Duration) *Store {
+	s := &Store{jobs: make(map[string]*Job), ttl: ttl}
+	go s.cleanup()
+	return s
+}
+
+func (s *Store) cleanup() {
+	for {
+		time.Sleep(time.Minute)
+		s.mu.Lock()
+		for id, j := range s.jobs {
+			v := j.View()
+			if v.FinishedAt != nil && time.Since(*v.FinishedAt) > s.ttl {
+				delete(s.jobs, id)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Start — создаёт задачу и запускает run в отдельной горутине
+func (s *Store) Start(jobType, owner string, run func(j *Job) (interface{}, error)) *Job {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	j := &Job{
+		owner: owner,
+		view: View{
+			ID:        hex.EncodeToString(buf),
+			Type:      jobType,
+			Status:    StatusPending,
+			CreatedAt: time.Now(),
+		},
+	}
+
+	s.mu.Lock()
+	s.jobs[j.view.ID] = j
+	s.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				j.finish(nil, fmt.Errorf("job panicked: %v", rec))
+			}
+		}()
+		j.mu.Lock()
+		j.view.Status = StatusRunning
+		j.mu.Unlock()
+		j.finish(run(j))
+	}()
+	return j
+}
+
+// Get — задача по id
+func (s *Store) Get(id string) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	return j, ok
+}
@@ -5,18 +5,22 @@ import (
 )
 
 type Exec struct {
-	ID                int            `json:"id" db:"id"`
-	FirstName         string         `json:"firstName" db:"firstName"`
-	LastName          string         `json:"lastName" db:"lastName"`
-	Email             string         `json:"email" db:"email"`
-	Username          string         `json:"username" db:"username"`
-	Password          string         `json:"password" db:"password"`
+	ID                int            `json:"id" db:"id" filter:"eq,ne,in,nin,gt,gte,lt,lte"`
+	FirstName         string         `json:"firstName" db:"firstName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	LastName          string         `json:"lastName" db:"lastName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	Email             string         `json:"email" db:"email" validate:"required,email,max=100" filter:"eq,ne,like,nlike" sort:"true"`
+	Username          string         `json:"username" db:"username" validate:"required,min=3,max=50,pattern=^[A-Za-z0-9_.-]+$" filter:"eq,ne,like,in" sort:"true"`
+	Password          string         `json:"password" db:"password" validate:"min=8" export:"-"`
 	PasswordChangedAt sql.NullString `json:"passwordChangedAt" db:"passwordChangedAt"`
-	UserCreatedAt     sql.NullString `json:"userCreatedAt" db:"userCreatedAt"`
-	CodeExpiresAt     sql.NullString `json:"tokenExpiresAt" db:"tokenExpiresAt"`
-	ResetCode         sql.NullString `json:"resetCode" db:"passwordResetToken"`
-	InactiveStatus    bool           `json:"inactiveStatus" db:"inactiveStatus"`
-	Role              string         `json:"role" db:"role"`
+	UserCreatedAt     sql.NullString `json:"userCreatedAt" db:"userCreatedAt" filter:"gt,gte,lt,lte,null"`
+	CodeExpiresAt     sql.NullString `json:"tokenExpiresAt" db:"tokenExpiresAt" export:"-"`
+	ResetCode         sql.NullString `json:"resetCode" db:"passwordResetToken" export:"-"`
+	InactiveStatus    bool           `json:"inactiveStatus" db:"inactiveStatus" filter:"eq"`
+	Role              string         `json:"role" db:"role" validate:"required,oneof=admin manager member teacher parent" filter:"eq,ne,in,nin" sort:"true"`
+	TeacherID         *int           `json:"teacherId" db:"teacherId" filter:"eq,null"`
+	GuardianID        *int           `json:"guardianId" db:"guardianId" filter:"eq,null"`
+	Version           int            `json:"version" db:"version" readonly:"true"`
+	UpdatedAt         *string        `json:"updatedAt" db:"updatedAt" readonly:"true"`
 }
 
 type UpdatePasswordRequest struct {
@@ -0,0 +1,78 @@
+package models
+
+// AcademicYear — учебный год ("2024-2025"); классы ссылаются на него по имени
+type AcademicYear struct {
+	ID        int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	Name      string  `json:"name" db:"name" validate:"required,pattern=^[0-9]{4}-[0-9]{4}$" filter:"eq,ne,in,nin" sort:"true"`
+	StartDate string  `json:"startDate" db:"startDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	EndDate   string  `json:"endDate" db:"endDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	Status    string  `json:"status" db:"status" validate:"required,oneof=planned current closed" filter:"eq,ne,in,nin" sort:"true"`
+	Terms     []Term  `json:"terms,omitempty"`
+	Version   int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// Term — четверть (триместр) учебного года с номером number
+type Term struct {
+	Number    int    `json:"number" validate:"min=1,max=4"`
+	Name      string `json:"name" validate:"required,max=50"`
+	StartDate string `json:"startDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+	EndDate   string `json:"endDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$"`
+}
+
+// Enrollment — класс студента в учебном году; status — чем год закончился (enrolled, пока год идёт)
+type Enrollment struct {
+	ID             int     `json:"id"`
+	StudentID      int     `json:"studentId"`
+	FirstName      string  `json:"firstName"`
+	LastName       string  `json:"lastName"`
+	AcademicYearID int     `json:"academicYearId"`
+	AcademicYear   string  `json:"academicYear"`
+	ClassID        *int    `json:"classId"`
+	Class          *string `json:"class"`
+	Status         string  `json:"status"`
+	UpdatedAt      *string `json:"updatedAt"`
+}
+
+// PromotionRequest — параметры перевода: откуда (по умолчанию предыдущий учебный год), кто остаётся
+// на второй год и после какого класса студенты выпускаются
+type PromotionRequest struct {
+	FromYearID *int  `json:"fromYearId"`
+	Retain     []int `json:"retain"`
+	FinalGrade int   `json:"finalGrade"`
+}
+
+// PromotionClass — класс нового учебного года, в который переходят студенты; id пуст, пока класс не создан
+type PromotionClass struct {
+	ID                *int   `json:"id"`
+	Name              string `json:"name"`
+	GradeLevel        int    `json:"gradeLevel"`
+	HomeroomTeacherID *int   `json:"homeroomTeacherId"`
+	Capacity          int    `json:"capacity"`
+	Enrolled          int    `json:"enrolled"`
+	Create            bool   `json:"create"`
+}
+
+// PromotionMove — строка перевода: promote, retain или graduate
+type PromotionMove struct {
+	StudentID int    `json:"studentId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	FromClass string `json:"fromClass"`
+	ToClass   string `json:"toClass,omitempty"`
+	Action    string `json:"action"`
+}
+
+// PromotionResult — разница до и после перевода; при DryRun или предупреждениях база не изменяется
+type PromotionResult struct {
+	DryRun    bool             `json:"dryRun"`
+	Applied   bool             `json:"applied"`
+	FromYear  string           `json:"fromYear"`
+	ToYear    string           `json:"toYear"`
+	Promoted  int              `json:"promoted"`
+	Retained  int              `json:"retained"`
+	Graduated int              `json:"graduated"`
+	Classes   []PromotionClass `json:"classes"`
+	Students  []PromotionMove  `json:"students"`
+	Warnings  []string         `json:"warnings"`
+}
@@ -0,0 +1,37 @@
+package models
+
+// Announcement — объявление для аудитории audience: school — вся школа, class — класс classId (его учителя и родители),
+// teachers — все учителя, execs — учётные записи recipientIds. Видно с publishAt до expiresAt ("2025-03-14 08:00:00");
+// с sendEmail после публикации уходит письмом. readAt — когда объявление прочитал текущий пользователь
+type Announcement struct {
+	ID           int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	Title        string  `json:"title" db:"title" validate:"required,max=200" filter:"eq,like" sort:"true"`
+	Body         string  `json:"body" db:"body" validate:"required,max=20000"`
+	Audience     string  `json:"audience" db:"audience" validate:"required,oneof=school class teachers execs" filter:"eq,ne,in,nin" sort:"true"`
+	ClassID      *int    `json:"classId" db:"classId" filter:"eq,null"`
+	PublishAt    string  `json:"publishAt" db:"publishAt" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}(:[0-9]{2})?$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	ExpiresAt    *string `json:"expiresAt" db:"expiresAt" validate:"pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}(:[0-9]{2})?$" filter:"gt,gte,lt,lte,null"`
+	SendEmail    bool    `json:"sendEmail" db:"sendEmail" filter:"eq"`
+	EmailedAt    *string `json:"emailedAt" db:"emailedAt" readonly:"true" filter:"null"`
+	CreatedBy    *int    `json:"createdBy" db:"createdBy" filter:"eq"`
+	RecipientIDs []int   `json:"recipientIds,omitempty"`
+	ReadAt       *string `json:"readAt"`
+	Version      int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt    *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// AnnouncementReader — учётная запись, прочитавшая объявление
+type AnnouncementReader struct {
+	ExecID    int    `json:"execId"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Role      string `json:"role"`
+	ReadAt    string `json:"readAt"`
+}
+
+// AnnouncementReceipts — сколько учётных записей входит в аудиторию объявления и кто из них его прочитал
+type AnnouncementReceipts struct {
+	Recipients int                  `json:"recipients"`
+	Read       int                  `json:"read"`
+	Readers    []AnnouncementReader `json:"readers"`
+}
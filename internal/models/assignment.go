@@ -0,0 +1,20 @@
+package models
+
+// Assignment — учитель ведёт предмет в классе; у одного учителя может быть много назначений
+type Assignment struct {
+	ID           int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	TeacherID    int     `json:"teacherId" db:"teacherId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	ClassID      int     `json:"classId" db:"classId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	Subject      string  `json:"subject" db:"subject" validate:"required,max=50" filter:"eq,ne,like,in,nin" sort:"true"`
+	HoursPerWeek *int    `json:"hoursPerWeek" db:"hoursPerWeek" validate:"min=1,max=40" filter:"eq,gt,gte,lt,lte,null"`
+	Version      int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt    *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// ClassStudents — студенты одного класса учителя и предметы, которые он ведёт в этом классе
+type ClassStudents struct {
+	ClassID  int       `json:"classId"`
+	Class    string    `json:"class"`
+	Subjects []string  `json:"subjects"`
+	Students []Student `json:"students"`
+}
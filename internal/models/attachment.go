@@ -0,0 +1,23 @@
+package models
+
+// Attachment — файл в карточке студента, учителя или учётной записи (ownerType student, teacher, exec).
+// kind photo — фотография владельца, document — прочие документы
+type Attachment struct {
+	ID          int    `json:"id"`
+	OwnerType   string `json:"ownerType"`
+	OwnerID     int    `json:"ownerId"`
+	Kind        string `json:"kind"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	StorageKey  string `json:"-"`
+	UploadedBy  *int   `json:"uploadedBy"`
+	CreatedAt   string `json:"createdAt"`
+}
+
+// SignedURL — ссылка на скачивание без токена, действующая до expiresAt
+type SignedURL struct {
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expiresAt"`
+}
@@ -0,0 +1,54 @@
+package models
+
+// Attendance — отметка посещаемости студента за день
+type Attendance struct {
+	ID         int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	StudentID  int     `json:"studentId" db:"studentId" validate:"required" filter:"eq,in,nin" sort:"true"`
+	ClassID    int     `json:"classId" db:"classId" validate:"required" filter:"eq,in,nin" sort:"true"`
+	Date       string  `json:"date" db:"date" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	Status     string  `json:"status" db:"status" validate:"required,oneof=present absent late excused" filter:"eq,ne,in,nin" sort:"true"`
+	Reason     *string `json:"reason" db:"reason" validate:"max=255" filter:"null"`
+	RecordedBy *int    `json:"recordedBy" db:"recordedBy" readonly:"true"`
+	Version    int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt  *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// AttendanceEntry — строка отметки класса за день
+type AttendanceEntry struct {
+	StudentID int     `json:"studentId"`
+	Status    string  `json:"status"`
+	Reason    *string `json:"reason"`
+}
+
+// AttendanceSummary — посещаемость студента за период; rate — доля дней, когда студент был на занятиях (present или late)
+type AttendanceSummary struct {
+	StudentID   int      `json:"studentId"`
+	FirstName   string   `json:"firstName"`
+	LastName    string   `json:"lastName"`
+	Class       string   `json:"class"`
+	Days        int      `json:"days"`
+	Present     int      `json:"present"`
+	Late        int      `json:"late"`
+	Absent      int      `json:"absent"`
+	Excused     int      `json:"excused"`
+	Rate        *float64 `json:"rate"`
+	AbsenceRate *float64 `json:"absenceRate"`
+}
+
+// StudentAttendance — сводка и отметки студента за период
+type StudentAttendance struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Summary AttendanceSummary `json:"summary"`
+	Records []Attendance      `json:"records"`
+}
+
+// ClassAttendance — посещаемость класса за период: итог по классу и по каждому студенту
+type ClassAttendance struct {
+	ClassID  int                 `json:"classId"`
+	Class    string              `json:"class"`
+	From     string              `json:"from"`
+	To       string              `json:"to"`
+	Rate     *float64            `json:"rate"`
+	Students []AttendanceSummary `json:"students"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// Holiday — каникулы или праздник; уроки в эти дни исключаются из календарных подписок
+type Holiday struct {
+	ID        int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	Name      string  `json:"name" db:"name" validate:"required,max=100" filter:"eq,like" sort:"true"`
+	StartDate string  `json:"startDate" db:"startDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	EndDate   string  `json:"endDate" db:"endDate" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	Version   int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// CalendarFeed — подписка на расписание учителя или класса; token и url возвращаются только при создании
+type CalendarFeed struct {
+	ID             int     `json:"id"`
+	OwnerType      string  `json:"ownerType"`
+	OwnerID        int     `json:"ownerId"`
+	Token          string  `json:"token,omitempty"`
+	URL            string  `json:"url,omitempty"`
+	CreatedBy      *int    `json:"createdBy"`
+	CreatedAt      string  `json:"createdAt"`
+	RevokedAt      *string `json:"revokedAt"`
+	LastAccessedAt *string `json:"lastAccessedAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+// Class — учебный класс; студенты ссылаются на него через classId
+type Class struct {
+	ID                int     `json:"id" db:"id" filter:"eq,ne,in,nin,gt,gte,lt,lte"`
+	Name              string  `json:"name" db:"name" validate:"required,pattern=^(1[0-2]|[1-9])[A-Z]$" filter:"eq,ne,like,in,nin" sort:"true"`
+	GradeLevel        int     `json:"gradeLevel" db:"gradeLevel" validate:"min=1,max=12" filter:"eq,ne,in,nin,gt,gte,lt,lte" sort:"true"`
+	AcademicYear      string  `json:"academicYear" db:"academicYear" validate:"required,pattern=^[0-9]{4}-[0-9]{4}$" filter:"eq,ne,in,nin" sort:"true"`
+	HomeroomTeacherID *int    `json:"homeroomTeacherId" db:"homeroomTeacherId" filter:"eq,ne,in,nin,null"`
+	Capacity          int     `json:"capacity" db:"capacity" validate:"min=1,max=100" filter:"eq,ne,gt,gte,lt,lte" sort:"true"`
+	Enrolled          *int    `json:"enrolled,omitempty"`
+	Version           int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt         *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
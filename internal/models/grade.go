@@ -0,0 +1,77 @@
+package models
+
+// Assessment — контрольная, тест или другая оцениваемая работа класса по предмету
+type Assessment struct {
+	ID        int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	Title     string  `json:"title" db:"title" validate:"required,max=100" filter:"eq,like" sort:"true"`
+	Subject   string  `json:"subject" db:"subject" validate:"required,max=50" filter:"eq,ne,in,nin" sort:"true"`
+	ClassID   int     `json:"classId" db:"classId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	TeacherID *int    `json:"teacherId" db:"teacherId" filter:"eq,ne,in,nin,null"`
+	Date      string  `json:"date" db:"date" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	Term      int     `json:"term" db:"term" validate:"required,min=1,max=4" filter:"eq,in" sort:"true"`
+	Weight    float64 `json:"weight" db:"weight" validate:"min=0.1,max=10" filter:"eq,gt,gte,lt,lte"`
+	MaxScore  float64 `json:"maxScore" db:"maxScore" validate:"required,min=1,max=1000"`
+	Version   int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// Grade — оценка студента за работу; пустой score — работа ещё не оценена
+type Grade struct {
+	ID           int      `json:"id" db:"id"`
+	AssessmentID int      `json:"assessmentId" db:"assessmentId" validate:"required"`
+	StudentID    int      `json:"studentId" db:"studentId" validate:"required"`
+	Score        *float64 `json:"score" db:"score" validate:"min=0"`
+	Comment      *string  `json:"comment" db:"comment" validate:"max=500"`
+	Version      int      `json:"version" db:"version" readonly:"true"`
+	UpdatedAt    *string  `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// GradeEntry — строка ввода оценок за работу для всего класса
+type GradeEntry struct {
+	StudentID int      `json:"studentId"`
+	Score     *float64 `json:"score"`
+	Comment   *string  `json:"comment"`
+}
+
+// StudentGrade — оценка вместе с работой, к которой она относится
+type StudentGrade struct {
+	Assessment Assessment `json:"assessment"`
+	Score      *float64   `json:"score"`
+	Comment    *string    `json:"comment"`
+}
+
+// TermAverage — взвешенное среднее за четверть в процентах от максимального балла
+type TermAverage struct {
+	Term    int            `json:"term"`
+	Average *float64       `json:"average"`
+	Grades  []StudentGrade `json:"grades"`
+}
+
+// SubjectGrades — оценки студента по одному предмету, по четвертям
+type SubjectGrades struct {
+	Subject string        `json:"subject"`
+	Terms   []TermAverage `json:"terms"`
+}
+
+// StudentGrades — ответ GET /students/{id}/grades
+type StudentGrades struct {
+	StudentID int             `json:"studentId"`
+	Subjects  []SubjectGrades `json:"subjects"`
+}
+
+// GradebookRow — строка журнала: оценки студента по работам и взвешенное среднее
+type GradebookRow struct {
+	Student Student          `json:"student"`
+	Scores  map[int]*float64 `json:"scores"`
+	Average *float64         `json:"average"`
+}
+
+// Gradebook — журнал класса: работы (столбцы) и студенты (строки)
+type Gradebook struct {
+	ClassID     int            `json:"classId"`
+	Class       string         `json:"class"`
+	Subject     string         `json:"subject,omitempty"`
+	Term        int            `json:"term,omitempty"`
+	Assessments []Assessment   `json:"assessments"`
+	Rows        []GradebookRow `json:"rows"`
+}
@@ -0,0 +1,23 @@
+package models
+
+// Guardian — родитель или опекун; studentIds — студенты, за которых он отвечает
+type Guardian struct {
+	ID               int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	FirstName        string  `json:"firstName" db:"firstName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	LastName         string  `json:"lastName" db:"lastName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	Phone            *string `json:"phone" db:"phone" validate:"max=20,pattern=^\\+?[0-9 ()-]+$" filter:"eq,like,null"`
+	Email            *string `json:"email" db:"email" validate:"email,max=100" filter:"eq,ne,like,nlike,null"`
+	Relationship     string  `json:"relationship" db:"relationship" validate:"required,oneof=mother father grandparent sibling guardian other" filter:"eq,ne,in,nin" sort:"true"`
+	EmergencyContact bool    `json:"emergencyContact" db:"emergencyContact" filter:"eq"`
+	CustodyNotes     *string `json:"custodyNotes" db:"custodyNotes" validate:"max=500"`
+	StudentIDs       []int   `json:"studentIds"`
+	Version          int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt        *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// ChildProfile — ребёнок в родительском кабинете: карточка студента, учебный год и классный руководитель
+type ChildProfile struct {
+	Student
+	AcademicYear    *string `json:"academicYear"`
+	HomeroomTeacher *string `json:"homeroomTeacher"`
+}
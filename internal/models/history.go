@@ -0,0 +1,17 @@
+package models
+
+import "encoding/json"
+
+// HistoryEntry — запись журнала изменений: состояние до/после, diff по полям и автор изменения
+type HistoryEntry struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entityId"`
+	Action    string          `json:"action"`
+	ActorID   *string         `json:"actorId"`
+	ActorName *string         `json:"actorName"`
+	ChangedAt string          `json:"changedAt"`
+	Before    json.RawMessage `json:"before"`
+	After     json.RawMessage `json:"after"`
+	Diff      json.RawMessage `json:"diff"`
+}
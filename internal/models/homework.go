@@ -0,0 +1,69 @@
+package models
+
+// Homework — домашнее задание классу по предмету со сроком сдачи dueAt ("2025-03-14 18:00:00").
+// С maxScore работы оцениваются баллами; assessmentId — работа журнала, куда попадают баллы
+type Homework struct {
+	ID           int            `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	ClassID      int            `json:"classId" db:"classId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	Subject      string         `json:"subject" db:"subject" validate:"required,max=50" filter:"eq,ne,in,nin" sort:"true"`
+	TeacherID    *int           `json:"teacherId" db:"teacherId" filter:"eq,ne,in,nin,null"`
+	Title        string         `json:"title" db:"title" validate:"required,max=200" filter:"eq,like" sort:"true"`
+	Instructions *string        `json:"instructions" db:"instructions" validate:"max=10000"`
+	DueAt        string         `json:"dueAt" db:"dueAt" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}(:[0-9]{2})?$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	MaxScore     *float64       `json:"maxScore" db:"maxScore" validate:"min=1,max=1000"`
+	AssessmentID *int           `json:"assessmentId" db:"assessmentId" readonly:"true" filter:"null"`
+	Attachments  []HomeworkFile `json:"attachments,omitempty"`
+	Version      int            `json:"version" db:"version" readonly:"true"`
+	UpdatedAt    *string        `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// HomeworkFile — файл, приложенный учителем к заданию
+type HomeworkFile struct {
+	ID          int    `json:"id"`
+	HomeworkID  int    `json:"homeworkId"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	StorageKey  string `json:"-"`
+	UploadedAt  string `json:"uploadedAt"`
+}
+
+// HomeworkSubmission — работа студента по заданию: файл, отметка о сдаче после срока, отзыв и балл учителя
+type HomeworkSubmission struct {
+	ID          int      `json:"id"`
+	HomeworkID  int      `json:"homeworkId"`
+	StudentID   int      `json:"studentId"`
+	FirstName   string   `json:"firstName"`
+	LastName    string   `json:"lastName"`
+	FileName    string   `json:"fileName"`
+	ContentType string   `json:"contentType"`
+	Size        int64    `json:"size"`
+	StorageKey  string   `json:"-"`
+	Comment     *string  `json:"comment"`
+	SubmittedAt string   `json:"submittedAt"`
+	Late        bool     `json:"late"`
+	Score       *float64 `json:"score"`
+	Feedback    *string  `json:"feedback"`
+	GradedAt    *string  `json:"gradedAt"`
+	Version     int      `json:"version"`
+	UpdatedAt   *string  `json:"updatedAt"`
+}
+
+// SubmissionFeedback — отзыв учителя о работе; пустой score снимает оценку
+type SubmissionFeedback struct {
+	Score    *float64 `json:"score" validate:"min=0"`
+	Feedback *string  `json:"feedback" validate:"max=2000"`
+}
+
+// HomeworkGradebook — параметры работы журнала, создаваемой по заданию; без term четверть ищется по сроку сдачи,
+// без weight вес равен 1
+type HomeworkGradebook struct {
+	Term   int     `json:"term"`
+	Weight float64 `json:"weight"`
+}
+
+// ChildHomework — задание для родительского кабинета вместе с работой ребёнка, если она сдана
+type ChildHomework struct {
+	Homework
+	Submission *HomeworkSubmission `json:"submission"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// ImportRowError — ошибка в строке импортируемого файла (нумерация строк как в Excel, заголовок — строка 1)
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ImportRow — действие над строкой файла: create или update (upsert по email)
+type ImportRow struct {
+	Row    int    `json:"row"`
+	Action string `json:"action"`
+	ID     int    `json:"id,omitempty"`
+	Email  string `json:"email"`
+}
+
+// ImportResult — итог импорта; при DryRun или ошибках в строках база не изменяется
+type ImportResult struct {
+	DryRun   bool              `json:"dryRun"`
+	Applied  bool              `json:"applied"`
+	Total    int               `json:"total"`
+	Created  int               `json:"created"`
+	Updated  int               `json:"updated"`
+	Failed   int               `json:"failed"`
+	Mapping  map[string]string `json:"mapping"`
+	Unmapped []string          `json:"unmapped"`
+	Rows     []ImportRow       `json:"rows"`
+	Errors   []ImportRowError  `json:"errors"`
+}
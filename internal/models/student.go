@@ -1,9 +1,13 @@
 package models
 
 type Student struct {
-	ID        int    `json:"id" db:"id"`
-	FirstName string `json:"firstName" db:"firstName"`
-	LastName  string `json:"lastName" db:"lastName"`
-	Email     string `json:"email" db:"email"`
-	Class     string `json:"class" db:"class"`
+	ID        int     `json:"id" db:"id" filter:"eq,ne,in,nin,gt,gte,lt,lte"`
+	FirstName string  `json:"firstName" db:"firstName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	LastName  string  `json:"lastName" db:"lastName" validate:"required,max=50" filter:"eq,ne,like,nlike,in,nin" sort:"true"`
+	Email     string  `json:"email" db:"email" validate:"required,email,max=100" filter:"eq,ne,like,nlike,null" sort:"true"`
+	Class     string  `json:"class" db:"class" validate:"pattern=^(1[0-2]|[1-9])[A-Za-z]$" filter:"eq,ne,like,in,nin,null" sort:"true"`
+	ClassID   *int    `json:"classId" db:"classId" filter:"eq,ne,in,nin,null"`
+	Version   int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+	DeletedAt *string `json:"deletedAt,omitempty" db:"deletedAt" readonly:"true"`
 }
@@ -0,0 +1,46 @@
+package models
+
+// Lesson — еженедельный урок расписания: класс, предмет, учитель и кабинет в день недели и номер урока
+type Lesson struct {
+	ID        int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	ClassID   int     `json:"classId" db:"classId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	Subject   string  `json:"subject" db:"subject" validate:"required,max=50" filter:"eq,ne,in,nin" sort:"true"`
+	TeacherID int     `json:"teacherId" db:"teacherId" validate:"required" filter:"eq,ne,in,nin" sort:"true"`
+	Room      *string `json:"room" db:"room" validate:"max=20" filter:"eq,ne,in,nin,null"`
+	Weekday   int     `json:"weekday" db:"weekday" validate:"required,min=1,max=7" filter:"eq,ne,in,nin" sort:"true"`
+	Period    int     `json:"period" db:"period" validate:"required,min=1,max=12" filter:"eq,ne,in,nin,gt,gte,lt,lte" sort:"true"`
+	ValidFrom string  `json:"validFrom" db:"validFrom" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	ValidTo   *string `json:"validTo" db:"validTo" validate:"pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte,null"`
+	Version   int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// Period — звонок: время начала и конца урока с номером number
+type Period struct {
+	Number    int    `json:"number"`
+	StartTime string `json:"startTime"`
+	EndTime   string `json:"endTime"`
+}
+
+// Substitution — замена урока на дату; пустой substituteTeacherId — урок отменён
+type Substitution struct {
+	ID                  int     `json:"id" db:"id" filter:"eq,ne,in,nin"`
+	LessonID            int     `json:"lessonId" db:"lessonId" validate:"required" filter:"eq,in,nin" sort:"true"`
+	Date                string  `json:"date" db:"date" validate:"required,pattern=^[0-9]{4}-[0-9]{2}-[0-9]{2}$" filter:"eq,gt,gte,lt,lte" sort:"true"`
+	SubstituteTeacherID *int    `json:"substituteTeacherId" db:"substituteTeacherId" filter:"eq,in,nin,null"`
+	Reason              *string `json:"reason" db:"reason" validate:"max=255"`
+	CreatedBy           *int    `json:"createdBy" db:"createdBy"`
+	Version             int     `json:"version" db:"version" readonly:"true"`
+	UpdatedAt           *string `json:"updatedAt" db:"updatedAt" readonly:"true"`
+}
+
+// TimetableSlot — урок в представлении расписания учителя или класса; date и substitution заполняются для конкретной недели
+type TimetableSlot struct {
+	Lesson
+	Class        string        `json:"class"`
+	Teacher      string        `json:"teacher"`
+	StartTime    string        `json:"startTime"`
+	EndTime      string        `json:"endTime"`
+	Date         string        `json:"date,omitempty"`
+	Substitution *Substitution `json:"substitution,omitempty"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Trash — мягко удалённые записи, ожидающие окончательного удаления
+type Trash struct {
+	RetentionDays int       `json:"retentionDays"`
+	Students      []Student `json:"students"`
+	Teachers      []Teacher `json:"teachers"`
+}
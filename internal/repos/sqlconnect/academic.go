@@ -0,0 +1,841 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	yearPlanned = "planned"
+	yearCurrent = "current"
+	yearClosed  = "closed"
+
+	enrollmentEnrolled  = "enrolled"
+	enrollmentPromoted  = "promoted"
+	enrollmentRetained  = "retained"
+	enrollmentGraduated = "graduated"
+
+	// defaultFinalGrade — выпускной класс, если в запросе перевода finalGrade не указан
+	defaultFinalGrade = 11
+)
+
+// GetAllAcademicYears — учебные годы (?status=current, ?startDate[gte]=)
+func GetAllAcademicYears(r *http.Request) ([]mod.AcademicYear, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.AcademicYear{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM academic_years WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.AcademicYear{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.AcademicYear{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	years := make([]mod.AcademicYear, 0)
+	for rows.Next() {
+		var y mod.AcademicYear
+		err := rows.Scan(utils.GetScanFields(&y, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		years = append(years, y)
+	}
+
+	years, info := utils.Paginate(r, page, years)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return years, info, nil
+}
+
+// FindAcademicYearById — учебный год вместе с четвертями
+func FindAcademicYearById(id int) (mod.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.AcademicYear{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	year, err := findAcademicYear(db, id)
+	if err != nil {
+		return mod.AcademicYear{}, err
+	}
+	year.Terms, err = yearTerms(db, id)
+	if err != nil {
+		return mod.AcademicYear{}, err
+	}
+	return year, nil
+}
+
+func findAcademicYear(q queryer, id int) (mod.AcademicYear, error) {
+	var year mod.AcademicYear
+	err := q.QueryRow(utils.GenerateSQL(mod.AcademicYear{}, "select"), id).Scan(utils.GetStructFields(&year, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.AcademicYear{}, utils.ErrorHandler(err, "Academic year not found")
+		}
+		return mod.AcademicYear{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return year, nil
+}
+
+// SaveAcademicYears — создание учебных годов (транзакция); без status год создаётся как planned
+func SaveAcademicYears(r *http.Request) ([]mod.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newYears []mod.AcademicYear
+	err = json.NewDecoder(r.Body).Decode(&newYears)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newYears {
+		if newYears[i].Status == "" {
+			newYears[i].Status = yearPlanned
+		}
+		newYears[i].Terms = nil
+	}
+	err = utils.ValidateSlice(newYears)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(utils.GenerateSQL(mod.AcademicYear{}, "insert"))
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+
+		for i := range newYears {
+			err = checkAcademicYear(tx, newYears[i])
+			if err != nil {
+				return withIndex(err, i)
+			}
+			res, err := stmt.Exec(utils.GetStructFields(newYears[i], true, false)...)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error inserting academic year")
+			}
+			lastId, err := res.LastInsertId()
+			if err != nil {
+				return utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			newYears[i].ID = int(lastId)
+			newYears[i].Version = 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newYears, nil
+}
+
+// PatchAcademicYearById — частичное обновление учебного года; имя года, в котором уже есть классы, не меняется
+func PatchAcademicYearById(id int, patch utils.Patch, expectedVersion int) (mod.AcademicYear, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.AcademicYear{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.AcademicYear
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.AcademicYear](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Academic year not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching academic year")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		patched.Terms = nil
+		err = utils.Validate(patched)
+		if err != nil {
+			return err
+		}
+		if patched.Name != existing.Name {
+			var classes int
+			err = tx.QueryRow("SELECT COUNT(*) FROM classes WHERE academicYear = ?", existing.Name).Scan(&classes)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error checking classes")
+			}
+			if classes > 0 {
+				return &utils.ValidationError{Errors: []utils.FieldError{{Field: "name", Message: "cannot be changed while the year has classes"}}}
+			}
+		}
+		err = checkAcademicYear(tx, patched)
+		if err != nil {
+			return err
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.AcademicYear{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating academic year")
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.AcademicYear{}, err
+	}
+	return patched, nil
+}
+
+// DeleteAcademicYearById — удаление учебного года без классов и зачислений; четверти удаляются вместе с ним
+func DeleteAcademicYearById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.AcademicYear](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Academic year not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching academic year")
+		}
+		var classes, enrollments int
+		err = tx.QueryRow("SELECT COUNT(*) FROM classes WHERE academicYear = ?", existing.Name).Scan(&classes)
+		if err == nil {
+			err = tx.QueryRow("SELECT COUNT(*) FROM enrollments WHERE academicYearId = ?", id).Scan(&enrollments)
+		}
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking academic year usage")
+		}
+		if classes > 0 || enrollments > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Academic year %s still has %d classes and %d enrollments", existing.Name, classes, enrollments))
+		}
+		_, err = tx.Exec(utils.GenerateSQL(mod.AcademicYear{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting academic year")
+		}
+		return nil
+	})
+}
+
+// checkAcademicYear — проверки, которым нужна база: даты, уникальность имени и единственный текущий год
+func checkAcademicYear(q queryer, year mod.AcademicYear) error {
+	var errs []utils.FieldError
+	start, errStart := time.Parse(time.DateOnly, year.StartDate)
+	end, errEnd := time.Parse(time.DateOnly, year.EndDate)
+	if errStart != nil || errEnd != nil {
+		errs = append(errs, utils.FieldError{Field: "startDate", Message: "invalid date"})
+	} else if !end.After(start) {
+		errs = append(errs, utils.FieldError{Field: "endDate", Message: "must be after startDate"})
+	}
+	if first, _ := strconv.Atoi(year.Name[:4]); errStart == nil && start.Year() != first {
+		errs = append(errs, utils.FieldError{Field: "startDate", Message: "does not match year " + year.Name})
+	}
+
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM academic_years WHERE name = ? AND id <> ?", year.Name, year.ID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking academic year name")
+	}
+	if n > 0 {
+		errs = append(errs, utils.FieldError{Field: "name", Message: "already exists"})
+	}
+	if year.Status == yearCurrent {
+		var current string
+		err = q.QueryRow("SELECT name FROM academic_years WHERE status = ? AND id <> ? LIMIT 1", yearCurrent, year.ID).Scan(&current)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrorHandler(err, "Error checking current academic year")
+		}
+		if err == nil {
+			errs = append(errs, utils.FieldError{Field: "status", Message: "academic year " + current + " is already current"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// GetTerms — четверти учебного года по порядку
+func GetTerms(yearID int) ([]mod.Term, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	_, err = findAcademicYear(db, yearID)
+	if err != nil {
+		return nil, err
+	}
+	return yearTerms(db, yearID)
+}
+
+func yearTerms(db *sql.DB, yearID int) ([]mod.Term, error) {
+	rows, err := db.Query("SELECT number, name, startDate, endDate FROM terms WHERE academicYearId = ? ORDER BY number", yearID)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying terms")
+	}
+	defer rows.Close()
+
+	terms := make([]mod.Term, 0)
+	for rows.Next() {
+		var t mod.Term
+		err = rows.Scan(&t.Number, &t.Name, &t.StartDate, &t.EndDate)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning terms")
+		}
+		terms = append(terms, t)
+	}
+	return terms, rows.Err()
+}
+
+// ReplaceTerms — четверти учебного года целиком: номера без повторов, даты внутри года и без пересечений
+func ReplaceTerms(yearID int, terms []mod.Term) ([]mod.Term, error) {
+	err := utils.ValidateSlice(terms)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	sorted := append([]mod.Term(nil), terms...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+	err = withTx(db, func(tx *sql.Tx) error {
+		year, err := selectForUpdate[mod.AcademicYear](tx, yearID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Academic year not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching academic year")
+		}
+
+		var errs []utils.FieldError
+		seen := make(map[int]bool)
+		for i, t := range terms {
+			index := i
+			_, errStart := time.Parse(time.DateOnly, t.StartDate)
+			_, errEnd := time.Parse(time.DateOnly, t.EndDate)
+			switch {
+			case seen[t.Number]:
+				errs = append(errs, utils.FieldError{Index: &index, Field: "number", Message: "duplicate term"})
+			case errStart != nil || errEnd != nil:
+				errs = append(errs, utils.FieldError{Index: &index, Field: "startDate", Message: "invalid date"})
+			case t.EndDate < t.StartDate:
+				errs = append(errs, utils.FieldError{Index: &index, Field: "endDate", Message: "must not be before startDate"})
+			case t.StartDate < year.StartDate || t.EndDate > year.EndDate:
+				errs = append(errs, utils.FieldError{Index: &index, Field: "startDate", Message: "must be within academic year " + year.Name})
+			}
+			seen[t.Number] = true
+		}
+		for i := 1; i < len(sorted) && len(errs) == 0; i++ {
+			if sorted[i].StartDate <= sorted[i-1].EndDate {
+				errs = append(errs, utils.FieldError{Field: "startDate", Message: fmt.Sprintf("term %d overlaps term %d", sorted[i].Number, sorted[i-1].Number)})
+			}
+		}
+		if len(errs) > 0 {
+			return &utils.ValidationError{Errors: errs}
+		}
+
+		_, err = tx.Exec("DELETE FROM terms WHERE academicYearId = ?", yearID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error replacing terms")
+		}
+		for _, t := range sorted {
+			_, err = tx.Exec("INSERT INTO terms (academicYearId, number, name, startDate, endDate) VALUES (?, ?, ?, ?, ?)",
+				yearID, t.Number, strings.TrimSpace(t.Name), t.StartDate, t.EndDate)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error replacing terms")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sorted, nil
+}
+
+// enrollmentSQL — зачисления с именем студента, учебного года и класса
+const enrollmentSQL = `SELECT e.id, e.studentId, s.firstName, s.lastName, e.academicYearId, y.name, e.classId, c.name, e.status, e.updatedAt
+	FROM enrollments e
+	JOIN students s ON s.id = e.studentId
+	JOIN academic_years y ON y.id = e.academicYearId
+	LEFT JOIN classes c ON c.id = e.classId`
+
+// GetYearEnrollments — зачисления учебного года (?classId=, ?status=)
+func GetYearEnrollments(yearID int, classID *int, status string) ([]mod.Enrollment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	_, err = findAcademicYear(db, yearID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := enrollmentSQL + " WHERE e.academicYearId = ? AND s.deletedAt IS NULL"
+	args := []interface{}{yearID}
+	if classID != nil {
+		query += " AND e.classId = ?"
+		args = append(args, *classID)
+	}
+	if status != "" {
+		query += " AND e.status = ?"
+		args = append(args, status)
+	}
+	return queryEnrollments(db, query+" ORDER BY c.gradeLevel, c.name, s.lastName, s.firstName, s.id", args...)
+}
+
+// GetStudentEnrollments — классы студента по учебным годам, от первого к последнему
+func GetStudentEnrollments(studentID int) ([]mod.Enrollment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deletedAt IS NULL", studentID).Scan(&exists)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if exists == 0 {
+		return nil, utils.ErrorHandler(sql.ErrNoRows, "Student not found")
+	}
+	return queryEnrollments(db, enrollmentSQL+" WHERE e.studentId = ? ORDER BY y.startDate", studentID)
+}
+
+func queryEnrollments(db *sql.DB, query string, args ...interface{}) ([]mod.Enrollment, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying enrollments")
+	}
+	defer rows.Close()
+
+	enrollments := make([]mod.Enrollment, 0)
+	for rows.Next() {
+		var e mod.Enrollment
+		err = rows.Scan(&e.ID, &e.StudentID, &e.FirstName, &e.LastName, &e.AcademicYearID, &e.AcademicYear, &e.ClassID, &e.Class, &e.Status, &e.UpdatedAt)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning enrollments")
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, rows.Err()
+}
+
+// syncEnrollment — при зачислении или переводе студента запоминает его класс в учебном году этого класса
+func syncEnrollment(ex execer, s mod.Student, prev *mod.Student) error {
+	if s.ClassID == nil || (prev != nil && sameClassID(s.ClassID, prev.ClassID)) {
+		return nil
+	}
+	_, err := ex.Exec(`INSERT INTO enrollments (studentId, academicYearId, classId, status)
+		SELECT ?, y.id, c.id, ? FROM classes c JOIN academic_years y ON y.name = c.academicYear WHERE c.id = ?
+		ON DUPLICATE KEY UPDATE classId = VALUES(classId)`, s.ID, enrollmentEnrolled, *s.ClassID)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error updating enrollment")
+	}
+	return nil
+}
+
+// promotionPlan — перевод, рассчитанный по текущему состоянию базы; students и classes идут в порядке result.Students
+type promotionPlan struct {
+	result   mod.PromotionResult
+	from, to mod.AcademicYear
+	students []mod.Student
+	sources  []mod.Class
+	targets  map[string]*mod.PromotionClass
+}
+
+// PromoteStudents — перевод студентов учебного года fromYearId в год yearID: классы переходят на ступень выше
+// (недостающие создаются с тем же руководителем и вместимостью), выпускные классы выпускаются, студенты из retain
+// остаются в той же параллели. Все изменения — одна транзакция; dryRun только возвращает разницу.
+// Если в новом классе не хватает мест, перевод не выполняется
+func PromoteStudents(ctx context.Context, yearID int, req mod.PromotionRequest, dryRun bool) (mod.PromotionResult, error) {
+	if req.FinalGrade == 0 {
+		req.FinalGrade = defaultFinalGrade
+	}
+	if req.FinalGrade < 1 || req.FinalGrade > 12 {
+		return mod.PromotionResult{}, &utils.ValidationError{Errors: []utils.FieldError{{Field: "finalGrade", Message: "must be between 1 and 12"}}}
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.PromotionResult{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	if dryRun {
+		plan, err := planPromotion(db, yearID, req)
+		if err != nil {
+			return mod.PromotionResult{}, err
+		}
+		plan.result.DryRun = true
+		return plan.result, nil
+	}
+
+	var plan promotionPlan
+	err = withTx(db, func(tx *sql.Tx) error {
+		// параллельный перевод в тот же год ждёт здесь и затем видит закрытый исходный год
+		to, err := selectForUpdate[mod.AcademicYear](tx, yearID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Academic year not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching academic year")
+		}
+		err = lockPromotionSource(tx, to, req)
+		if err != nil {
+			return err
+		}
+		plan, err = planPromotion(tx, yearID, req)
+		if err != nil {
+			return err
+		}
+		if len(plan.result.Warnings) > 0 {
+			return utils.ErrorHandler(utils.ErrCapacityExceeded, plan.result.Warnings[0])
+		}
+		return applyPromotion(ctx, tx, &plan)
+	})
+	if err != nil {
+		return mod.PromotionResult{}, err
+	}
+	for _, s := range plan.students {
+		indexStudent(s)
+	}
+	plan.result.Applied = true
+	return plan.result, nil
+}
+
+// lockPromotionSource — блокирует исходный год перевода (fromYearId или предыдущий по startDate), чтобы перевод
+// из того же года в другой год ждал окончания этой транзакции. Отсутствующий год сообщит planPromotion
+func lockPromotionSource(tx *sql.Tx, to mod.AcademicYear, req mod.PromotionRequest) error {
+	var err error
+	if req.FromYearID != nil {
+		_, err = selectForUpdate[mod.AcademicYear](tx, *req.FromYearID)
+	} else {
+		var id int
+		err = tx.QueryRow("SELECT id FROM academic_years WHERE startDate < ? ORDER BY startDate DESC LIMIT 1 FOR UPDATE", to.StartDate).Scan(&id)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return utils.ErrorHandler(err, "Error fetching academic year")
+	}
+	return nil
+}
+
+// planPromotion — кто куда переходит и какие классы нужно создать; база не изменяется
+func planPromotion(q rowsQueryer, yearID int, req mod.PromotionRequest) (promotionPlan, error) {
+	var plan promotionPlan
+	var err error
+	plan.to, err = findAcademicYear(q, yearID)
+	if err != nil {
+		return plan, err
+	}
+	if plan.to.Status == yearClosed {
+		return plan, &utils.ValidationError{Errors: []utils.FieldError{{Field: "academicYear", Message: "academic year " + plan.to.Name + " is closed"}}}
+	}
+
+	if req.FromYearID != nil {
+		plan.from, err = findAcademicYear(q, *req.FromYearID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return plan, &utils.ValidationError{Errors: []utils.FieldError{{Field: "fromYearId", Message: "academic year not found"}}}
+		}
+	} else {
+		query := strings.Replace(utils.GenerateSQL(mod.AcademicYear{}, "select"), "WHERE id = ?", "WHERE startDate < ?", 1) + " ORDER BY startDate DESC LIMIT 1"
+		err = q.QueryRow(query, plan.to.StartDate).Scan(utils.GetStructFields(&plan.from, true, true)...)
+		if errors.Is(err, sql.ErrNoRows) {
+			return plan, &utils.ValidationError{Errors: []utils.FieldError{{Field: "fromYearId", Message: "no academic year before " + plan.to.Name}}}
+		}
+		if err != nil {
+			err = utils.ErrorHandler(err, "Error querying DB")
+		}
+	}
+	if err != nil {
+		return plan, err
+	}
+	if plan.from.StartDate >= plan.to.StartDate {
+		return plan, &utils.ValidationError{Errors: []utils.FieldError{{Field: "fromYearId", Message: "must start before " + plan.to.Name}}}
+	}
+	if plan.from.Status == yearClosed {
+		return plan, utils.ErrorHandler(utils.ErrInUse, "Academic year "+plan.from.Name+" is closed; students were already promoted")
+	}
+
+	sources, err := yearClasses(q, plan.from.Name)
+	if err != nil {
+		return plan, err
+	}
+	targets, err := yearClasses(q, plan.to.Name)
+	if err != nil {
+		return plan, err
+	}
+	plan.targets = make(map[string]*mod.PromotionClass)
+	for _, c := range targets {
+		id := c.ID
+		plan.targets[c.Name] = &mod.PromotionClass{ID: &id, Name: c.Name, GradeLevel: c.GradeLevel, HomeroomTeacherID: c.HomeroomTeacherID, Capacity: c.Capacity, Enrolled: *c.Enrolled}
+	}
+	byID := make(map[int]mod.Class)
+	for _, c := range sources {
+		byID[c.ID] = c
+	}
+
+	columns := utils.SelectColumns(mod.Student{}, nil)
+	rows, err := q.Query(`SELECT `+strings.Join(prefixColumns("s", columns), ", ")+` FROM students s JOIN classes c ON c.id = s.classId
+		WHERE c.academicYear = ? AND s.deletedAt IS NULL ORDER BY c.gradeLevel, c.name, s.lastName, s.firstName, s.id`, plan.from.Name)
+	if err != nil {
+		return plan, utils.ErrorHandler(err, "Error querying students")
+	}
+	for rows.Next() {
+		var s mod.Student
+		err = rows.Scan(utils.GetScanFields(&s, columns)...)
+		if err != nil {
+			rows.Close()
+			return plan, utils.ErrorHandler(err, "Error scanning students")
+		}
+		plan.students = append(plan.students, s)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return plan, utils.ErrorHandler(err, "Error scanning students")
+	}
+
+	retain := make(map[int]bool)
+	for _, id := range req.Retain {
+		retain[id] = true
+	}
+	found := 0
+	for _, s := range plan.students {
+		if retain[s.ID] {
+			found++
+		}
+	}
+	if found < len(retain) {
+		return plan, &utils.ValidationError{Errors: []utils.FieldError{{Field: "retain", Message: "contains students not enrolled in " + plan.from.Name}}}
+	}
+
+	var enrolled int
+	err = q.QueryRow(`SELECT COUNT(*) FROM enrollments e JOIN students s ON s.id = e.studentId JOIN classes c ON c.id = s.classId
+		WHERE e.academicYearId = ? AND c.academicYear = ? AND s.deletedAt IS NULL`, plan.to.ID, plan.from.Name).Scan(&enrolled)
+	if err != nil {
+		return plan, utils.ErrorHandler(err, "Error checking enrollments")
+	}
+	if enrolled > 0 {
+		return plan, utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("%d students of %s are already enrolled in %s", enrolled, plan.from.Name, plan.to.Name))
+	}
+
+	plan.result = mod.PromotionResult{FromYear: plan.from.Name, ToYear: plan.to.Name, Classes: []mod.PromotionClass{}, Students: []mod.PromotionMove{}, Warnings: []string{}}
+	for _, s := range plan.students {
+		source := byID[*s.ClassID]
+		plan.sources = append(plan.sources, source)
+		move := mod.PromotionMove{StudentID: s.ID, FirstName: s.FirstName, LastName: s.LastName, FromClass: source.Name}
+
+		grade := source.GradeLevel
+		switch {
+		case retain[s.ID]:
+			move.Action = enrollmentRetained
+			plan.result.Retained++
+		case grade >= req.FinalGrade:
+			move.Action = enrollmentGraduated
+			plan.result.Graduated++
+			plan.result.Students = append(plan.result.Students, move)
+			continue
+		default:
+			move.Action = enrollmentPromoted
+			plan.result.Promoted++
+			grade++
+		}
+
+		name := strconv.Itoa(grade) + strings.TrimLeft(source.Name, "0123456789")
+		target, ok := plan.targets[name]
+		if !ok {
+			target = &mod.PromotionClass{Name: name, GradeLevel: grade, Capacity: source.Capacity, Create: true}
+			plan.targets[name] = target
+		}
+		// классный руководитель переходит вместе с классом
+		if target.Create && move.Action == enrollmentPromoted && target.HomeroomTeacherID == nil {
+			target.HomeroomTeacherID = source.HomeroomTeacherID
+		}
+		target.Enrolled++
+		move.ToClass = name
+		plan.result.Students = append(plan.result.Students, move)
+	}
+
+	for _, c := range plan.targets {
+		plan.result.Classes = append(plan.result.Classes, *c)
+	}
+	sort.Slice(plan.result.Classes, func(i, j int) bool {
+		a, b := plan.result.Classes[i], plan.result.Classes[j]
+		if a.GradeLevel != b.GradeLevel {
+			return a.GradeLevel < b.GradeLevel
+		}
+		return a.Name < b.Name
+	})
+	for _, c := range plan.result.Classes {
+		if c.Enrolled > c.Capacity {
+			plan.result.Warnings = append(plan.result.Warnings, fmt.Sprintf("Class %s in %s would have %d students for capacity %d", c.Name, plan.to.Name, c.Enrolled, c.Capacity))
+		}
+	}
+	return plan, nil
+}
+
+// applyPromotion — выполняет рассчитанный перевод в транзакции tx
+func applyPromotion(ctx context.Context, tx *sql.Tx, plan *promotionPlan) error {
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Class{}, "insert"))
+	if err != nil {
+		return utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i := range plan.result.Classes {
+		c := &plan.result.Classes[i]
+		if !c.Create {
+			continue
+		}
+		class := mod.Class{Name: c.Name, GradeLevel: c.GradeLevel, AcademicYear: plan.to.Name, HomeroomTeacherID: c.HomeroomTeacherID, Capacity: c.Capacity}
+		res, err := stmt.Exec(utils.GetStructFields(class, true, false)...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error creating class "+c.Name)
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		id := int(lastId)
+		c.ID = &id
+		plan.targets[c.Name].ID = &id
+	}
+
+	for i, move := range plan.result.Students {
+		before := plan.students[i]
+		after := before
+		after.ClassID, after.Class = nil, ""
+		if move.ToClass != "" {
+			after.ClassID, after.Class = plan.targets[move.ToClass].ID, move.ToClass
+		}
+		err = execVersionedUpdate(tx, "UPDATE students SET classId = ?, class = ?, version = version + 1 WHERE id = ? AND version = ?",
+			after.ClassID, after.Class, before.ID, before.Version)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating Student with ID "+strconv.Itoa(before.ID))
+		}
+		after.Version++
+		after.UpdatedAt = nowTimestamp()
+		plan.students[i] = after
+
+		_, err = tx.Exec(`INSERT INTO enrollments (studentId, academicYearId, classId, status) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE classId = VALUES(classId), status = VALUES(status)`, before.ID, plan.from.ID, before.ClassID, move.Action)
+		if err == nil && after.ClassID != nil {
+			_, err = tx.Exec("INSERT INTO enrollments (studentId, academicYearId, classId, status) VALUES (?, ?, ?, ?)",
+				before.ID, plan.to.ID, after.ClassID, enrollmentEnrolled)
+		}
+		if err != nil {
+			return utils.ErrorHandler(err, "Error recording enrollment")
+		}
+		err = recordHistory(ctx, tx, historyStudent, before.ID, historyUpdate, before, after)
+		if err != nil {
+			return err
+		}
+	}
+
+	// карточка классного руководителя указывает на класс, в который перешли его студенты
+	moved := make(map[string]bool)
+	for i, move := range plan.result.Students {
+		source := plan.sources[i]
+		if move.Action != enrollmentPromoted || moved[source.Name] || source.HomeroomTeacherID == nil {
+			continue
+		}
+		moved[source.Name] = true
+		_, err = tx.Exec("UPDATE teachers SET class = ?, version = version + 1 WHERE id = ? AND class = ? AND deletedAt IS NULL",
+			move.ToClass, *source.HomeroomTeacherID, source.Name)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating homeroom teacher")
+		}
+	}
+
+	_, err = tx.Exec("UPDATE academic_years SET status = ?, version = version + 1 WHERE id = ?", yearClosed, plan.from.ID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE academic_years SET status = ?, version = version + 1 WHERE id = ?", yearCurrent, plan.to.ID)
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error updating academic years")
+	}
+	return nil
+}
+
+// yearClasses — классы учебного года с числом студентов
+func yearClasses(q rowsQueryer, year string) ([]mod.Class, error) {
+	columns := utils.SelectColumns(mod.Class{}, nil)
+	rows, err := q.Query(`SELECT `+strings.Join(prefixColumns("c", columns), ", ")+`,
+		(SELECT COUNT(*) FROM students s WHERE s.classId = c.id AND s.deletedAt IS NULL)
+		FROM classes c WHERE c.academicYear = ? ORDER BY c.gradeLevel, c.name`, year)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying classes")
+	}
+	defer rows.Close()
+
+	classes := make([]mod.Class, 0)
+	for rows.Next() {
+		var c mod.Class
+		var enrolled int
+		err = rows.Scan(append(utils.GetScanFields(&c, columns), &enrolled)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning classes")
+		}
+		c.Enrolled = &enrolled
+		classes = append(classes, c)
+	}
+	return classes, rows.Err()
+}
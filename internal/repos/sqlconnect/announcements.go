@@ -0,0 +1,582 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	audienceSchool   = "school"
+	audienceClass    = "class"
+	audienceTeachers = "teachers"
+	audienceExecs    = "execs"
+)
+
+// audienceSQL — условие «учётная запись e входит в аудиторию объявления a»: вся школа, учитель при audience teachers,
+// получатель из списка, учитель класса или родитель ученика класса
+func audienceSQL(a, e string) string {
+	return "(" + a + ".audience = 'school'" +
+		" OR (" + a + ".audience = 'teachers' AND " + e + ".role = 'teacher')" +
+		" OR (" + a + ".audience = 'execs' AND EXISTS (SELECT 1 FROM announcement_recipients ar WHERE ar.announcementId = " + a + ".id AND ar.execId = " + e + ".id))" +
+		" OR (" + a + ".audience = 'class' AND (" + teachesClassSQL(e+".teacherId", a+".classId") +
+		" OR EXISTS (SELECT 1 FROM student_guardians sg JOIN students s ON s.id = sg.studentId WHERE sg.guardianId = " + e + ".guardianId AND s.classId = " + a + ".classId AND s.deletedAt IS NULL))))"
+}
+
+// visibleSQL — опубликованное и не истёкшее объявление a, адресованное учётной записи с ID из аргумента
+func visibleSQL(a string) string {
+	return a + ".publishAt <= NOW() AND (" + a + ".expiresAt IS NULL OR " + a + ".expiresAt > NOW())" +
+		" AND EXISTS (SELECT 1 FROM execs e WHERE e.id = ? AND " + audienceSQL(a, "e") + ")"
+}
+
+// GetAnnouncements — объявления с фильтрами (?audience=, ?classId=, ?unread=true), по умолчанию новые сверху.
+// all — admin и manager видят все объявления, включая будущие и истёкшие; остальные — только адресованные им и действующие
+func GetAnnouncements(r *http.Request, execID int, all bool) ([]mod.Announcement, utils.PageInfo, error) {
+	if r.URL.Query().Get("sortBy") == "" {
+		params := r.URL.Query()
+		params.Set("sortBy", "publishAt:desc")
+		r = r.Clone(r.Context())
+		r.URL.RawQuery = params.Encode()
+	}
+	columns, err := utils.QueryColumns(r, mod.Announcement{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") +
+		", (SELECT r.readAt FROM announcement_reads r WHERE r.announcementId = a.id AND r.execId = ?) AS readAt FROM announcements a WHERE 1=1"
+	args := []interface{}{execID}
+	if !all {
+		query += " AND " + visibleSQL("a")
+		args = append(args, execID)
+	}
+	if r.URL.Query().Get("unread") == "true" {
+		query += " AND NOT EXISTS (SELECT 1 FROM announcement_reads r WHERE r.announcementId = a.id AND r.execId = ?)"
+		args = append(args, execID)
+	}
+
+	query, args, err = utils.AddFilters(r, mod.Announcement{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Announcement{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	announcements := make([]mod.Announcement, 0)
+	for rows.Next() {
+		var a mod.Announcement
+		err := rows.Scan(append(utils.GetScanFields(&a, columns), &a.ReadAt)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		announcements = append(announcements, a)
+	}
+
+	announcements, info := utils.Paginate(r, page, announcements)
+	if all {
+		err = fillAnnouncementRecipients(db, announcements)
+		if err != nil {
+			return nil, utils.PageInfo{}, err
+		}
+	}
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return announcements, info, nil
+}
+
+// FindAnnouncement — объявление по ID; без all объявление, не адресованное пользователю или не действующее, не найдено
+func FindAnnouncement(id, execID int, all bool) (mod.Announcement, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Announcement{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	a, err := findAnnouncement(db, id, execID, all)
+	if err != nil {
+		return mod.Announcement{}, err
+	}
+	if all {
+		list := []mod.Announcement{a}
+		err = fillAnnouncementRecipients(db, list)
+		if err != nil {
+			return mod.Announcement{}, err
+		}
+		a = list[0]
+	}
+	return a, nil
+}
+
+func findAnnouncement(q queryer, id, execID int, all bool) (mod.Announcement, error) {
+	columns := utils.SelectColumns(mod.Announcement{}, nil)
+	query := "SELECT " + strings.Join(prefixColumns("a", columns), ", ") +
+		", (SELECT r.readAt FROM announcement_reads r WHERE r.announcementId = a.id AND r.execId = ?) AS readAt FROM announcements a WHERE a.id = ?"
+	args := []interface{}{execID, id}
+	if !all {
+		query += " AND " + visibleSQL("a")
+		args = append(args, execID)
+	}
+	var a mod.Announcement
+	err := q.QueryRow(query, args...).Scan(append(utils.GetScanFields(&a, columns), &a.ReadAt)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Announcement{}, utils.ErrorHandler(err, "Announcement not found")
+		}
+		return mod.Announcement{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return a, nil
+}
+
+// fillAnnouncementRecipients — recipientIds для объявлений с аудиторией execs
+func fillAnnouncementRecipients(q rowsQueryer, announcements []mod.Announcement) error {
+	var ids []interface{}
+	for _, a := range announcements {
+		if a.Audience == audienceExecs {
+			ids = append(ids, a.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	rows, err := q.Query("SELECT announcementId, execId FROM announcement_recipients WHERE announcementId IN ("+placeholders+") ORDER BY execId", ids...)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying recipients")
+	}
+	defer rows.Close()
+
+	recipients := make(map[int][]int)
+	for rows.Next() {
+		var announcementID, execID int
+		if err := rows.Scan(&announcementID, &execID); err != nil {
+			return utils.ErrorHandler(err, "Error scanning recipients")
+		}
+		recipients[announcementID] = append(recipients[announcementID], execID)
+	}
+	for i := range announcements {
+		if announcements[i].Audience == audienceExecs {
+			announcements[i].RecipientIDs = recipients[announcements[i].ID]
+			if announcements[i].RecipientIDs == nil {
+				announcements[i].RecipientIDs = []int{}
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// SaveAnnouncements — создание объявлений из JSON (транзакция); автор — текущий пользователь, publishAt по умолчанию — сейчас
+func SaveAnnouncements(r *http.Request, createdBy int) ([]mod.Announcement, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newAnnouncements []mod.Announcement
+	err = json.NewDecoder(r.Body).Decode(&newAnnouncements)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newAnnouncements {
+		normalizeAnnouncement(&newAnnouncements[i])
+		newAnnouncements[i].CreatedBy = &createdBy
+		newAnnouncements[i].EmailedAt = nil
+		newAnnouncements[i].ReadAt = nil
+	}
+	err = utils.ValidateSlice(newAnnouncements)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(utils.GenerateSQL(mod.Announcement{}, "insert"))
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+
+		for i := range newAnnouncements {
+			a := &newAnnouncements[i]
+			err = checkAnnouncementRefs(tx, *a)
+			if err != nil {
+				return withIndex(err, i)
+			}
+			res, err := stmt.Exec(utils.GetStructFields(*a, true, false)...)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error inserting announcement")
+			}
+			lastId, err := res.LastInsertId()
+			if err != nil {
+				return utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			a.ID = int(lastId)
+			a.Version = 1
+			err = setAnnouncementRecipients(tx, a.ID, a.RecipientIDs)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newAnnouncements, nil
+}
+
+// PatchAnnouncementById — частичное обновление объявления; recipientIds в патче заменяет список получателей целиком.
+// Уже разосланное письмо повторно не отправляется
+func PatchAnnouncementById(id int, patch utils.Patch, expectedVersion int) (mod.Announcement, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Announcement{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Announcement
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Announcement](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Announcement not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching announcement")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+		list := []mod.Announcement{existing}
+		err = fillAnnouncementRecipients(tx, list)
+		if err != nil {
+			return err
+		}
+		existing = list[0]
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		normalizeAnnouncement(&patched)
+		patched.CreatedBy = existing.CreatedBy
+		patched.EmailedAt = existing.EmailedAt
+		patched.ReadAt = nil
+		err = utils.Validate(patched)
+		if err != nil {
+			return err
+		}
+		err = checkAnnouncementRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Announcement{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating announcement")
+		}
+		if !slices.Equal(patched.RecipientIDs, existing.RecipientIDs) {
+			_, err = tx.Exec("DELETE FROM announcement_recipients WHERE announcementId = ?", id)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error updating recipients")
+			}
+			err = setAnnouncementRecipients(tx, id, patched.RecipientIDs)
+			if err != nil {
+				return err
+			}
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Announcement{}, err
+	}
+	return patched, nil
+}
+
+// DeleteAnnouncementById — удаление объявления вместе с получателями и отметками о прочтении
+func DeleteAnnouncementById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Announcement{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting announcement")
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error getting rows affected")
+	}
+	if affected == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Announcement not found")
+	}
+	return nil
+}
+
+// MarkAnnouncementRead — отметка о прочтении; повторная отметка сохраняет время первого прочтения
+func MarkAnnouncementRead(id, execID int, all bool) (mod.Announcement, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Announcement{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	_, err = findAnnouncement(db, id, execID, all)
+	if err != nil {
+		return mod.Announcement{}, err
+	}
+	_, err = db.Exec("INSERT IGNORE INTO announcement_reads (announcementId, execId, readAt) VALUES (?, ?, NOW())", id, execID)
+	if err != nil {
+		return mod.Announcement{}, utils.ErrorHandler(err, "Error saving read receipt")
+	}
+	return findAnnouncement(db, id, execID, all)
+}
+
+// GetAnnouncementReceipts — размер аудитории (активные учётные записи) и прочитавшие объявление, первые прочтения сверху
+func GetAnnouncementReceipts(id int) (mod.AnnouncementReceipts, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.AnnouncementReceipts{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var receipts mod.AnnouncementReceipts
+	err = db.QueryRow(`SELECT (SELECT COUNT(*) FROM execs e WHERE e.inactiveStatus = FALSE AND `+audienceSQL("a", "e")+`)
+		FROM announcements a WHERE a.id = ?`, id).Scan(&receipts.Recipients)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.AnnouncementReceipts{}, utils.ErrorHandler(err, "Announcement not found")
+		}
+		return mod.AnnouncementReceipts{}, utils.ErrorHandler(err, "Error counting recipients")
+	}
+
+	rows, err := db.Query(`SELECT e.id, e.firstName, e.lastName, e.role, r.readAt FROM announcement_reads r
+		JOIN execs e ON e.id = r.execId WHERE r.announcementId = ? ORDER BY r.readAt, e.id`, id)
+	if err != nil {
+		return mod.AnnouncementReceipts{}, utils.ErrorHandler(err, "Error querying read receipts")
+	}
+	defer rows.Close()
+
+	receipts.Readers = make([]mod.AnnouncementReader, 0)
+	for rows.Next() {
+		var reader mod.AnnouncementReader
+		err = rows.Scan(&reader.ExecID, &reader.FirstName, &reader.LastName, &reader.Role, &reader.ReadAt)
+		if err != nil {
+			return mod.AnnouncementReceipts{}, utils.ErrorHandler(err, "Error scanning read receipts")
+		}
+		receipts.Readers = append(receipts.Readers, reader)
+	}
+	receipts.Read = len(receipts.Readers)
+	return receipts, rows.Err()
+}
+
+// SendDueAnnouncements — рассылает письмом опубликованные объявления с sendEmail, которые ещё не рассылались.
+// emailedAt ставится до отправки, поэтому при ошибке SMTP письмо не уходит повторно
+func SendDueAnnouncements() (int, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(`SELECT id FROM announcements WHERE sendEmail = TRUE AND emailedAt IS NULL
+		AND publishAt <= NOW() AND (expiresAt IS NULL OR expiresAt > NOW()) ORDER BY publishAt, id`)
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "Error querying announcements")
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, utils.ErrorHandler(err, "Error scanning announcements")
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	sent := 0
+	for _, id := range ids {
+		res, err := db.Exec("UPDATE announcements SET emailedAt = NOW() WHERE id = ? AND emailedAt IS NULL", id)
+		if err != nil {
+			return sent, utils.ErrorHandler(err, "Error updating announcement")
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		err = emailAnnouncement(db, id)
+		if err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func emailAnnouncement(q rowsQueryer, id int) error {
+	var title, body string
+	err := q.QueryRow("SELECT title, body FROM announcements WHERE id = ?", id).Scan(&title, &body)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying announcement")
+	}
+	rows, err := q.Query(`SELECT DISTINCT e.email FROM execs e JOIN announcements a ON a.id = ?
+		WHERE e.inactiveStatus = FALSE AND e.email <> '' AND `+audienceSQL("a", "e"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying recipients")
+	}
+	defer rows.Close()
+
+	var to []string
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return utils.ErrorHandler(err, "Error scanning recipients")
+		}
+		to = append(to, email)
+	}
+	if err := rows.Err(); err != nil {
+		return utils.ErrorHandler(err, "Error querying recipients")
+	}
+
+	message := "<p>" + strings.ReplaceAll(html.EscapeString(body), "\n", "<br>") + "</p>"
+	err = utils.SendMail(title, message, to...)
+	if err != nil {
+		return utils.ErrorHandler(err, fmt.Sprintf("Failed to email announcement %d", id))
+	}
+	return nil
+}
+
+// SendAnnouncementsPeriodically — фоновая рассылка объявлений; запускается из main в отдельной горутине
+func SendAnnouncementsPeriodically(every time.Duration) {
+	for {
+		sent, err := SendDueAnnouncements()
+		if err == nil && sent > 0 {
+			log.Printf("Announcements: %d emailed", sent)
+		}
+		time.Sleep(every)
+	}
+}
+
+// normalizeAnnouncement — обрезает пробелы, приводит время к виду с секундами (publishAt по умолчанию — сейчас);
+// classId нужен только классу, recipientIds — списку учётных записей
+func normalizeAnnouncement(a *mod.Announcement) {
+	a.Title = strings.TrimSpace(a.Title)
+	a.Audience = strings.ToLower(strings.TrimSpace(a.Audience))
+	a.PublishAt = withSeconds(strings.TrimSpace(a.PublishAt))
+	if a.PublishAt == "" {
+		a.PublishAt = time.Now().Format(time.DateTime)
+	}
+	if a.ExpiresAt != nil {
+		v := withSeconds(strings.TrimSpace(*a.ExpiresAt))
+		a.ExpiresAt = &v
+		if v == "" {
+			a.ExpiresAt = nil
+		}
+	}
+	if a.Audience != audienceClass {
+		a.ClassID = nil
+	}
+	if a.Audience == audienceExecs {
+		slices.Sort(a.RecipientIDs)
+		a.RecipientIDs = slices.Compact(a.RecipientIDs)
+		if a.RecipientIDs == nil {
+			a.RecipientIDs = []int{}
+		}
+	} else {
+		a.RecipientIDs = nil
+	}
+}
+
+// withSeconds — "2025-03-14 08:00" → "2025-03-14 08:00:00", чтобы время сравнивалось как строка
+func withSeconds(v string) string {
+	if len(v) == len("2006-01-02 15:04") {
+		return v + ":00"
+	}
+	return v
+}
+
+// checkAnnouncementRefs — класс существует, получатели заданы и существуют, expiresAt позже publishAt
+func checkAnnouncementRefs(q queryer, a mod.Announcement) error {
+	var errs []utils.FieldError
+	switch a.Audience {
+	case audienceClass:
+		if a.ClassID == nil {
+			errs = append(errs, utils.FieldError{Field: "classId", Message: "is required for audience class"})
+			break
+		}
+		var n int
+		err := q.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", *a.ClassID).Scan(&n)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking class")
+		}
+		if n == 0 {
+			errs = append(errs, utils.FieldError{Field: "classId", Message: "class not found"})
+		}
+	case audienceExecs:
+		if len(a.RecipientIDs) == 0 {
+			errs = append(errs, utils.FieldError{Field: "recipientIds", Message: "is required for audience execs"})
+		}
+		for _, id := range a.RecipientIDs {
+			var n int
+			err := q.QueryRow("SELECT COUNT(*) FROM execs WHERE id = ?", id).Scan(&n)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error checking recipients")
+			}
+			if n == 0 {
+				errs = append(errs, utils.FieldError{Field: "recipientIds", Message: "exec " + strconv.Itoa(id) + " not found"})
+			}
+		}
+	}
+	if a.ExpiresAt != nil && *a.ExpiresAt <= a.PublishAt {
+		errs = append(errs, utils.FieldError{Field: "expiresAt", Message: "must be after publishAt"})
+	}
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func setAnnouncementRecipients(ex execer, announcementID int, execIDs []int) error {
+	for _, execID := range execIDs {
+		_, err := ex.Exec("INSERT INTO announcement_recipients (announcementId, execId) VALUES (?, ?)", announcementID, execID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving recipients")
+		}
+	}
+	return nil
+}
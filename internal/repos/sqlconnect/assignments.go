@@ -0,0 +1,393 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// GetAllAssignments — назначения учителей с фильтрами (?teacherId=, ?classId=, ?subject=) и пагинацией
+func GetAllAssignments(r *http.Request) ([]mod.Assignment, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Assignment{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM assignments WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Assignment{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Assignment{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	assignments := make([]mod.Assignment, 0)
+	for rows.Next() {
+		var a mod.Assignment
+		err := rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		assignments = append(assignments, a)
+	}
+
+	assignments, info := utils.Paginate(r, page, assignments)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return assignments, info, nil
+}
+
+// FindAssignmentById — назначение по ID
+func FindAssignmentById(id int) (mod.Assignment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Assignment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var a mod.Assignment
+	err = db.QueryRow(utils.GenerateSQL(mod.Assignment{}, "select"), id).Scan(utils.GetStructFields(&a, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Assignment{}, utils.ErrorHandler(err, "Assignment not found")
+		}
+		return mod.Assignment{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return a, nil
+}
+
+// SaveAssignments — создание назначений из JSON (транзакция)
+func SaveAssignments(r *http.Request) ([]mod.Assignment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newAssignments []mod.Assignment
+	err = json.NewDecoder(r.Body).Decode(&newAssignments)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newAssignments {
+		newAssignments[i].Subject = strings.TrimSpace(newAssignments[i].Subject)
+	}
+	err = utils.ValidateSlice(newAssignments)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Assignment{}, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i, a := range newAssignments {
+		err = checkAssignmentRefs(tx, a)
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
+		res, err := stmt.Exec(utils.GetStructFields(a, true, false)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error inserting assignment")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		newAssignments[i].ID = int(lastId)
+		newAssignments[i].Version = 1
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return newAssignments, nil
+}
+
+// PatchAssignmentById — частичное обновление назначения (учитель, класс, предмет, часы)
+func PatchAssignmentById(id int, patch utils.Patch, expectedVersion int) (mod.Assignment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Assignment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Assignment
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Assignment](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Assignment not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching assignment")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		patched.Subject = strings.TrimSpace(patched.Subject)
+		err = checkAssignmentRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Assignment{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating assignment")
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Assignment{}, err
+	}
+	return patched, nil
+}
+
+// DeleteAssignmentById — удаление назначения
+func DeleteAssignmentById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Assignment{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting assignment")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking deletion result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Assignment not found")
+	}
+	return nil
+}
+
+// checkAssignmentRefs — учитель и класс существуют, а такого же назначения ещё нет
+func checkAssignmentRefs(q queryer, a mod.Assignment) error {
+	var errs []utils.FieldError
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", a.TeacherID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "teacherId", Message: "teacher not found"})
+	}
+	err = q.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", a.ClassID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking class")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "classId", Message: "class not found"})
+	}
+	err = q.QueryRow("SELECT COUNT(*) FROM assignments WHERE teacherId = ? AND classId = ? AND subject = ? AND id <> ?", a.TeacherID, a.ClassID, a.Subject, a.ID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking assignment")
+	}
+	if n > 0 {
+		errs = append(errs, utils.FieldError{Field: "subject", Message: "teacher already teaches " + a.Subject + " in this class"})
+	}
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// FindStudentsByTeacherId — студенты всех классов учителя, сгруппированные по классу.
+// Классы учителя — его назначения и класс из карточки учителя
+func FindStudentsByTeacherId(id int) ([]mod.ClassStudents, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", id).Scan(&exists)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if exists == 0 {
+		return nil, utils.ErrorHandler(sql.ErrNoRows, "Teacher not found")
+	}
+
+	byTeacher, err := studentsByTeachers(db, []int{id})
+	if err != nil {
+		return nil, err
+	}
+	groups := byTeacher[id]
+	if groups == nil {
+		groups = []mod.ClassStudents{}
+	}
+	return groups, nil
+}
+
+// FindStudentsByTeachers — то же, что FindStudentsByTeacherId, для нескольких учителей одним набором запросов (?include=students)
+func FindStudentsByTeachers(ids []int) (map[int][]mod.ClassStudents, error) {
+	if len(ids) == 0 {
+		return map[int][]mod.ClassStudents{}, nil
+	}
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+	return studentsByTeachers(db, ids)
+}
+
+func studentsByTeachers(db *sql.DB, ids []int) (map[int][]mod.ClassStudents, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, 0, 2*len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, args...)
+
+	// класс из карточки учителя берётся за последний учебный год
+	rows, err := db.Query(`SELECT a.teacherId, c.id, c.name, a.subject FROM assignments a JOIN classes c ON c.id = a.classId
+		WHERE a.teacherId IN (`+placeholders+`)
+		UNION
+		SELECT t.id, c.id, c.name, t.subject FROM teachers t
+		JOIN classes c ON c.id = (SELECT c2.id FROM classes c2 WHERE c2.name = t.class ORDER BY c2.academicYear DESC LIMIT 1)
+		WHERE t.id IN (`+placeholders+`) AND t.deletedAt IS NULL`, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying teacher classes")
+	}
+	defer rows.Close()
+
+	groups := make(map[int]map[int]*mod.ClassStudents)
+	classIDs := make(map[int]bool)
+	for rows.Next() {
+		var teacherID, classID int
+		var name, subject string
+		err = rows.Scan(&teacherID, &classID, &name, &subject)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning teacher classes")
+		}
+		if groups[teacherID] == nil {
+			groups[teacherID] = make(map[int]*mod.ClassStudents)
+		}
+		g := groups[teacherID][classID]
+		if g == nil {
+			g = &mod.ClassStudents{ClassID: classID, Class: name, Subjects: []string{}, Students: []mod.Student{}}
+			groups[teacherID][classID] = g
+		}
+		if !containsString(g.Subjects, subject) {
+			g.Subjects = append(g.Subjects, subject)
+		}
+		classIDs[classID] = true
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying teacher classes")
+	}
+
+	students, err := studentsByClassIds(db, classIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[int][]mod.ClassStudents)
+	for teacherID, byClass := range groups {
+		list := make([]mod.ClassStudents, 0, len(byClass))
+		for classID, g := range byClass {
+			if s := students[classID]; s != nil {
+				g.Students = s
+			}
+			sort.Strings(g.Subjects)
+			list = append(list, *g)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Class < list[j].Class })
+		result[teacherID] = list
+	}
+	return result, nil
+}
+
+// studentsByClassIds — студенты нескольких классов одним запросом, по classId
+func studentsByClassIds(db *sql.DB, classIDs map[int]bool) (map[int][]mod.Student, error) {
+	byClass := make(map[int][]mod.Student)
+	if len(classIDs) == 0 {
+		return byClass, nil
+	}
+	args := make([]interface{}, 0, len(classIDs))
+	for id := range classIDs {
+		args = append(args, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	columns := utils.SelectColumns(mod.Student{}, nil)
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+" FROM students WHERE classId IN ("+placeholders+") AND deletedAt IS NULL ORDER BY lastName, firstName, id", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s mod.Student
+		err = rows.Scan(utils.GetScanFields(&s, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		byClass[*s.ClassID] = append(byClass[*s.ClassID], s)
+	}
+	return byClass, rows.Err()
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,324 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/internal/storage"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	ownerStudent = "student"
+	ownerTeacher = "teacher"
+	ownerExec    = "exec"
+
+	attachmentPhoto    = "photo"
+	attachmentDocument = "document"
+
+	// defaultURLTTL и maxURLTTL — срок действия подписанной ссылки по умолчанию и наибольший
+	defaultURLTTL = 15 * time.Minute
+	maxURLTTL     = 7 * 24 * time.Hour
+)
+
+const attachmentSQL = `SELECT a.id, a.ownerType, a.ownerId, a.kind, a.fileName, f.contentType, f.size, f.sha256, f.storageKey, a.uploadedBy, a.createdAt
+	FROM attachments a JOIN files f ON f.id = a.fileId`
+
+func attachmentScanFields(a *mod.Attachment) []interface{} {
+	return []interface{}{&a.ID, &a.OwnerType, &a.OwnerID, &a.Kind, &a.FileName, &a.ContentType, &a.Size, &a.SHA256, &a.StorageKey, &a.UploadedBy, &a.CreatedAt}
+}
+
+func queryAttachments(q rowsQueryer, where string, args ...interface{}) ([]mod.Attachment, error) {
+	rows, err := q.Query(attachmentSQL+" WHERE "+where+" ORDER BY a.kind DESC, a.id", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying attachments")
+	}
+	defer rows.Close()
+
+	attachments := make([]mod.Attachment, 0)
+	for rows.Next() {
+		var a mod.Attachment
+		err = rows.Scan(attachmentScanFields(&a)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning attachments")
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, rows.Err()
+}
+
+func findAttachment(q queryer, id int) (mod.Attachment, error) {
+	var a mod.Attachment
+	err := q.QueryRow(attachmentSQL+" WHERE a.id = ?", id).Scan(attachmentScanFields(&a)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Attachment{}, utils.ErrorHandler(err, "Attachment not found")
+		}
+		return mod.Attachment{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return a, nil
+}
+
+// checkAttachmentOwner — владелец вложений существует (студенты и учителя в корзине не считаются)
+func checkAttachmentOwner(q queryer, ownerType string, ownerID int) error {
+	var query, notFound string
+	switch ownerType {
+	case ownerStudent:
+		query, notFound = "SELECT COUNT(*) FROM students WHERE id = ? AND deletedAt IS NULL", "Student not found"
+	case ownerTeacher:
+		query, notFound = "SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", "Teacher not found"
+	case ownerExec:
+		query, notFound = "SELECT COUNT(*) FROM execs WHERE id = ?", "Exec not found"
+	default:
+		return utils.ErrorHandler(utils.ErrInvalidFilter, "Unknown attachment owner "+ownerType)
+	}
+	var n int
+	err := q.QueryRow(query, ownerID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying DB")
+	}
+	if n == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, notFound)
+	}
+	return nil
+}
+
+func checkAttachmentKind(kind string) error {
+	if kind != attachmentPhoto && kind != attachmentDocument {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "kind", Message: "must be one of: photo document"}}}
+	}
+	return nil
+}
+
+// GetAttachments — вложения владельца; kind оставляет только фотографию или только документы
+func GetAttachments(ownerType string, ownerID int, kind string) ([]mod.Attachment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkAttachmentOwner(db, ownerType, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if kind != "" {
+		err = checkAttachmentKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		return queryAttachments(db, "a.ownerType = ? AND a.ownerId = ? AND a.kind = ?", ownerType, ownerID, kind)
+	}
+	return queryAttachments(db, "a.ownerType = ? AND a.ownerId = ?", ownerType, ownerID)
+}
+
+// FindAttachment — вложение по ID
+func FindAttachment(id int) (mod.Attachment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Attachment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return findAttachment(db, id)
+}
+
+// SaveAttachment — прикрепляет файл к владельцу. Тип проверяется по содержимому (для photo — только изображения),
+// одинаковое содержимое хранится один раз; новая фотография заменяет прежнюю
+func SaveAttachment(ownerType string, ownerID int, kind string, file storage.File, uploadedBy *int) (mod.Attachment, error) {
+	if kind == "" {
+		kind = attachmentDocument
+	}
+	err := checkAttachmentKind(kind)
+	if err != nil {
+		return mod.Attachment{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Attachment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkAttachmentOwner(db, ownerType, ownerID)
+	if err != nil {
+		return mod.Attachment{}, err
+	}
+
+	allowed := storage.DocumentTypes
+	if kind == attachmentPhoto {
+		allowed = storage.PhotoTypes
+	}
+	up, err := storage.Spool(file, storage.MaxFileSize(), allowed)
+	if err != nil {
+		return mod.Attachment{}, err
+	}
+	defer up.Close()
+
+	key := storage.BlobKey(up.SHA256)
+	var stored bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM files WHERE sha256 = ?)", up.SHA256).Scan(&stored)
+	if err != nil {
+		return mod.Attachment{}, utils.ErrorHandler(err, "Error querying files")
+	}
+	if !stored {
+		_, err = storage.Default().Put(key, up)
+		if err != nil {
+			return mod.Attachment{}, utils.ErrorHandler(err, "Error storing file")
+		}
+	}
+
+	var attachment mod.Attachment
+	var released []string
+	err = withTx(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT IGNORE INTO files (sha256, size, contentType, storageKey) VALUES (?, ?, ?, ?)",
+			up.SHA256, up.Size, up.ContentType, key)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving file")
+		}
+		var fileID int
+		err = tx.QueryRow("SELECT id FROM files WHERE sha256 = ? FOR UPDATE", up.SHA256).Scan(&fileID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying files")
+		}
+
+		res, err := tx.Exec("INSERT INTO attachments (fileId, ownerType, ownerId, kind, fileName, uploadedBy) VALUES (?, ?, ?, ?, ?, ?)",
+			fileID, ownerType, ownerID, kind, fileName(file.Name), uploadedBy)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving attachment")
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		if kind == attachmentPhoto {
+			released, err = deleteAttachments(tx, "ownerType = ? AND ownerId = ? AND kind = ? AND id <> ?", ownerType, ownerID, attachmentPhoto, id)
+			if err != nil {
+				return err
+			}
+		}
+		attachment, err = findAttachment(tx, int(id))
+		return err
+	})
+	if err != nil {
+		return mod.Attachment{}, err
+	}
+	removeStoredFiles(released...)
+	return attachment, nil
+}
+
+// DeleteAttachment — удаляет вложение; файл удаляется из хранилища, если на него больше никто не ссылается
+func DeleteAttachment(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var released []string
+	err = withTx(db, func(tx *sql.Tx) error {
+		_, err := findAttachment(tx, id)
+		if err != nil {
+			return err
+		}
+		released, err = deleteAttachments(tx, "id = ?", id)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	removeStoredFiles(released...)
+	return nil
+}
+
+// deleteOwnerAttachments — все вложения владельца, который удаляется окончательно
+func deleteOwnerAttachments(tx *sql.Tx, ownerType string, ownerID int) ([]string, error) {
+	return deleteAttachments(tx, "ownerType = ? AND ownerId = ?", ownerType, ownerID)
+}
+
+// deleteAttachments — удаляет вложения по условию и файлы, на которые больше нет ссылок.
+// Возвращает ключи освободившихся файлов: удалять их из хранилища нужно после коммита
+func deleteAttachments(tx *sql.Tx, where string, args ...interface{}) ([]string, error) {
+	rows, err := tx.Query("SELECT DISTINCT fileId FROM attachments WHERE "+where+" FOR UPDATE", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying attachments")
+	}
+	var fileIDs []int
+	for rows.Next() {
+		var fileID int
+		if err := rows.Scan(&fileID); err != nil {
+			rows.Close()
+			return nil, utils.ErrorHandler(err, "Error scanning attachments")
+		}
+		fileIDs = append(fileIDs, fileID)
+	}
+	rows.Close()
+	if len(fileIDs) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.Exec("DELETE FROM attachments WHERE "+where, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error deleting attachments")
+	}
+
+	var released []string
+	for _, fileID := range fileIDs {
+		var key string
+		var inUse bool
+		err = tx.QueryRow("SELECT storageKey, EXISTS (SELECT 1 FROM attachments WHERE fileId = files.id) FROM files WHERE id = ? FOR UPDATE", fileID).Scan(&key, &inUse)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error querying files")
+		}
+		if inUse {
+			continue
+		}
+		_, err = tx.Exec("DELETE FROM files WHERE id = ?", fileID)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error deleting file")
+		}
+		released = append(released, key)
+	}
+	return released, nil
+}
+
+func signedFilePath(id int) string {
+	return "/files/" + strconv.Itoa(id)
+}
+
+// AttachmentURL — подписанная ссылка /files/{id} на скачивание без токена; ttl от секунды до maxURLTTL, 0 — defaultURLTTL
+func AttachmentURL(id int, ttl time.Duration) (mod.SignedURL, error) {
+	if ttl == 0 {
+		ttl = defaultURLTTL
+	}
+	if ttl < time.Second || ttl > maxURLTTL {
+		return mod.SignedURL{}, &utils.ValidationError{Errors: []utils.FieldError{{Field: "ttl", Message: "must be between 1s and " + maxURLTTL.String()}}}
+	}
+	_, err := FindAttachment(id)
+	if err != nil {
+		return mod.SignedURL{}, err
+	}
+
+	path := signedFilePath(id)
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	signature, err := utils.SignURL(path, expires)
+	if err != nil {
+		return mod.SignedURL{}, err
+	}
+	return mod.SignedURL{
+		URL:       publicURL(fmt.Sprintf("%s?expires=%d&signature=%s", path, expires.Unix(), signature)),
+		ExpiresAt: expires.Format(time.DateTime),
+	}, nil
+}
+
+// FindSignedAttachment — вложение по подписанной ссылке; неверная подпись или истёкший срок — ErrForbidden
+func FindSignedAttachment(id int, expires, signature string) (mod.Attachment, error) {
+	err := utils.VerifyURL(signedFilePath(id), expires, signature)
+	if err != nil {
+		return mod.Attachment{}, err
+	}
+	return FindAttachment(id)
+}
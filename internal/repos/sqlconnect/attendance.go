@@ -0,0 +1,520 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const historyAttendance = "attendance"
+
+// teachesClassSQL — условие «учитель ведёт предмет в классе или является его классным руководителем»;
+// teacher и class — SQL-выражения с ID учителя и класса
+func teachesClassSQL(teacher, class string) string {
+	return "(EXISTS (SELECT 1 FROM assignments x WHERE x.teacherId = " + teacher + " AND x.classId = " + class + ")" +
+		" OR EXISTS (SELECT 1 FROM classes xc WHERE xc.id = " + class + " AND xc.homeroomTeacherId = " + teacher + ")" +
+		" OR EXISTS (SELECT 1 FROM teachers xt JOIN classes xc ON xc.name = xt.class WHERE xt.id = " + teacher + " AND xt.deletedAt IS NULL AND xc.id = " + class + "))"
+}
+
+// checkTeachesClass — учитель отмечает посещаемость только в классах, где он ведёт предмет или является классным руководителем
+func checkTeachesClass(q queryer, teacherID *int, classID int) error {
+	if teacherID == nil {
+		return nil
+	}
+	var ok bool
+	err := q.QueryRow("SELECT "+teachesClassSQL("tc.teacherId", "tc.classId")+" FROM (SELECT ? AS teacherId, ? AS classId) AS tc",
+		*teacherID, classID).Scan(&ok)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher assignment")
+	}
+	if !ok {
+		return utils.ErrorHandler(utils.ErrForbidden, "Teacher does not teach this class")
+	}
+	return nil
+}
+
+// checkTeachesStudent — студент удалён не окончательно и учится в классе, который ведёт учитель
+func checkTeachesStudent(q queryer, teacherID *int, studentID int) error {
+	var classID *int
+	err := q.QueryRow("SELECT classId FROM students WHERE id = ? AND deletedAt IS NULL", studentID).Scan(&classID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrorHandler(err, "Student not found")
+		}
+		return utils.ErrorHandler(err, "Error querying DB")
+	}
+	if teacherID == nil {
+		return nil
+	}
+	if classID == nil {
+		return utils.ErrorHandler(utils.ErrForbidden, "Teacher does not teach this student")
+	}
+	return checkTeachesClass(q, teacherID, *classID)
+}
+
+// CheckTeachesStudent — учитель видит данные студента своего класса; teacherID nil — без ограничений
+func CheckTeachesStudent(studentID int, teacherID *int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return checkTeachesStudent(db, teacherID, studentID)
+}
+
+// SubmitClassAttendance — отметка всего класса за день одним запросом (транзакция, upsert по студенту).
+// Нужны отметки для каждого студента класса; учитель отмечает только текущий день, прошлые дни исправляет администрация
+func SubmitClassAttendance(ctx context.Context, classID int, date string, entries []mod.AttendanceEntry, teacherID *int, userID *int) ([]mod.Attendance, error) {
+	_, err := time.ParseInLocation(time.DateOnly, date, time.Local)
+	if err != nil {
+		return nil, utils.ErrorHandler(utils.ErrInvalidFilter, "date must be YYYY-MM-DD")
+	}
+	today := time.Now().Format(time.DateOnly)
+	if date > today {
+		return nil, &utils.ValidationError{Errors: []utils.FieldError{{Field: "date", Message: "cannot record attendance in the future"}}}
+	}
+	if teacherID != nil && date != today {
+		return nil, utils.ErrorHandler(utils.ErrForbidden, "Teachers can only submit attendance for today")
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var records []mod.Attendance
+	err = withTx(db, func(tx *sql.Tx) error {
+		_, err := selectForUpdate[mod.Class](tx, classID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Class not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching class")
+		}
+		err = checkTeachesClass(tx, teacherID, classID)
+		if err != nil {
+			return err
+		}
+
+		inClass := make(map[int]bool)
+		rows, err := tx.Query("SELECT id FROM students WHERE classId = ? AND deletedAt IS NULL", classID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying students")
+		}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return utils.ErrorHandler(err, "Error scanning students")
+			}
+			inClass[id] = true
+		}
+		rows.Close()
+
+		var errs []utils.FieldError
+		seen := make(map[int]bool)
+		for i, e := range entries {
+			index := i
+			fail := func(field, msg string) {
+				errs = append(errs, utils.FieldError{Index: &index, Field: field, Message: msg})
+			}
+			switch {
+			case !inClass[e.StudentID]:
+				fail("studentId", "student is not in class")
+			case seen[e.StudentID]:
+				fail("studentId", "duplicate student")
+			}
+			seen[e.StudentID] = true
+			record := mod.Attendance{StudentID: e.StudentID, ClassID: classID, Date: date, Status: e.Status, Reason: e.Reason}
+			for _, fe := range utils.ValidateStruct(record) {
+				fail(fe.Field, fe.Message)
+			}
+		}
+		for id := range inClass {
+			if !seen[id] {
+				sid := id
+				errs = append(errs, utils.FieldError{ID: &sid, Field: "studentId", Message: "attendance for this student is missing"})
+			}
+		}
+		if len(errs) > 0 {
+			return &utils.ValidationError{Errors: errs}
+		}
+
+		previous, err := classAttendance(tx, classID, date)
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare(`INSERT INTO attendance (studentId, classId, date, status, reason, recordedBy) VALUES (?, ?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE version = IF(status = VALUES(status) AND reason <=> VALUES(reason) AND classId = VALUES(classId), version, version + 1),
+			classId = VALUES(classId), status = VALUES(status), reason = VALUES(reason), recordedBy = VALUES(recordedBy)`)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+		for _, e := range entries {
+			_, err = stmt.Exec(e.StudentID, classID, date, e.Status, e.Reason, userID)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error saving attendance")
+			}
+		}
+		records, err = classAttendance(tx, classID, date)
+		if err != nil {
+			return err
+		}
+
+		// повторная отметка того же дня — исправление, оно попадает в журнал
+		byStudent := make(map[int]mod.Attendance, len(previous))
+		for _, p := range previous {
+			byStudent[p.StudentID] = p
+		}
+		for _, rec := range records {
+			prev, ok := byStudent[rec.StudentID]
+			if !ok || prev.Version == rec.Version {
+				continue
+			}
+			err = recordHistory(ctx, tx, historyAttendance, rec.ID, historyUpdate, prev, rec)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetClassAttendance — отметки класса за день
+func GetClassAttendance(classID int, date string, teacherID *int) ([]mod.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	_, err = className(db, classID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkTeachesClass(db, teacherID, classID)
+	if err != nil {
+		return nil, err
+	}
+	return classAttendance(db, classID, date)
+}
+
+func classAttendance(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, classID int, date string) ([]mod.Attendance, error) {
+	columns := utils.SelectColumns(mod.Attendance{}, nil)
+	rows, err := q.Query("SELECT "+strings.Join(columns, ", ")+" FROM attendance WHERE classId = ? AND date = ? ORDER BY studentId", classID, date)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying attendance")
+	}
+	defer rows.Close()
+
+	records := make([]mod.Attendance, 0)
+	for rows.Next() {
+		var a mod.Attendance
+		err = rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning attendance")
+		}
+		records = append(records, a)
+	}
+	return records, rows.Err()
+}
+
+// className — название класса по ID
+func className(q queryer, classID int) (string, error) {
+	var name string
+	err := q.QueryRow("SELECT name FROM classes WHERE id = ?", classID).Scan(&name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", utils.ErrorHandler(err, "Class not found")
+		}
+		return "", utils.ErrorHandler(err, "Error querying DB")
+	}
+	return name, nil
+}
+
+// GetAllAttendance — отметки с фильтрами (?studentId=, ?classId=, ?date[gte]=, ?status=)
+func GetAllAttendance(r *http.Request) ([]mod.Attendance, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Attendance{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM attendance WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Attendance{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Attendance{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	records := make([]mod.Attendance, 0)
+	for rows.Next() {
+		var a mod.Attendance
+		err := rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		records = append(records, a)
+	}
+
+	records, info := utils.Paginate(r, page, records)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return records, info, nil
+}
+
+// FindAttendanceById — отметка по ID
+func FindAttendanceById(id int) (mod.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Attendance{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var a mod.Attendance
+	err = db.QueryRow(utils.GenerateSQL(mod.Attendance{}, "select"), id).Scan(utils.GetStructFields(&a, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Attendance{}, utils.ErrorHandler(err, "Attendance record not found")
+		}
+		return mod.Attendance{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return a, nil
+}
+
+// PatchAttendanceById — исправление отметки администрацией; меняются только status и reason, правка пишется в журнал
+func PatchAttendanceById(ctx context.Context, id int, patch utils.Patch, expectedVersion int, userID *int) (mod.Attendance, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Attendance{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Attendance
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Attendance](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Attendance record not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching attendance")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		patched.StudentID, patched.ClassID, patched.Date = existing.StudentID, existing.ClassID, existing.Date
+		patched.RecordedBy = userID
+
+		err = execVersionedUpdate(tx, "UPDATE attendance SET status = ?, reason = ?, recordedBy = ?, version = version + 1 WHERE id = ? AND version = ?",
+			patched.Status, patched.Reason, patched.RecordedBy, id, existing.Version)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating attendance")
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return recordHistory(ctx, tx, historyAttendance, id, historyUpdate, existing, patched)
+	})
+	if err != nil {
+		return mod.Attendance{}, err
+	}
+	return patched, nil
+}
+
+// GetAttendanceHistory — журнал исправлений отметки
+func GetAttendanceHistory(id int) ([]mod.HistoryEntry, error) {
+	return getHistory(historyAttendance, id)
+}
+
+// summarySQL — сводка посещаемости по студентам; alias a — отметки за период, присоединённые к students s
+const summarySQL = `SELECT s.id, s.firstName, s.lastName, COALESCE(c.name, s.class, ''), COUNT(a.id),
+	COALESCE(SUM(a.status = 'present'), 0), COALESCE(SUM(a.status = 'late'), 0),
+	COALESCE(SUM(a.status = 'absent'), 0), COALESCE(SUM(a.status = 'excused'), 0)
+	FROM students s
+	LEFT JOIN classes c ON c.id = s.classId
+	LEFT JOIN attendance a ON a.studentId = s.id AND a.date BETWEEN ? AND ?`
+
+func querySummaries(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]mod.AttendanceSummary, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying attendance")
+	}
+	defer rows.Close()
+
+	summaries := make([]mod.AttendanceSummary, 0)
+	for rows.Next() {
+		var s mod.AttendanceSummary
+		err = rows.Scan(&s.StudentID, &s.FirstName, &s.LastName, &s.Class, &s.Days, &s.Present, &s.Late, &s.Absent, &s.Excused)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning attendance")
+		}
+		s.Rate = percent(s.Present+s.Late, s.Days)
+		s.AbsenceRate = percent(s.Absent+s.Excused, s.Days)
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// percent — доля в процентах с двумя знаками; nil, если отметок за период нет
+func percent(part, total int) *float64 {
+	if total == 0 {
+		return nil
+	}
+	p := math.Round(float64(part)/float64(total)*100*100) / 100
+	return &p
+}
+
+// GetStudentAttendance — сводка и отметки студента за период
+func GetStudentAttendance(studentID int, from, to string, teacherID *int) (mod.StudentAttendance, error) {
+	result := mod.StudentAttendance{From: from, To: to, Records: []mod.Attendance{}}
+	db, err := ConnectDB()
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkTeachesStudent(db, teacherID, studentID)
+	if err != nil {
+		return result, err
+	}
+
+	summaries, err := querySummaries(db, summarySQL+" WHERE s.id = ? AND s.deletedAt IS NULL GROUP BY s.id, s.firstName, s.lastName, c.name, s.class", from, to, studentID)
+	if err != nil {
+		return result, err
+	}
+	if len(summaries) == 0 {
+		return result, utils.ErrorHandler(sql.ErrNoRows, "Student not found")
+	}
+	result.Summary = summaries[0]
+
+	columns := utils.SelectColumns(mod.Attendance{}, nil)
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+" FROM attendance WHERE studentId = ? AND date BETWEEN ? AND ? ORDER BY date", studentID, from, to)
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error querying attendance")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a mod.Attendance
+		err = rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			return result, utils.ErrorHandler(err, "Error scanning attendance")
+		}
+		result.Records = append(result.Records, a)
+	}
+	return result, rows.Err()
+}
+
+// GetClassAttendanceSummary — посещаемость класса за период: текущие студенты и все, у кого есть отметки в этом классе
+func GetClassAttendanceSummary(classID int, from, to string, teacherID *int) (mod.ClassAttendance, error) {
+	result := mod.ClassAttendance{ClassID: classID, From: from, To: to, Students: []mod.AttendanceSummary{}}
+	db, err := ConnectDB()
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	result.Class, err = className(db, classID)
+	if err != nil {
+		return result, err
+	}
+	err = checkTeachesClass(db, teacherID, classID)
+	if err != nil {
+		return result, err
+	}
+
+	query := strings.Replace(summarySQL, "a.date BETWEEN ? AND ?", "a.classId = ? AND a.date BETWEEN ? AND ?", 1) +
+		" WHERE s.deletedAt IS NULL AND (s.classId = ? OR a.id IS NOT NULL)" +
+		" GROUP BY s.id, s.firstName, s.lastName, c.name, s.class ORDER BY s.lastName, s.firstName, s.id"
+	result.Students, err = querySummaries(db, query, classID, from, to, classID)
+	if err != nil {
+		return result, err
+	}
+
+	var attended, days int
+	for _, s := range result.Students {
+		attended += s.Present + s.Late
+		days += s.Days
+	}
+	result.Rate = percent(attended, days)
+	return result, nil
+}
+
+// GetAbsenceList — студенты, у которых доля пропусков (absent и excused) за период не ниже threshold процентов; classID 0 — все классы
+func GetAbsenceList(from, to string, threshold float64, classID int) ([]mod.AttendanceSummary, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	query := summarySQL + " WHERE s.deletedAt IS NULL"
+	args := []interface{}{from, to}
+	if classID != 0 {
+		query += " AND s.classId = ?"
+		args = append(args, classID)
+	}
+	query += " GROUP BY s.id, s.firstName, s.lastName, c.name, s.class HAVING COUNT(a.id) > 0"
+
+	summaries, err := querySummaries(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	over := make([]mod.AttendanceSummary, 0)
+	for _, s := range summaries {
+		if *s.AbsenceRate >= threshold {
+			over = append(over, s)
+		}
+	}
+	sort.SliceStable(over, func(i, j int) bool {
+		if *over[i].AbsenceRate != *over[j].AbsenceRate {
+			return *over[i].AbsenceRate > *over[j].AbsenceRate
+		}
+		return over[i].LastName < over[j].LastName
+	})
+	return over, nil
+}
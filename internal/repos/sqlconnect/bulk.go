@@ -0,0 +1,55 @@
+package sqlconnect
+
+import (
+	"WebProject/pkg/utils"
+	"net/http"
+)
+
+// patchPartial — bulk PATCH в режиме partial: каждая запись патчится отдельно, ошибки не откатывают остальные
+func patchPartial[T any](patch utils.Patch, patchOne func(id int, p utils.Patch, expectedVersion int) (T, error)) ([]utils.BulkItemResult, error) {
+	if patch.IsJSONPatch() {
+		ids, patches, err := splitBulkJSONPatch(patch.Body)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]utils.BulkItemResult, 0, len(ids))
+		for i, id := range ids {
+			_, err := patchOne(id, patches[id], 0)
+			if err != nil {
+				results = append(results, utils.BulkFailure(i, id, err))
+				continue
+			}
+			results = append(results, utils.BulkSuccess(i, id, http.StatusOK))
+		}
+		return results, nil
+	}
+
+	items, err := parseBulkMergePatch(patch.Body)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]utils.BulkItemResult, 0, len(items))
+	for i, item := range items {
+		_, err := patchOne(item.ID, item.Patch, item.Version)
+		if err != nil {
+			results = append(results, utils.BulkFailure(i, item.ID, err))
+			continue
+		}
+		results = append(results, utils.BulkSuccess(i, item.ID, http.StatusOK))
+	}
+	return results, nil
+}
+
+// deletePartial — bulk DELETE в режиме partial: каждая запись удаляется отдельно
+func deletePartial(ids []int, deleteOne func(id int) error) []utils.BulkItemResult {
+	results := make([]utils.BulkItemResult, 0, len(ids))
+	for i, id := range ids {
+		err := deleteOne(id)
+		if err != nil {
+			results = append(results, utils.BulkFailure(i, id, err))
+			continue
+		}
+		results = append(results, utils.BulkSuccess(i, id, http.StatusOK))
+	}
+	return results
+}
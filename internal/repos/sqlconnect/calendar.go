@@ -0,0 +1,586 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	feedTeacher = "teacher"
+	feedClass   = "class"
+
+	// feedHistoryDays — уроки, закончившиеся раньше, в подписку не попадают
+	feedHistoryDays = 30
+)
+
+// GetAllHolidays — каникулы и праздники (?startDate[gte]=, ?name[like]=)
+func GetAllHolidays(r *http.Request) ([]mod.Holiday, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Holiday{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM holidays WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Holiday{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Holiday{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	holidays := make([]mod.Holiday, 0)
+	for rows.Next() {
+		var h mod.Holiday
+		err := rows.Scan(utils.GetScanFields(&h, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		holidays = append(holidays, h)
+	}
+
+	holidays, info := utils.Paginate(r, page, holidays)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return holidays, info, nil
+}
+
+// SaveHolidays — добавление каникул; без endDate — один день
+func SaveHolidays(r *http.Request) ([]mod.Holiday, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newHolidays []mod.Holiday
+	err = json.NewDecoder(r.Body).Decode(&newHolidays)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newHolidays {
+		h := &newHolidays[i]
+		h.Name = strings.TrimSpace(h.Name)
+		if h.EndDate == "" {
+			h.EndDate = h.StartDate
+		}
+	}
+	err = utils.ValidateSlice(newHolidays)
+	if err != nil {
+		return nil, err
+	}
+	var errs []utils.FieldError
+	for i, h := range newHolidays {
+		index := i
+		_, errStart := time.Parse(time.DateOnly, h.StartDate)
+		_, errEnd := time.Parse(time.DateOnly, h.EndDate)
+		if errStart != nil || errEnd != nil {
+			errs = append(errs, utils.FieldError{Index: &index, Field: "startDate", Message: "invalid date"})
+		} else if h.EndDate < h.StartDate {
+			errs = append(errs, utils.FieldError{Index: &index, Field: "endDate", Message: "must not be before startDate"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &utils.ValidationError{Errors: errs}
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(utils.GenerateSQL(mod.Holiday{}, "insert"))
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+		for i, h := range newHolidays {
+			res, err := stmt.Exec(utils.GetStructFields(h, true, false)...)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error inserting holiday")
+			}
+			lastId, err := res.LastInsertId()
+			if err != nil {
+				return utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			newHolidays[i].ID = int(lastId)
+			newHolidays[i].Version = 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newHolidays, nil
+}
+
+// DeleteHolidayById — удаление каникул
+func DeleteHolidayById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Holiday{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting holiday")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error retrieving delete result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Holiday not found")
+	}
+	return nil
+}
+
+// CreateTeacherFeed — новая ссылка на календарь учителя; requester — учитель, который делает запрос (nil для admin и manager)
+func CreateTeacherFeed(teacherID int, requester *int, createdBy *int) (mod.CalendarFeed, error) {
+	return createFeed(feedTeacher, teacherID, requester, createdBy)
+}
+
+// CreateClassFeed — новая ссылка на календарь класса
+func CreateClassFeed(classID int, requester *int, createdBy *int) (mod.CalendarFeed, error) {
+	return createFeed(feedClass, classID, requester, createdBy)
+}
+
+// createFeed — случайный токен 32 байта; в базу пишется только его sha256, поэтому ссылку нельзя восстановить, только отозвать
+func createFeed(ownerType string, ownerID int, requester *int, createdBy *int) (mod.CalendarFeed, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.CalendarFeed{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkFeedOwner(db, ownerType, ownerID, requester)
+	if err != nil {
+		return mod.CalendarFeed{}, err
+	}
+
+	tokenBytes := make([]byte, 32)
+	_, err = rand.Read(tokenBytes)
+	if err != nil {
+		return mod.CalendarFeed{}, utils.ErrorHandler(err, "Error generating feed token")
+	}
+	hashed := sha256.Sum256(tokenBytes)
+
+	res, err := db.Exec("INSERT INTO calendar_feeds (ownerType, ownerId, tokenHash, createdBy) VALUES (?, ?, ?, ?)",
+		ownerType, ownerID, hex.EncodeToString(hashed[:]), createdBy)
+	if err != nil {
+		return mod.CalendarFeed{}, utils.ErrorHandler(err, "Error creating calendar feed")
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return mod.CalendarFeed{}, utils.ErrorHandler(err, "Error getting last insert ID")
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+	return mod.CalendarFeed{
+		ID:        int(id),
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		Token:     token,
+		URL:       feedURL(token),
+		CreatedBy: createdBy,
+		CreatedAt: *nowTimestamp(),
+	}, nil
+}
+
+// feedURL — публичная ссылка на .ics
+func feedURL(token string) string {
+	return publicURL(fmt.Sprintf("/calendar/%s.ics", token))
+}
+
+// publicURL — адрес для ссылок, которые открываются без токена; базовый адрес из PUBLIC_BASE_URL
+func publicURL(path string) string {
+	base := strings.TrimSuffix(os.Getenv("PUBLIC_BASE_URL"), "/")
+	if base == "" {
+		base = "http://localhost:8080"
+	}
+	return base + path
+}
+
+// checkFeedOwner — владелец подписки существует; учитель управляет только своим календарём и календарями своих классов
+func checkFeedOwner(q queryer, ownerType string, ownerID int, requester *int) error {
+	var n int
+	var err error
+	if ownerType == feedTeacher {
+		err = q.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", ownerID).Scan(&n)
+	} else {
+		err = q.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", ownerID).Scan(&n)
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying DB")
+	}
+	if n == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, strings.ToUpper(ownerType[:1])+ownerType[1:]+" not found")
+	}
+	if ownerType == feedClass {
+		return checkTeachesClass(q, requester, ownerID)
+	}
+	if requester != nil && *requester != ownerID {
+		return utils.ErrorHandler(utils.ErrForbidden, "Teachers can only manage their own calendar")
+	}
+	return nil
+}
+
+// GetTeacherFeeds — ссылки на календарь учителя, включая отозванные
+func GetTeacherFeeds(teacherID int, requester *int) ([]mod.CalendarFeed, error) {
+	return getFeeds(feedTeacher, teacherID, requester)
+}
+
+// GetClassFeeds — ссылки на календарь класса, включая отозванные
+func GetClassFeeds(classID int, requester *int) ([]mod.CalendarFeed, error) {
+	return getFeeds(feedClass, classID, requester)
+}
+
+func getFeeds(ownerType string, ownerID int, requester *int) ([]mod.CalendarFeed, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkFeedOwner(db, ownerType, ownerID, requester)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT id, ownerType, ownerId, createdBy, createdAt, revokedAt, lastAccessedAt FROM calendar_feeds WHERE ownerType = ? AND ownerId = ? ORDER BY id", ownerType, ownerID)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying calendar feeds")
+	}
+	defer rows.Close()
+
+	feeds := make([]mod.CalendarFeed, 0)
+	for rows.Next() {
+		var f mod.CalendarFeed
+		err = rows.Scan(&f.ID, &f.OwnerType, &f.OwnerID, &f.CreatedBy, &f.CreatedAt, &f.RevokedAt, &f.LastAccessedAt)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning calendar feeds")
+		}
+		feeds = append(feeds, f)
+	}
+	return feeds, rows.Err()
+}
+
+// RevokeCalendarFeed — отзыв ссылки: календарные приложения с ней перестают получать расписание
+func RevokeCalendarFeed(id int, requester *int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var ownerType string
+	var ownerID int
+	err = db.QueryRow("SELECT ownerType, ownerId FROM calendar_feeds WHERE id = ? AND revokedAt IS NULL", id).Scan(&ownerType, &ownerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrorHandler(err, "Active calendar feed not found")
+		}
+		return utils.ErrorHandler(err, "Error querying DB")
+	}
+	err = checkFeedOwner(db, ownerType, ownerID, requester)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = db.Exec("UPDATE calendar_feeds SET revokedAt = NOW() WHERE id = ?", id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error revoking calendar feed")
+	}
+	return nil
+}
+
+// CalendarForToken — календарь по токену из ссылки: название и события; отозванный или неизвестный токен — not found
+func CalendarForToken(token string) (string, []utils.ICalEvent, error) {
+	tokenBytes, err := hex.DecodeString(token)
+	if err != nil || len(tokenBytes) != 32 {
+		return "", nil, utils.ErrorHandler(sql.ErrNoRows, "Calendar feed not found")
+	}
+	hashed := sha256.Sum256(tokenBytes)
+
+	db, err := ConnectDB()
+	if err != nil {
+		return "", nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var feed mod.CalendarFeed
+	err = db.QueryRow("SELECT id, ownerType, ownerId FROM calendar_feeds WHERE tokenHash = ? AND revokedAt IS NULL", hex.EncodeToString(hashed[:])).
+		Scan(&feed.ID, &feed.OwnerType, &feed.OwnerID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, utils.ErrorHandler(err, "Calendar feed not found")
+		}
+		return "", nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	_, err = db.Exec("UPDATE calendar_feeds SET lastAccessedAt = NOW() WHERE id = ?", feed.ID)
+	if err != nil {
+		return "", nil, utils.ErrorHandler(err, "Error updating calendar feed")
+	}
+
+	var name string
+	if feed.OwnerType == feedTeacher {
+		err = db.QueryRow("SELECT CONCAT(firstName, ' ', lastName) FROM teachers WHERE id = ? AND deletedAt IS NULL", feed.OwnerID).Scan(&name)
+	} else {
+		err = db.QueryRow("SELECT CONCAT('Class ', name) FROM classes WHERE id = ?", feed.OwnerID).Scan(&name)
+	}
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, utils.ErrorHandler(err, "Calendar owner no longer exists")
+		}
+		return "", nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+
+	events, err := feedEvents(db, feed.OwnerType, feed.OwnerID)
+	if err != nil {
+		return "", nil, err
+	}
+	return name + " timetable", events, nil
+}
+
+// feedSubstitution — замена с именем заменяющего учителя
+type feedSubstitution struct {
+	mod.Substitution
+	Substitute string
+}
+
+// feedEvents — еженедельные уроки как повторяющиеся события: каникулы и отменённые уроки — EXDATE,
+// замена в календаре класса — изменённый экземпляр (RECURRENCE-ID), в календаре учителя — отдельное событие у заменяющего
+func feedEvents(db *sql.DB, ownerType string, ownerID int) ([]utils.ICalEvent, error) {
+	since := time.Now().AddDate(0, 0, -feedHistoryDays).Format(time.DateOnly)
+	column := "l.classId"
+	if ownerType == feedTeacher {
+		column = "l.teacherId"
+	}
+	slots, err := querySlots(db, slotSQL+" WHERE "+column+" = ? AND COALESCE(l.validTo, ?) >= ? ORDER BY l.weekday, l.period", ownerID, openEnd, since)
+	if err != nil {
+		return nil, err
+	}
+
+	// замены на уроки владельца и, для учителя, замены, которые он ведёт сам
+	query := "SELECT " + strings.Join(prefixColumns("x", utils.SelectColumns(mod.Substitution{}, nil)), ", ") +
+		", COALESCE(CONCAT(t.firstName, ' ', t.lastName), '') FROM substitutions x JOIN lessons l ON l.id = x.lessonId" +
+		" LEFT JOIN teachers t ON t.id = x.substituteTeacherId WHERE x.date >= ? AND (" + column + " = ?"
+	args := []interface{}{since, ownerID}
+	if ownerType == feedTeacher {
+		query += " OR x.substituteTeacherId = ?"
+		args = append(args, ownerID)
+	}
+	rows, err := db.Query(query+") ORDER BY x.date", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying substitutions")
+	}
+	subsByLesson := make(map[int][]feedSubstitution)
+	var covering []feedSubstitution
+	for rows.Next() {
+		var s feedSubstitution
+		err = rows.Scan(append(utils.GetStructFields(&s.Substitution, true, true), &s.Substitute)...)
+		if err != nil {
+			rows.Close()
+			return nil, utils.ErrorHandler(err, "Error scanning substitutions")
+		}
+		if ownerType == feedTeacher && s.SubstituteTeacherID != nil && *s.SubstituteTeacherID == ownerID {
+			covering = append(covering, s)
+			continue
+		}
+		subsByLesson[s.LessonID] = append(subsByLesson[s.LessonID], s)
+	}
+	rows.Close()
+
+	holidays, err := holidaysSince(db, since)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]utils.ICalEvent, 0, len(slots))
+	for _, slot := range slots {
+		first, until, ok := lessonRange(slot)
+		if !ok {
+			continue
+		}
+		start, end, ok := slotTimes(slot, first)
+		if !ok {
+			continue
+		}
+		event := lessonEvent(slot, start, end)
+		event.UID = fmt.Sprintf("lesson-%d@webproject", slot.ID)
+		event.RRule = "FREQ=WEEKLY;BYDAY=" + utils.ICalWeekday(slot.Weekday)
+		if until != nil {
+			event.RRule += ";UNTIL=" + until.Format("20060102") + "T235959"
+		}
+
+		for d := first; until == nil || !d.After(*until); d = d.AddDate(0, 0, 7) {
+			if len(holidays) == 0 || d.Format(time.DateOnly) > holidays[len(holidays)-1].EndDate {
+				break
+			}
+			if onHoliday(holidays, d.Format(time.DateOnly)) {
+				at, _, _ := slotTimes(slot, d)
+				event.ExDates = append(event.ExDates, at)
+			}
+		}
+
+		var overrides []utils.ICalEvent
+		for _, s := range subsByLesson[slot.ID] {
+			day, err := time.ParseInLocation(time.DateOnly, s.Date, time.Local)
+			if err != nil {
+				continue
+			}
+			at, atEnd, _ := slotTimes(slot, day)
+			if s.SubstituteTeacherID == nil || ownerType == feedTeacher {
+				// отменённый урок или урок, который ведёт другой учитель, из календаря учителя убирается
+				event.ExDates = append(event.ExDates, at)
+				continue
+			}
+			override := lessonEvent(slot, at, atEnd)
+			override.UID = event.UID
+			override.RecurrenceID = &at
+			override.Description = "Substitute: " + s.Substitute
+			if s.Reason != nil && *s.Reason != "" {
+				override.Description += "\n" + *s.Reason
+			}
+			overrides = append(overrides, override)
+		}
+		events = append(events, event)
+		events = append(events, overrides...)
+	}
+
+	if len(covering) > 0 {
+		ids := make([]interface{}, 0, len(covering))
+		for _, s := range covering {
+			ids = append(ids, s.LessonID)
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+		lessons, err := querySlots(db, slotSQL+" WHERE l.id IN ("+placeholders+")", ids...)
+		if err != nil {
+			return nil, err
+		}
+		byID := make(map[int]mod.TimetableSlot, len(lessons))
+		for _, l := range lessons {
+			byID[l.ID] = l
+		}
+		for _, s := range covering {
+			slot, ok := byID[s.LessonID]
+			if !ok {
+				continue
+			}
+			day, err := time.ParseInLocation(time.DateOnly, s.Date, time.Local)
+			if err != nil {
+				continue
+			}
+			start, end, ok := slotTimes(slot, day)
+			if !ok {
+				continue
+			}
+			event := lessonEvent(slot, start, end)
+			event.UID = fmt.Sprintf("substitution-%d@webproject", s.ID)
+			event.Summary += " (substitution)"
+			event.Description = "Covering for " + slot.Teacher
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func lessonEvent(slot mod.TimetableSlot, start, end time.Time) utils.ICalEvent {
+	event := utils.ICalEvent{
+		Summary:     slot.Subject + " — " + slot.Class,
+		Description: slot.Teacher,
+		Start:       start,
+		End:         end,
+	}
+	if slot.Room != nil {
+		event.Location = *slot.Room
+	}
+	return event
+}
+
+// lessonRange — первая дата урока (первый подходящий день недели с validFrom) и последняя дата действия
+func lessonRange(slot mod.TimetableSlot) (time.Time, *time.Time, bool) {
+	from, err := time.ParseInLocation(time.DateOnly, slot.ValidFrom, time.Local)
+	if err != nil {
+		return time.Time{}, nil, false
+	}
+	first := from.AddDate(0, 0, (slot.Weekday-isoWeekday(from)+7)%7)
+	if slot.ValidTo == nil {
+		return first, nil, true
+	}
+	until, err := time.ParseInLocation(time.DateOnly, *slot.ValidTo, time.Local)
+	if err != nil || first.After(until) {
+		return time.Time{}, nil, false
+	}
+	return first, &until, true
+}
+
+// slotTimes — начало и конец урока в день day по расписанию звонков
+func slotTimes(slot mod.TimetableSlot, day time.Time) (time.Time, time.Time, bool) {
+	start, errStart := time.Parse("15:04", slot.StartTime)
+	end, errEnd := time.Parse("15:04", slot.EndTime)
+	if errStart != nil || errEnd != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	at := func(t time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+	}
+	return at(start), at(end), true
+}
+
+func holidaysSince(db *sql.DB, since string) ([]mod.Holiday, error) {
+	columns := utils.SelectColumns(mod.Holiday{}, nil)
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+" FROM holidays WHERE endDate >= ? ORDER BY endDate", since)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying holidays")
+	}
+	defer rows.Close()
+
+	holidays := make([]mod.Holiday, 0)
+	for rows.Next() {
+		var h mod.Holiday
+		err = rows.Scan(utils.GetScanFields(&h, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning holidays")
+		}
+		holidays = append(holidays, h)
+	}
+	return holidays, rows.Err()
+}
+
+func onHoliday(holidays []mod.Holiday, date string) bool {
+	for _, h := range holidays {
+		if date >= h.StartDate && date <= h.EndDate {
+			return true
+		}
+	}
+	return false
+}
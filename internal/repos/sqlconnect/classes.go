@@ -0,0 +1,545 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetAllClasses — список классов с фильтрами, сортировкой и числом студентов в каждом
+func GetAllClasses(r *http.Request) ([]mod.Class, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Class{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM classes WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Class{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Class{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	classList := make([]mod.Class, 0)
+	for rows.Next() {
+		var class mod.Class
+		err := rows.Scan(utils.GetScanFields(&class, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		classList = append(classList, class)
+	}
+
+	classList, info := utils.Paginate(r, page, classList)
+	err = fillEnrollment(db, classList)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return classList, info, nil
+}
+
+// FindClassById — класс по ID вместе с числом студентов
+func FindClassById(id int) (mod.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Class{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var class mod.Class
+	err = db.QueryRow(utils.GenerateSQL(mod.Class{}, "select"), id).Scan(utils.GetStructFields(&class, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Class{}, utils.ErrorHandler(err, "Class not found")
+		}
+		return mod.Class{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+
+	list := []mod.Class{class}
+	err = fillEnrollment(db, list)
+	if err != nil {
+		return mod.Class{}, err
+	}
+	return list[0], nil
+}
+
+// fillEnrollment — число студентов (без удалённых) для каждого класса одним запросом
+func fillEnrollment(db *sql.DB, classes []mod.Class) error {
+	if len(classes) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(classes)), ", ")
+	args := make([]interface{}, len(classes))
+	for i, c := range classes {
+		args[i] = c.ID
+	}
+	rows, err := db.Query("SELECT classId, COUNT(*) FROM students WHERE classId IN ("+placeholders+") AND deletedAt IS NULL GROUP BY classId", args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error counting students")
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return utils.ErrorHandler(err, "Error counting students")
+		}
+		counts[id] = n
+	}
+	for i := range classes {
+		n := counts[classes[i].ID]
+		classes[i].Enrolled = &n
+	}
+	return rows.Err()
+}
+
+// SaveClasses — создание классов из JSON (транзакция)
+func SaveClasses(r *http.Request) ([]mod.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newClasses []mod.Class
+	err = json.NewDecoder(r.Body).Decode(&newClasses)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+
+	for i := range newClasses {
+		normalizeClass(&newClasses[i])
+	}
+	err = utils.ValidateSlice(newClasses)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Class{}, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i, class := range newClasses {
+		err = checkClassRefs(tx, class)
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
+		res, err := stmt.Exec(utils.GetStructFields(class, true, false)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error inserting class")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		enrolled := 0
+		newClasses[i].ID = int(lastId)
+		newClasses[i].Version = 1
+		newClasses[i].Enrolled = &enrolled
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return newClasses, nil
+}
+
+// PatchClassById — частичное обновление класса; переименование переносится на студентов и учителей,
+// вместимость нельзя сделать меньше текущего числа студентов
+func PatchClassById(id int, patch utils.Patch, expectedVersion int) (mod.Class, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Class{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Class
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Class](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Class not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching class")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		normalizeClass(&patched)
+		err = utils.Validate(patched)
+		if err != nil {
+			return err
+		}
+		err = checkClassRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+
+		var enrolled int
+		err = tx.QueryRow("SELECT COUNT(*) FROM students WHERE classId = ? AND deletedAt IS NULL", id).Scan(&enrolled)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error counting students")
+		}
+		if patched.Capacity < enrolled {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "capacity", Message: "is below current enrollment (" + strconv.Itoa(enrolled) + ")"}}}
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Class{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating class")
+		}
+		if patched.Name != existing.Name {
+			err = renameClass(tx, existing, patched.Name)
+			if err != nil {
+				return err
+			}
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		patched.Enrolled = &enrolled
+		return nil
+	})
+	if err != nil {
+		return mod.Class{}, err
+	}
+	return patched, nil
+}
+
+// renameClass — новое имя класса в студентах и учителях; учителя переносятся, только если класса
+// с прежним именем в других учебных годах больше нет
+func renameClass(tx *sql.Tx, existing mod.Class, name string) error {
+	_, err := tx.Exec("UPDATE students SET class = ?, version = version + 1 WHERE classId = ?", name, existing.ID)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error renaming class for students")
+	}
+	var others int
+	err = tx.QueryRow("SELECT COUNT(*) FROM classes WHERE name = ? AND id <> ?", existing.Name, existing.ID).Scan(&others)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking class name")
+	}
+	if others == 0 {
+		_, err = tx.Exec("UPDATE teachers SET class = ?, version = version + 1 WHERE class = ?", name, existing.Name)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error renaming class for teachers")
+		}
+	}
+	return nil
+}
+
+// DeleteClassById — удаление класса, в котором нет студентов и учителей (ни по карточке, ни по назначениям)
+func DeleteClassById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Class](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Class not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching class")
+		}
+		var students, teachers, assessments, attendance, lessons, homework int
+		err = tx.QueryRow("SELECT COUNT(*) FROM students WHERE classId = ? AND deletedAt IS NULL", id).Scan(&students)
+		if err == nil {
+			err = tx.QueryRow(`SELECT COUNT(DISTINCT t.id) FROM teachers t LEFT JOIN assignments a ON a.teacherId = t.id
+				WHERE t.deletedAt IS NULL AND (t.class = ? OR a.classId = ?)`, existing.Name, id).Scan(&teachers)
+		}
+		if err == nil {
+			err = tx.QueryRow("SELECT COUNT(*) FROM assessments WHERE classId = ?", id).Scan(&assessments)
+		}
+		if err == nil {
+			err = tx.QueryRow("SELECT COUNT(*) FROM attendance WHERE classId = ?", id).Scan(&attendance)
+		}
+		if err == nil {
+			err = tx.QueryRow("SELECT COUNT(*) FROM lessons WHERE classId = ?", id).Scan(&lessons)
+		}
+		if err == nil {
+			err = tx.QueryRow("SELECT COUNT(*) FROM homework WHERE classId = ?", id).Scan(&homework)
+		}
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking class usage")
+		}
+		if students > 0 || teachers > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Class %s still has %d students and %d teachers", existing.Name, students, teachers))
+		}
+		if lessons > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Class %s still has %d lessons in the timetable", existing.Name, lessons))
+		}
+		if assessments > 0 || attendance > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Class %s has %d assessments and %d attendance records", existing.Name, assessments, attendance))
+		}
+		if homework > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Class %s has %d homework assignments", existing.Name, homework))
+		}
+		// назначения удалённых (в корзине) учителей не держат класс
+		_, err = tx.Exec("DELETE FROM assignments WHERE classId = ?", id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting class assignments")
+		}
+		_, err = tx.Exec(utils.GenerateSQL(mod.Class{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting class")
+		}
+		return nil
+	})
+}
+
+// FindStudentsByClassId — студенты класса по classId
+func FindStudentsByClassId(id int) ([]mod.Student, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var exists int
+	err = db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", id).Scan(&exists)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if exists == 0 {
+		return nil, utils.ErrorHandler(sql.ErrNoRows, "Class not found")
+	}
+
+	columns := utils.SelectColumns(mod.Student{}, nil)
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+" FROM students WHERE classId = ? AND deletedAt IS NULL ORDER BY lastName, firstName, id", id)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	students := make([]mod.Student, 0)
+	for rows.Next() {
+		var s mod.Student
+		err = rows.Scan(utils.GetScanFields(&s, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		students = append(students, s)
+	}
+	return students, rows.Err()
+}
+
+// defaultClassCapacity — вместимость класса, если она не указана (совпадает с DEFAULT в миграции)
+const defaultClassCapacity = 30
+
+// normalizeClass — имя класса в верхнем регистре; gradeLevel по умолчанию берётся из имени ("10B" → 10)
+func normalizeClass(class *mod.Class) {
+	class.Name = strings.ToUpper(strings.TrimSpace(class.Name))
+	if class.GradeLevel == 0 {
+		class.GradeLevel = classGrade(class.Name)
+	}
+	if class.Capacity == 0 {
+		class.Capacity = defaultClassCapacity
+	}
+}
+
+func classGrade(name string) int {
+	digits := strings.TrimRightFunc(name, func(r rune) bool { return r < '0' || r > '9' })
+	grade, _ := strconv.Atoi(digits)
+	return grade
+}
+
+// checkClassRefs — проверки, которым нужна база: уникальность имени в учебном году, существование учебного года,
+// соответствие gradeLevel имени и существование классного руководителя
+func checkClassRefs(q queryer, class mod.Class) error {
+	var errs []utils.FieldError
+	if grade := classGrade(class.Name); grade != 0 && grade != class.GradeLevel {
+		errs = append(errs, utils.FieldError{Field: "gradeLevel", Message: "does not match class name " + class.Name})
+	}
+
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM classes WHERE name = ? AND academicYear = ? AND id <> ?", class.Name, class.AcademicYear, class.ID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking class name")
+	}
+	if n > 0 {
+		errs = append(errs, utils.FieldError{Field: "name", Message: "already exists in academic year " + class.AcademicYear})
+	}
+
+	err = q.QueryRow("SELECT COUNT(*) FROM academic_years WHERE name = ?", class.AcademicYear).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking academic year")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "academicYear", Message: "unknown academic year " + class.AcademicYear})
+	}
+
+	if class.HomeroomTeacherID != nil {
+		err = q.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", *class.HomeroomTeacherID).Scan(&n)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking homeroom teacher")
+		}
+		if n == 0 {
+			errs = append(errs, utils.FieldError{Field: "homeroomTeacherId", Message: "teacher not found"})
+		}
+	}
+
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// findClassByName — класс по имени без учёта регистра; если имя встречается в нескольких учебных годах, берётся последний
+func findClassByName(q queryer, name string) (mod.Class, error) {
+	var class mod.Class
+	query := strings.Replace(utils.GenerateSQL(mod.Class{}, "select"), "WHERE id = ?", "WHERE name = ?", 1) + " ORDER BY academicYear DESC LIMIT 1"
+	err := q.QueryRow(query, strings.ToUpper(strings.TrimSpace(name))).Scan(utils.GetStructFields(&class, true, true)...)
+	return class, err
+}
+
+// resolveStudentClass — связывает студента с классом: по classId, а если он не задан или изменилось
+// только имя класса — по имени. Имя в записи приводится к имени класса, неизвестный класс — ошибка валидации
+func resolveStudentClass(q queryer, s *mod.Student, prev *mod.Student) error {
+	byName := s.ClassID == nil
+	if prev != nil && sameClassID(s.ClassID, prev.ClassID) && !strings.EqualFold(s.Class, prev.Class) {
+		byName = true
+	}
+
+	var class mod.Class
+	var err error
+	if byName {
+		if strings.TrimSpace(s.Class) == "" {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "class", Message: "is required"}}}
+		}
+		class, err = findClassByName(q, s.Class)
+	} else {
+		err = q.QueryRow(utils.GenerateSQL(mod.Class{}, "select"), *s.ClassID).Scan(utils.GetStructFields(&class, true, true)...)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if byName {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "class", Message: "unknown class " + s.Class}}}
+		}
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "classId", Message: "class not found"}}}
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error looking up class")
+	}
+	s.ClassID = &class.ID
+	s.Class = class.Name
+	return nil
+}
+
+// resolveTeacherClass — класс учителя должен существовать; имя приводится к имени класса
+func resolveTeacherClass(q queryer, t *mod.Teacher) error {
+	if strings.TrimSpace(t.Class) == "" {
+		return nil
+	}
+	class, err := findClassByName(q, t.Class)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "class", Message: "unknown class " + t.Class}}}
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error looking up class")
+	}
+	t.Class = class.Name
+	return nil
+}
+
+// resolveRecordClass — resolveStudentClass / resolveTeacherClass для обобщённого кода (импорт)
+func resolveRecordClass(q queryer, record, prev interface{}) error {
+	switch rec := record.(type) {
+	case *mod.Student:
+		p, _ := prev.(*mod.Student)
+		return resolveStudentClass(q, rec, p)
+	case *mod.Teacher:
+		return resolveTeacherClass(q, rec)
+	}
+	return nil
+}
+
+// checkStudentCapacity — при зачислении или переводе студента проверяет, что в классе есть место.
+// Строка класса блокируется до конца транзакции, чтобы параллельные зачисления не превысили вместимость
+func checkStudentCapacity(tx *sql.Tx, s mod.Student, prev *mod.Student) error {
+	if s.ClassID == nil || (prev != nil && sameClassID(s.ClassID, prev.ClassID)) {
+		return nil
+	}
+	var capacity, enrolled int
+	err := tx.QueryRow("SELECT capacity FROM classes WHERE id = ? FOR UPDATE", *s.ClassID).Scan(&capacity)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error locking class")
+	}
+	err = tx.QueryRow("SELECT COUNT(*) FROM students WHERE classId = ? AND id <> ? AND deletedAt IS NULL", *s.ClassID, s.ID).Scan(&enrolled)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error counting students")
+	}
+	if enrolled >= capacity {
+		return utils.ErrorHandler(utils.ErrCapacityExceeded, fmt.Sprintf("Class %s is full (%d of %d)", s.Class, enrolled, capacity))
+	}
+	return nil
+}
+
+func sameClassID(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// withIndex — проставляет индекс элемента bulk-запроса в ошибках валидации
+func withIndex(err error, index int) error {
+	var verr *utils.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	for i := range verr.Errors {
+		verr.Errors[i].Index = &index
+	}
+	return verr
+}
@@ -2,7 +2,9 @@ package sqlconnect
 
 import (
 	model "WebProject/internal/models"
+	"WebProject/internal/search"
 	"WebProject/pkg/utils"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
@@ -10,7 +12,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/go-mail/mail/v2"
 	"net/http"
 	"os"
 	"reflect"
@@ -18,22 +19,30 @@ import (
 	"time"
 )
 
-func GetAllExecs(r *http.Request) ([]model.Exec, error) {
-	query := "SELECT id, firstname, lastname, email, username,  usercreatedat, inactivestatus, role FROM execs WHERE 1=1"
+func GetAllExecs(r *http.Request) ([]model.Exec, utils.PageInfo, error) {
+	query := "SELECT id, firstname, lastname, email, username,  usercreatedat, inactivestatus, role, teacherId, guardianId, version, updatedAt FROM execs WHERE 1=1"
 	var args []interface{}
 
-	query, args = utils.AddFilters(r, query, args)
-	query = utils.AddSorting(r, query)
+	query, args, err := utils.AddFilters(r, model.Exec{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, model.Exec{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
 
 	db, err := ConnectDB()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error querying DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
 	}
 	defer rows.Close()
 
@@ -42,13 +51,22 @@ func GetAllExecs(r *http.Request) ([]model.Exec, error) {
 	for rows.Next() {
 		var Exec model.Exec
 		err := rows.Scan(&Exec.ID, &Exec.FirstName, &Exec.LastName, &Exec.Email,
-			&Exec.Username, &Exec.UserCreatedAt, &Exec.InactiveStatus, &Exec.Role)
+			&Exec.Username, &Exec.UserCreatedAt, &Exec.InactiveStatus, &Exec.Role, &Exec.TeacherID, &Exec.GuardianID, &Exec.Version, &Exec.UpdatedAt)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error scanning DB")
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
 		}
 		ExecList = append(ExecList, Exec)
 	}
-	return ExecList, nil
+
+	ExecList, info := utils.Paginate(r, page, ExecList)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return ExecList, info, nil
 }
 
 func FindExecById(err error, id int, Exec model.Exec) (model.Exec, error) {
@@ -59,10 +77,10 @@ func FindExecById(err error, id int, Exec model.Exec) (model.Exec, error) {
 	defer db.Close()
 
 	err = db.QueryRow(
-		"SELECT id, firstname, lastname, email, username,  usercreatedat, inactivestatus, role FROM execs WHERE id = ?",
+		"SELECT id, firstname, lastname, email, username,  usercreatedat, inactivestatus, role, teacherId, guardianId, version, updatedAt FROM execs WHERE id = ?",
 		id,
 	).Scan(&Exec.ID, &Exec.FirstName, &Exec.LastName, &Exec.Email,
-		&Exec.Username, &Exec.UserCreatedAt, &Exec.InactiveStatus, &Exec.Role)
+		&Exec.Username, &Exec.UserCreatedAt, &Exec.InactiveStatus, &Exec.Role, &Exec.TeacherID, &Exec.GuardianID, &Exec.Version, &Exec.UpdatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.Exec{}, utils.ErrorHandler(err, "Exec not found")
@@ -86,20 +104,41 @@ func SaveExecs(r *http.Request) ([]model.Exec, error) {
 		return nil, utils.ErrorHandler(err, "Error decoding JSON")
 	}
 
-	stmt, err := db.Prepare(utils.GenerateSQL(model.Exec{}, "insert"))
+	err = utils.ValidateSlice(newExecs)
 	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(model.Exec{}, "insert"))
+	if err != nil {
+		tx.Rollback()
 		return nil, utils.ErrorHandler(err, "Error preparing statement")
 	}
 	defer stmt.Close()
 
 	addedExecs := make([]model.Exec, len(newExecs))
 	for i, Exec := range newExecs {
+		err = checkExecTeacher(tx, Exec)
+		if err == nil {
+			err = checkExecGuardian(tx, Exec)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
 		if Exec.Password == "" {
-			return nil, utils.ErrorHandler(err, "Enter valid password")
+			tx.Rollback()
+			return nil, utils.ErrorHandler(errors.New("empty password"), "Enter valid password")
 		}
 
 		err, encodedPass := utils.PasswordHashing(Exec.Password)
 		if err != nil {
+			tx.Rollback()
 			return nil, utils.ErrorHandler(err, "Error hashing password")
 		}
 
@@ -107,31 +146,58 @@ func SaveExecs(r *http.Request) ([]model.Exec, error) {
 
 		res, err := stmt.Exec(utils.GetStructFields(Exec, true, false)...)
 		if err != nil {
+			tx.Rollback()
 			return nil, utils.ErrorHandler(err, "Error inserting Exec")
 		}
 		lastId, err := res.LastInsertId()
 		if err != nil {
+			tx.Rollback()
 			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
 		}
 		Exec.ID = int(lastId)
+		Exec.Version = 1
+		err = recordHistory(r.Context(), tx, historyExec, Exec.ID, historyCreate, nil, Exec)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 		addedExecs[i] = Exec
 	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	for _, Exec := range addedExecs {
+		indexExec(Exec)
+	}
 	return addedExecs, nil
 }
 
+// execCredentialFields — поля, которые меняются только через смену и сброс пароля, но не через PATCH
+var execCredentialFields = []string{"password", "passwordChangedAt", "resetCode", "tokenExpiresAt"}
+
 // PatchExecById — частичное обновление по ID
-func PatchExecById(err error, id int, updates map[string]interface{}) (model.Exec, error) {
+func PatchExecById(ctx context.Context, err error, id int, updates map[string]interface{}, expectedVersion int) (model.Exec, error) {
 	var existingExec model.Exec
 
+	var errs []utils.FieldError
+	for _, k := range execCredentialFields {
+		if _, ok := updates[k]; ok {
+			errs = append(errs, utils.FieldError{Field: k, Message: "cannot be changed with PATCH, use /execs/{id}/updatepassword"})
+		}
+	}
+	if len(errs) > 0 {
+		return model.Exec{}, &utils.ValidationError{Errors: errs}
+	}
+
 	db, err := ConnectDB()
 	if err != nil {
 		return model.Exec{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
-	err = db.QueryRow("SELECT id, firstname, lastname, email, username,  usercreatedat, inactivestatus, role FROM execs WHERE id = ?", id).
-		Scan(&existingExec.ID, &existingExec.FirstName, &existingExec.LastName, &existingExec.Email,
-			&existingExec.Username, &existingExec.UserCreatedAt, &existingExec.InactiveStatus, &existingExec.Role)
+	// читаем запись целиком, иначе UPDATE по всем полям затрёт пароль и токены сброса
+	err = db.QueryRow(utils.GenerateSQL(model.Exec{}, "select"), id).Scan(utils.GetStructFields(&existingExec, true, true)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.Exec{}, utils.ErrorHandler(err, "Exec not found")
@@ -139,16 +205,31 @@ func PatchExecById(err error, id int, updates map[string]interface{}) (model.Exe
 		return model.Exec{}, utils.ErrorHandler(err, "Error fetching Exec")
 	}
 
+	err = utils.CheckVersion(expectedVersion, existingExec.Version)
+	if err != nil {
+		return model.Exec{}, err
+	}
+
+	before := existingExec
 	ExecVal := reflect.ValueOf(&existingExec).Elem()
 	ExecType := ExecVal.Type()
 
 	for k, v := range updates {
 		for i := 0; i < ExecVal.NumField(); i++ {
 			field := ExecType.Field(i)
-			if field.Tag.Get("json") == k {
+			if field.Tag.Get("json") == k && field.Tag.Get("readonly") != "true" {
 				if ExecVal.Field(i).CanSet() {
+					fieldType := ExecVal.Field(i).Type()
+					if v == nil && fieldType.Kind() == reflect.Ptr {
+						ExecVal.Field(i).Set(reflect.Zero(fieldType))
+						continue
+					}
 					val := reflect.ValueOf(v)
-					if val.Type().ConvertibleTo(ExecVal.Field(i).Type()) {
+					if v != nil && fieldType.Kind() == reflect.Ptr && val.Type().ConvertibleTo(fieldType.Elem()) {
+						ptr := reflect.New(fieldType.Elem())
+						ptr.Elem().Set(val.Convert(fieldType.Elem()))
+						ExecVal.Field(i).Set(ptr)
+					} else if v != nil && val.Type().ConvertibleTo(fieldType) {
 						ExecVal.Field(i).Set(val.Convert(ExecVal.Field(i).Type()))
 					} else {
 						return model.Exec{}, utils.ErrorHandler(errors.New("type mismatch"), "Invalid JSON value for field "+k)
@@ -158,35 +239,70 @@ func PatchExecById(err error, id int, updates map[string]interface{}) (model.Exe
 		}
 	}
 
+	err = utils.Validate(existingExec)
+	if err == nil {
+		err = checkExecTeacher(db, existingExec)
+	}
+	if err == nil {
+		err = checkExecGuardian(db, existingExec)
+	}
+	if err != nil {
+		return model.Exec{}, err
+	}
+
 	fields := utils.GetStructFields(existingExec, false, false)
-	fields = append(fields, existingExec.ID)
+	fields = append(fields, existingExec.ID, existingExec.Version)
 
-	_, err = db.Exec(utils.GenerateSQL(model.Exec{}, "update"), fields...)
+	existingExec.Version++
+	existingExec.UpdatedAt = nowTimestamp()
+	err = withTx(db, func(tx *sql.Tx) error {
+		err := execVersionedUpdate(tx, utils.GenerateSQL(model.Exec{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating Exec")
+		}
+		return recordHistory(ctx, tx, historyExec, id, historyUpdate, before, existingExec)
+	})
 	if err != nil {
-		return model.Exec{}, utils.ErrorHandler(err, "Error updating Exec")
+		return model.Exec{}, err
 	}
+	existingExec.Password = ""
+	existingExec.ResetCode = sql.NullString{}
+	indexExec(existingExec)
 	return existingExec, nil
 }
 
 // DeleteExecById — удаление по ID
-func DeleteExecById(err error, id int) error {
+func DeleteExecById(ctx context.Context, err error, id int) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
-	res, err := db.Exec("DELETE FROM execs where id = ?", id)
-	if err != nil {
-		return utils.ErrorHandler(err, "Error deleting Exec")
-	}
-	rows, err := res.RowsAffected()
+	var released []string
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[model.Exec](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Exec not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching Exec")
+		}
+		released, err = deleteOwnerAttachments(tx, ownerExec, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM execs where id = ?", id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting Exec")
+		}
+		return recordHistory(ctx, tx, historyExec, id, historyDelete, existing, nil)
+	})
 	if err != nil {
-		return utils.ErrorHandler(err, "Error checking deletion result")
-	}
-	if rows == 0 {
-		return utils.ErrorHandler(errors.New("no rows affected"), "Exec not found")
+		return err
 	}
+	removeStoredFiles(released...)
+	search.Default.Remove(search.TypeExec, id)
 	return nil
 }
 
@@ -212,7 +328,7 @@ func GetUserByUsername(username string) (error, *model.Exec) {
 }
 
 // UpdatePasswordById обновляем пароль по определенному ID и возвращаем токен
-func UpdatePasswordById(userId int, req model.UpdatePasswordRequest) (string, error) {
+func UpdatePasswordById(ctx context.Context, userId int, req model.UpdatePasswordRequest) (string, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		return "", utils.ErrorHandler(err, "Cannot connect to database")
@@ -240,7 +356,7 @@ func UpdatePasswordById(userId int, req model.UpdatePasswordRequest) (string, er
 
 	passwordChangedAt := time.Now().Format(time.RFC3339)
 
-	_, err = db.Exec("UPDATE Execs SET password=?, passwordChangedAt = ? WHERE id=?", encodedPass, passwordChangedAt, userId)
+	err = updatePassword(ctx, db, userId, "UPDATE Execs SET password=?, passwordChangedAt = ? WHERE id=?", encodedPass, passwordChangedAt)
 	if err != nil {
 		return "", utils.ErrorHandler(err, "Cannot update password,db error")
 	}
@@ -294,20 +410,14 @@ func ForgotPasswordDB(email string) {
 	//send email
 	resetUrl := fmt.Sprintf("http://localhost:8080/execs/resetpassword/reset/%s", token)
 	message := fmt.Sprintf("Use this link to reset the password: %s\nReset link valid %s minutes", resetUrl, os.Getenv("RESET_TOKEN_EXP_DURATION"))
-	m := mail.NewMessage()
-	m.SetHeader("From", "school.admin@example.com")
-	m.SetHeader("To", email)
-	m.SetHeader("Subject", "Password Reset Link")
-	m.SetBody("text/html", message)
-	d := mail.NewDialer("localhost", 1025, "", "")
-	err = d.DialAndSend(m)
+	err = utils.SendMail("Password Reset Link", message, email)
 	if err != nil {
 		utils.ErrorHandler(err, "Failed to send password reset email ")
 		return
 	}
 }
 
-func PasswordResetDB(newPassword string, hash string) error {
+func PasswordResetDB(ctx context.Context, newPassword string, hash string) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Cannot connect to database")
@@ -334,7 +444,7 @@ func PasswordResetDB(newPassword string, hash string) error {
 
 	passwordChangedAt := time.Now().Format(time.RFC3339)
 
-	_, err = db.Exec("UPDATE Execs SET password=?, passwordChangedAt = ?,passwordResetToken = NULL,tokenExpiresAt = NULL WHERE id=?", encodedPass, passwordChangedAt, exec.ID)
+	err = updatePassword(ctx, db, exec.ID, "UPDATE Execs SET password=?, passwordChangedAt = ?,passwordResetToken = NULL,tokenExpiresAt = NULL WHERE id=?", encodedPass, passwordChangedAt)
 	if err != nil {
 
 		return utils.ErrorHandler(err, "Cannot update password,db error")
@@ -342,3 +452,96 @@ func PasswordResetDB(newPassword string, hash string) error {
 	}
 	return nil
 }
+
+// updatePassword — смена пароля вместе со строкой журнала; сам хеш в журнал не попадает, видно только passwordChangedAt
+func updatePassword(ctx context.Context, db *sql.DB, id int, query, encodedPass, passwordChangedAt string) error {
+	return withTx(db, func(tx *sql.Tx) error {
+		before, err := selectForUpdate[model.Exec](tx, id)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(query, encodedPass, passwordChangedAt, id)
+		if err != nil {
+			return err
+		}
+		after := before
+		after.PasswordChangedAt = sql.NullString{String: passwordChangedAt, Valid: true}
+		return recordHistory(ctx, tx, historyExec, id, historyUpdate, before, after)
+	})
+}
+
+// checkExecTeacher — пользователь с ролью teacher должен быть связан с существующим учителем
+func checkExecTeacher(q queryer, exec model.Exec) error {
+	if exec.TeacherID == nil {
+		if exec.Role == "teacher" {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "teacherId", Message: "is required for role teacher"}}}
+		}
+		return nil
+	}
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", *exec.TeacherID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher")
+	}
+	if n == 0 {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "teacherId", Message: "teacher not found"}}}
+	}
+	return nil
+}
+
+// checkExecGuardian — пользователь с ролью parent должен быть связан с существующим опекуном
+func checkExecGuardian(q queryer, exec model.Exec) error {
+	if exec.GuardianID == nil {
+		if exec.Role == "parent" {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "guardianId", Message: "is required for role parent"}}}
+		}
+		return nil
+	}
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM guardians WHERE id = ?", *exec.GuardianID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking guardian")
+	}
+	if n == 0 {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "guardianId", Message: "guardian not found"}}}
+	}
+	return nil
+}
+
+// TeacherIdForUser — карточка учителя, связанная с учётной записью (для роли teacher)
+func TeacherIdForUser(userId int) (int, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var teacherID sql.NullInt64
+	err = db.QueryRow("SELECT teacherId FROM execs WHERE id = ? AND inactiveStatus = FALSE", userId).Scan(&teacherID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if !teacherID.Valid {
+		return 0, utils.ErrorHandler(utils.ErrForbidden, "User is not linked to a teacher")
+	}
+	return int(teacherID.Int64), nil
+}
+
+// GuardianIdForUser — карточка опекуна, связанная с учётной записью (для роли parent)
+func GuardianIdForUser(userId int) (int, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var guardianID sql.NullInt64
+	err = db.QueryRow("SELECT guardianId FROM execs WHERE id = ? AND inactiveStatus = FALSE", userId).Scan(&guardianID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if !guardianID.Valid {
+		return 0, utils.ErrorHandler(utils.ErrForbidden, "User is not linked to a guardian")
+	}
+	return int(guardianID.Int64), nil
+}
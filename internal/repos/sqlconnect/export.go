@@ -0,0 +1,133 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"net/http"
+	"strings"
+)
+
+// exportFlushRows — как часто выгрузка проталкивает накопленные строки клиенту
+const exportFlushRows = 500
+
+// exportExtra — вычисляемая колонка выгрузки (подзапрос по id записи); выгружается, если ?fields не задан
+type exportExtra struct {
+	Name string
+	SQL  string
+}
+
+// studentGuardiansExport — опекуны студента одной строкой: "Анна Иванова (mother, +7 900 000-00-00, anna@mail.ru, emergency); ..."
+var studentGuardiansExport = exportExtra{
+	Name: "guardians",
+	SQL: `(SELECT GROUP_CONCAT(CONCAT(g.firstName, ' ', g.lastName, ' (',
+		CONCAT_WS(', ', g.relationship, g.phone, g.email, IF(g.emergencyContact, 'emergency', NULL)), ')')
+		ORDER BY g.emergencyContact DESC, g.lastName, g.firstName SEPARATOR '; ')
+		FROM student_guardians sg JOIN guardians g ON g.id = sg.guardianId WHERE sg.studentId = students.id)`,
+}
+
+// ExportStudents — потоковая выгрузка студентов с фильтрами и сортировкой списка; опекуны — отдельной колонкой
+func ExportStudents(r *http.Request, open func() utils.ExportWriter) error {
+	return exportRecords[mod.Student](r, "students", open, studentGuardiansExport)
+}
+
+// ExportTeachers — потоковая выгрузка учителей
+func ExportTeachers(r *http.Request, open func() utils.ExportWriter) error {
+	return exportRecords[mod.Teacher](r, "teachers", open)
+}
+
+// ExportExecs — потоковая выгрузка сотрудников; пароль и токены сброса исключены тегом export:"-"
+func ExportExecs(r *http.Request, open func() utils.ExportWriter) error {
+	return exportRecords[mod.Exec](r, "execs", open)
+}
+
+// exportRecords — читает строки из rows.Next() и сразу пишет их клиенту, не собирая таблицу в памяти.
+// open вызывается только после успешного запроса, чтобы ошибки фильтров ещё могли вернуть нормальный статус
+func exportRecords[T any](r *http.Request, table string, open func() utils.ExportWriter, extras ...exportExtra) error {
+	var model T
+	columns, err := utils.ExportColumns(r, model)
+	if err != nil {
+		return err
+	}
+	dbColumns := make([]string, len(columns))
+	for i, c := range columns {
+		dbColumns[i] = c.DB
+	}
+	if r.URL.Query().Get("fields") != "" {
+		extras = nil
+	}
+	selected := append([]string(nil), dbColumns...)
+	header := append([]utils.ExportColumn(nil), columns...)
+	for _, e := range extras {
+		selected = append(selected, e.SQL)
+		header = append(header, utils.ExportColumn{DB: e.Name, Name: e.Name})
+	}
+
+	query := "SELECT " + strings.Join(selected, ", ") + " FROM " + table + " WHERE 1=1" + utils.NotDeleted(model)
+	var args []interface{}
+	query, args, err = utils.AddFilters(r, model, query, args)
+	if err != nil {
+		return err
+	}
+	if r.URL.Query().Get("sortBy") != "" {
+		query, err = utils.AddSorting(r, model, query)
+		if err != nil {
+			return err
+		}
+	} else {
+		query += " ORDER BY id"
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	out := open()
+	err = out.WriteHeader(header)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error writing export")
+	}
+	n := 0
+	for rows.Next() {
+		var rec T
+		extraValues := make([]sql.NullString, len(extras))
+		dest := utils.GetScanFields(&rec, dbColumns)
+		for i := range extraValues {
+			dest = append(dest, &extraValues[i])
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error scanning DB")
+		}
+		values := utils.ExportValues(rec, columns)
+		for _, v := range extraValues {
+			if v.Valid {
+				values = append(values, v.String)
+			} else {
+				values = append(values, nil)
+			}
+		}
+		err = out.WriteRow(values)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error writing export")
+		}
+		n++
+		if n%exportFlushRows == 0 {
+			if err = out.Flush(); err != nil {
+				return utils.ErrorHandler(err, "Error writing export")
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return utils.ErrorHandler(err, "Error reading rows")
+	}
+	return out.Close()
+}
@@ -0,0 +1,653 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// teachesSQL — условие «учитель ведёт предмет работы в её классе» (назначение или карточка учителя);
+// alias — псевдоним таблицы assessments, аргументы — teacherID дважды
+func teachesSQL(alias string) string {
+	return "(EXISTS (SELECT 1 FROM assignments x WHERE x.teacherId = ? AND x.classId = " + alias + ".classId AND x.subject = " + alias + ".subject)" +
+		" OR EXISTS (SELECT 1 FROM teachers t JOIN classes c ON c.name = t.class WHERE t.id = ? AND t.deletedAt IS NULL AND c.id = " + alias + ".classId AND t.subject = " + alias + ".subject))"
+}
+
+// checkTeaches — учитель может работать с журналом класса только по своим предметам; teacherID nil — admin или manager
+func checkTeaches(q queryer, teacherID *int, classID int, subject string) error {
+	if teacherID == nil {
+		return nil
+	}
+	var ok bool
+	err := q.QueryRow("SELECT "+teachesSQL("a")+" FROM (SELECT ? AS classId, ? AS subject) AS a", *teacherID, *teacherID, classID, subject).Scan(&ok)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher assignment")
+	}
+	if !ok {
+		return utils.ErrorHandler(utils.ErrForbidden, "Teacher does not teach "+subject+" in this class")
+	}
+	return nil
+}
+
+// GetAllAssessments — работы с фильтрами (?classId=, ?subject=, ?term=); учитель видит только работы по своим предметам
+func GetAllAssessments(r *http.Request, teacherID *int) ([]mod.Assessment, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Assessment{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM assessments a WHERE 1=1"
+	var args []interface{}
+	if teacherID != nil {
+		query += " AND " + teachesSQL("a")
+		args = append(args, *teacherID, *teacherID)
+	}
+
+	query, args, err = utils.AddFilters(r, mod.Assessment{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Assessment{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	assessments := make([]mod.Assessment, 0)
+	for rows.Next() {
+		var a mod.Assessment
+		err := rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		assessments = append(assessments, a)
+	}
+
+	assessments, info := utils.Paginate(r, page, assessments)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return assessments, info, nil
+}
+
+// FindAssessmentById — работа по ID
+func FindAssessmentById(id int, teacherID *int) (mod.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Assessment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	a, err := findAssessment(db, id, false)
+	if err != nil {
+		return mod.Assessment{}, err
+	}
+	err = checkTeaches(db, teacherID, a.ClassID, a.Subject)
+	if err != nil {
+		return mod.Assessment{}, err
+	}
+	return a, nil
+}
+
+func findAssessment(q queryer, id int, forUpdate bool) (mod.Assessment, error) {
+	query := utils.GenerateSQL(mod.Assessment{}, "select")
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var a mod.Assessment
+	err := q.QueryRow(query, id).Scan(utils.GetStructFields(&a, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Assessment{}, utils.ErrorHandler(err, "Assessment not found")
+		}
+		return mod.Assessment{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return a, nil
+}
+
+// SaveAssessments — создание работ; учитель создаёт работы только по своим предметам и становится их автором
+func SaveAssessments(r *http.Request, teacherID *int) ([]mod.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newAssessments []mod.Assessment
+	err = json.NewDecoder(r.Body).Decode(&newAssessments)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newAssessments {
+		normalizeAssessment(&newAssessments[i], teacherID)
+	}
+	err = utils.ValidateSlice(newAssessments)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Assessment{}, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i, a := range newAssessments {
+		err = checkAssessmentRefs(tx, a)
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
+		res, err := stmt.Exec(utils.GetStructFields(a, true, false)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error inserting assessment")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		newAssessments[i].ID = int(lastId)
+		newAssessments[i].Version = 1
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return newAssessments, nil
+}
+
+// PatchAssessmentById — частичное обновление работы; учитель не может передать работу в чужой класс или предмет
+func PatchAssessmentById(id int, patch utils.Patch, expectedVersion int, teacherID *int) (mod.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Assessment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Assessment
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := findAssessment(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, existing.ClassID, existing.Subject)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		if teacherID != nil {
+			patched.TeacherID = existing.TeacherID
+		}
+		err = checkAssessmentRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, patched.ClassID, patched.Subject)
+		if err != nil {
+			return err
+		}
+
+		// оценки выше нового максимума стали бы некорректными
+		if patched.MaxScore < existing.MaxScore {
+			var over int
+			err = tx.QueryRow("SELECT COUNT(*) FROM grades WHERE assessmentId = ? AND score > ?", id, patched.MaxScore).Scan(&over)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error checking grades")
+			}
+			if over > 0 {
+				return &utils.ValidationError{Errors: []utils.FieldError{{Field: "maxScore", Message: strconv.Itoa(over) + " grades exceed the new maximum"}}}
+			}
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Assessment{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating assessment")
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Assessment{}, err
+	}
+	return patched, nil
+}
+
+// DeleteAssessmentById — удаление работы вместе с её оценками
+func DeleteAssessmentById(id int, teacherID *int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return withTx(db, func(tx *sql.Tx) error {
+		existing, err := findAssessment(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, existing.ClassID, existing.Subject)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(utils.GenerateSQL(mod.Assessment{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting assessment")
+		}
+		return nil
+	})
+}
+
+// normalizeAssessment — вес по умолчанию 1; работа учителя всегда записывается на него самого
+func normalizeAssessment(a *mod.Assessment, teacherID *int) {
+	a.Title = strings.TrimSpace(a.Title)
+	a.Subject = strings.TrimSpace(a.Subject)
+	if a.Weight == 0 {
+		a.Weight = 1
+	}
+	if teacherID != nil {
+		id := *teacherID
+		a.TeacherID = &id
+	}
+}
+
+// checkAssessmentRefs — класс существует, а указанный учитель ведёт предмет работы в этом классе
+func checkAssessmentRefs(q queryer, a mod.Assessment) error {
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", a.ClassID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking class")
+	}
+	if n == 0 {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "classId", Message: "class not found"}}}
+	}
+	if a.TeacherID != nil {
+		err = checkTeaches(q, a.TeacherID, a.ClassID, a.Subject)
+		if errors.Is(err, utils.ErrForbidden) {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "teacherId", Message: "teacher is not assigned to " + a.Subject + " in this class"}}}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAssessmentGrades — оценки за работу
+func GetAssessmentGrades(id int, teacherID *int) ([]mod.Grade, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	a, err := findAssessment(db, id, false)
+	if err != nil {
+		return nil, err
+	}
+	err = checkTeaches(db, teacherID, a.ClassID, a.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return assessmentGrades(db, id)
+}
+
+func assessmentGrades(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, id int) ([]mod.Grade, error) {
+	columns := utils.SelectColumns(mod.Grade{}, nil)
+	rows, err := q.Query("SELECT "+strings.Join(columns, ", ")+" FROM grades WHERE assessmentId = ? ORDER BY studentId", id)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying grades")
+	}
+	defer rows.Close()
+
+	grades := make([]mod.Grade, 0)
+	for rows.Next() {
+		var g mod.Grade
+		err = rows.Scan(utils.GetScanFields(&g, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning grades")
+		}
+		grades = append(grades, g)
+	}
+	return grades, rows.Err()
+}
+
+// SaveGrades — ввод оценок за работу для всего класса одним запросом (транзакция, upsert по студенту).
+// Студент должен учиться в классе работы, балл — от 0 до maxScore; ошибки возвращаются все сразу с индексом строки
+func SaveGrades(id int, entries []mod.GradeEntry, teacherID *int) ([]mod.Grade, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var grades []mod.Grade
+	err = withTx(db, func(tx *sql.Tx) error {
+		a, err := findAssessment(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, a.ClassID, a.Subject)
+		if err != nil {
+			return err
+		}
+
+		inClass := make(map[int]bool)
+		rows, err := tx.Query("SELECT id FROM students WHERE classId = ? AND deletedAt IS NULL", a.ClassID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying students")
+		}
+		for rows.Next() {
+			var sid int
+			if err := rows.Scan(&sid); err != nil {
+				rows.Close()
+				return utils.ErrorHandler(err, "Error scanning students")
+			}
+			inClass[sid] = true
+		}
+		rows.Close()
+
+		var errs []utils.FieldError
+		seen := make(map[int]bool)
+		for i, e := range entries {
+			index := i
+			fail := func(field, msg string) {
+				errs = append(errs, utils.FieldError{Index: &index, Field: field, Message: msg})
+			}
+			switch {
+			case !inClass[e.StudentID]:
+				fail("studentId", "student is not in class")
+			case seen[e.StudentID]:
+				fail("studentId", "duplicate student")
+			}
+			seen[e.StudentID] = true
+			grade := mod.Grade{AssessmentID: id, StudentID: e.StudentID, Score: e.Score, Comment: e.Comment}
+			for _, fe := range utils.ValidateStruct(grade) {
+				fail(fe.Field, fe.Message)
+			}
+			if e.Score != nil && *e.Score > a.MaxScore {
+				fail("score", "must be at most "+strconv.FormatFloat(a.MaxScore, 'f', -1, 64))
+			}
+		}
+		if len(errs) > 0 {
+			return &utils.ValidationError{Errors: errs}
+		}
+
+		stmt, err := tx.Prepare(`INSERT INTO grades (assessmentId, studentId, score, comment) VALUES (?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE version = IF(score <=> VALUES(score) AND comment <=> VALUES(comment), version, version + 1),
+			score = VALUES(score), comment = VALUES(comment)`)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+		for _, e := range entries {
+			_, err = stmt.Exec(id, e.StudentID, e.Score, e.Comment)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error saving grade")
+			}
+		}
+		grades, err = assessmentGrades(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// GetStudentGrades — оценки студента по предметам и четвертям со взвешенным средним; term=0 — все четверти
+func GetStudentGrades(studentID, term int, teacherID *int) (mod.StudentGrades, error) {
+	result := mod.StudentGrades{StudentID: studentID, Subjects: []mod.SubjectGrades{}}
+	db, err := ConnectDB()
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var n int
+	err = db.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deletedAt IS NULL", studentID).Scan(&n)
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if n == 0 {
+		return result, utils.ErrorHandler(sql.ErrNoRows, "Student not found")
+	}
+
+	columns := prefixColumns("a", utils.SelectColumns(mod.Assessment{}, nil))
+	query := "SELECT " + strings.Join(columns, ", ") + ", g.score, g.comment FROM grades g JOIN assessments a ON a.id = g.assessmentId WHERE g.studentId = ?"
+	args := []interface{}{studentID}
+	if term != 0 {
+		query += " AND a.term = ?"
+		args = append(args, term)
+	}
+	if teacherID != nil {
+		query += " AND " + teachesSQL("a")
+		args = append(args, *teacherID, *teacherID)
+	}
+	query += " ORDER BY a.subject, a.term, a.date, a.id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error querying grades")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sg mod.StudentGrade
+		err = rows.Scan(append(utils.GetStructFields(&sg.Assessment, true, true), &sg.Score, &sg.Comment)...)
+		if err != nil {
+			return result, utils.ErrorHandler(err, "Error scanning grades")
+		}
+
+		// строки отсортированы по предмету и четверти — достаточно смотреть на последний элемент
+		subjects := &result.Subjects
+		if len(*subjects) == 0 || (*subjects)[len(*subjects)-1].Subject != sg.Assessment.Subject {
+			*subjects = append(*subjects, mod.SubjectGrades{Subject: sg.Assessment.Subject, Terms: []mod.TermAverage{}})
+		}
+		subject := &(*subjects)[len(*subjects)-1]
+		if len(subject.Terms) == 0 || subject.Terms[len(subject.Terms)-1].Term != sg.Assessment.Term {
+			subject.Terms = append(subject.Terms, mod.TermAverage{Term: sg.Assessment.Term, Grades: []mod.StudentGrade{}})
+		}
+		t := &subject.Terms[len(subject.Terms)-1]
+		t.Grades = append(t.Grades, sg)
+	}
+	err = rows.Err()
+	if err != nil {
+		return result, utils.ErrorHandler(err, "Error querying grades")
+	}
+
+	for i := range result.Subjects {
+		for j := range result.Subjects[i].Terms {
+			t := &result.Subjects[i].Terms[j]
+			items := make([]weightedScore, 0, len(t.Grades))
+			for _, g := range t.Grades {
+				items = append(items, weightedScore{Score: g.Score, MaxScore: g.Assessment.MaxScore, Weight: g.Assessment.Weight})
+			}
+			t.Average = weightedAverage(items)
+		}
+	}
+	return result, nil
+}
+
+// GetClassGradebook — журнал класса: работы (с фильтром по предмету и четверти) и оценки каждого студента
+func GetClassGradebook(classID int, subject string, term int, teacherID *int) (mod.Gradebook, error) {
+	book := mod.Gradebook{ClassID: classID, Subject: subject, Term: term, Assessments: []mod.Assessment{}, Rows: []mod.GradebookRow{}}
+	db, err := ConnectDB()
+	if err != nil {
+		return book, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = db.QueryRow("SELECT name FROM classes WHERE id = ?", classID).Scan(&book.Class)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return book, utils.ErrorHandler(err, "Class not found")
+		}
+		return book, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if subject != "" {
+		err = checkTeaches(db, teacherID, classID, subject)
+		if err != nil {
+			return book, err
+		}
+	}
+
+	columns := utils.SelectColumns(mod.Assessment{}, nil)
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM assessments a WHERE classId = ?"
+	args := []interface{}{classID}
+	if subject != "" {
+		query += " AND subject = ?"
+		args = append(args, subject)
+	}
+	if term != 0 {
+		query += " AND term = ?"
+		args = append(args, term)
+	}
+	if teacherID != nil {
+		query += " AND " + teachesSQL("a")
+		args = append(args, *teacherID, *teacherID)
+	}
+	rows, err := db.Query(query+" ORDER BY date, id", args...)
+	if err != nil {
+		return book, utils.ErrorHandler(err, "Error querying assessments")
+	}
+	byID := make(map[int]mod.Assessment)
+	for rows.Next() {
+		var a mod.Assessment
+		err = rows.Scan(utils.GetScanFields(&a, columns)...)
+		if err != nil {
+			rows.Close()
+			return book, utils.ErrorHandler(err, "Error scanning assessments")
+		}
+		book.Assessments = append(book.Assessments, a)
+		byID[a.ID] = a
+	}
+	rows.Close()
+
+	students, err := studentsByClassIds(db, map[int]bool{classID: true})
+	if err != nil {
+		return book, err
+	}
+
+	scores := make(map[int]map[int]*float64)
+	if len(book.Assessments) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(book.Assessments)), ", ")
+		ids := make([]interface{}, 0, len(book.Assessments))
+		for _, a := range book.Assessments {
+			ids = append(ids, a.ID)
+		}
+		rows, err = db.Query("SELECT assessmentId, studentId, score FROM grades WHERE assessmentId IN ("+placeholders+")", ids...)
+		if err != nil {
+			return book, utils.ErrorHandler(err, "Error querying grades")
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var assessmentID, studentID int
+			var score *float64
+			err = rows.Scan(&assessmentID, &studentID, &score)
+			if err != nil {
+				return book, utils.ErrorHandler(err, "Error scanning grades")
+			}
+			if scores[studentID] == nil {
+				scores[studentID] = make(map[int]*float64)
+			}
+			scores[studentID][assessmentID] = score
+		}
+		err = rows.Err()
+		if err != nil {
+			return book, utils.ErrorHandler(err, "Error querying grades")
+		}
+	}
+
+	for _, s := range students[classID] {
+		row := mod.GradebookRow{Student: s, Scores: make(map[int]*float64)}
+		items := make([]weightedScore, 0, len(book.Assessments))
+		for _, a := range book.Assessments {
+			score := scores[s.ID][a.ID]
+			row.Scores[a.ID] = score
+			items = append(items, weightedScore{Score: score, MaxScore: a.MaxScore, Weight: a.Weight})
+		}
+		row.Average = weightedAverage(items)
+		book.Rows = append(book.Rows, row)
+	}
+	sort.SliceStable(book.Rows, func(i, j int) bool {
+		return book.Rows[i].Student.LastName < book.Rows[j].Student.LastName
+	})
+	return book, nil
+}
+
+// weightedScore — балл за работу с её весом и максимумом
+type weightedScore struct {
+	Score    *float64
+	MaxScore float64
+	Weight   float64
+}
+
+// weightedAverage — Σ(вес × балл/максимум) / Σ вес в процентах, по оценённым работам; nil, если оценок нет
+func weightedAverage(items []weightedScore) *float64 {
+	var sum, weights float64
+	for _, it := range items {
+		if it.Score == nil || it.MaxScore <= 0 {
+			continue
+		}
+		sum += it.Weight * *it.Score / it.MaxScore
+		weights += it.Weight
+	}
+	if weights == 0 {
+		return nil
+	}
+	avg := math.Round(sum/weights*100*100) / 100
+	return &avg
+}
+
+// prefixColumns — колонки с псевдонимом таблицы для запросов с JOIN
+func prefixColumns(alias string, columns []string) []string {
+	prefixed := make([]string, len(columns))
+	for i, c := range columns {
+		prefixed[i] = alias + "." + c
+	}
+	return prefixed
+}
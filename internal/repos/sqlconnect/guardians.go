@@ -0,0 +1,423 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// GetAllGuardians — список опекунов с фильтрами; у каждого — его студенты
+func GetAllGuardians(r *http.Request) ([]mod.Guardian, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Guardian{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM guardians WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Guardian{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Guardian{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	guardians := make([]mod.Guardian, 0)
+	for rows.Next() {
+		var g mod.Guardian
+		err := rows.Scan(utils.GetScanFields(&g, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		guardians = append(guardians, g)
+	}
+
+	guardians, info := utils.Paginate(r, page, guardians)
+	err = fillGuardianStudents(db, guardians)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return guardians, info, nil
+}
+
+// FindGuardianById — опекун по ID вместе с его студентами
+func FindGuardianById(id int) (mod.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Guardian{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var g mod.Guardian
+	err = db.QueryRow(utils.GenerateSQL(mod.Guardian{}, "select"), id).Scan(utils.GetStructFields(&g, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Guardian{}, utils.ErrorHandler(err, "Guardian not found")
+		}
+		return mod.Guardian{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+
+	list := []mod.Guardian{g}
+	err = fillGuardianStudents(db, list)
+	if err != nil {
+		return mod.Guardian{}, err
+	}
+	return list[0], nil
+}
+
+// fillGuardianStudents — ID студентов (без удалённых) для каждого опекуна одним запросом
+func fillGuardianStudents(q rowsQueryer, guardians []mod.Guardian) error {
+	if len(guardians) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(guardians)), ", ")
+	args := make([]interface{}, len(guardians))
+	for i, g := range guardians {
+		args[i] = g.ID
+	}
+	rows, err := q.Query(`SELECT sg.guardianId, sg.studentId FROM student_guardians sg JOIN students s ON s.id = sg.studentId
+		WHERE sg.guardianId IN (`+placeholders+`) AND s.deletedAt IS NULL ORDER BY sg.studentId`, args...)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying guardian students")
+	}
+	defer rows.Close()
+
+	students := make(map[int][]int)
+	for rows.Next() {
+		var guardianID, studentID int
+		if err := rows.Scan(&guardianID, &studentID); err != nil {
+			return utils.ErrorHandler(err, "Error scanning guardian students")
+		}
+		students[guardianID] = append(students[guardianID], studentID)
+	}
+	for i := range guardians {
+		guardians[i].StudentIDs = students[guardians[i].ID]
+		if guardians[i].StudentIDs == nil {
+			guardians[i].StudentIDs = []int{}
+		}
+	}
+	return rows.Err()
+}
+
+// SaveGuardians — создание опекунов из JSON (транзакция) вместе со связями studentIds
+func SaveGuardians(r *http.Request) ([]mod.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newGuardians []mod.Guardian
+	err = json.NewDecoder(r.Body).Decode(&newGuardians)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newGuardians {
+		normalizeGuardian(&newGuardians[i])
+	}
+	err = utils.ValidateSlice(newGuardians)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(utils.GenerateSQL(mod.Guardian{}, "insert"))
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+
+		for i := range newGuardians {
+			g := &newGuardians[i]
+			err = checkGuardianStudents(tx, g.StudentIDs)
+			if err != nil {
+				return withIndex(err, i)
+			}
+			res, err := stmt.Exec(utils.GetStructFields(*g, true, false)...)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error inserting guardian")
+			}
+			lastId, err := res.LastInsertId()
+			if err != nil {
+				return utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			g.ID = int(lastId)
+			g.Version = 1
+			err = setGuardianStudents(tx, g.ID, g.StudentIDs)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newGuardians, nil
+}
+
+// PatchGuardianById — частичное обновление опекуна; studentIds в патче заменяет связи со студентами целиком
+func PatchGuardianById(id int, patch utils.Patch, expectedVersion int) (mod.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Guardian{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Guardian
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Guardian](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Guardian not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching guardian")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+		list := []mod.Guardian{existing}
+		err = fillGuardianStudents(tx, list)
+		if err != nil {
+			return err
+		}
+		existing = list[0]
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		normalizeGuardian(&patched)
+		err = utils.Validate(patched)
+		if err != nil {
+			return err
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Guardian{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating guardian")
+		}
+		if !slices.Equal(patched.StudentIDs, existing.StudentIDs) {
+			err = checkGuardianStudents(tx, patched.StudentIDs)
+			if err != nil {
+				return err
+			}
+			// связи со студентами в корзине не трогаем: они вернутся вместе со студентом
+			_, err = tx.Exec(`DELETE sg FROM student_guardians sg JOIN students s ON s.id = sg.studentId
+				WHERE sg.guardianId = ? AND s.deletedAt IS NULL`, id)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error updating guardian students")
+			}
+			err = setGuardianStudents(tx, id, patched.StudentIDs)
+			if err != nil {
+				return err
+			}
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Guardian{}, err
+	}
+	return patched, nil
+}
+
+// DeleteGuardianById — удаление опекуна вместе со связями со студентами
+func DeleteGuardianById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Guardian{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting guardian")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking delete result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Guardian not found")
+	}
+	return nil
+}
+
+// GetStudentGuardians — опекуны студента; учитель видит их, только если ведёт класс студента
+func GetStudentGuardians(studentID int, teacherID *int) ([]mod.Guardian, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = checkTeachesStudent(db, teacherID, studentID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := utils.SelectColumns(mod.Guardian{}, nil)
+	rows, err := db.Query(`SELECT `+strings.Join(prefixColumns("g", columns), ", ")+` FROM guardians g
+		JOIN student_guardians sg ON sg.guardianId = g.id WHERE sg.studentId = ?
+		ORDER BY g.emergencyContact DESC, g.lastName, g.firstName, g.id`, studentID)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying guardians")
+	}
+	defer rows.Close()
+
+	guardians := make([]mod.Guardian, 0)
+	for rows.Next() {
+		var g mod.Guardian
+		err = rows.Scan(utils.GetScanFields(&g, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning guardians")
+		}
+		guardians = append(guardians, g)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, utils.ErrorHandler(err, "Error scanning guardians")
+	}
+	err = fillGuardianStudents(db, guardians)
+	if err != nil {
+		return nil, err
+	}
+	return guardians, nil
+}
+
+// LinkStudentGuardian — связывает опекуна со студентом; повторная связь не ошибка
+func LinkStudentGuardian(studentID, guardianID int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	return withTx(db, func(tx *sql.Tx) error {
+		var n int
+		err := tx.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deletedAt IS NULL", studentID).Scan(&n)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying DB")
+		}
+		if n == 0 {
+			return utils.ErrorHandler(sql.ErrNoRows, "Student not found")
+		}
+		err = tx.QueryRow("SELECT COUNT(*) FROM guardians WHERE id = ?", guardianID).Scan(&n)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying DB")
+		}
+		if n == 0 {
+			return utils.ErrorHandler(sql.ErrNoRows, "Guardian not found")
+		}
+		_, err = tx.Exec("INSERT IGNORE INTO student_guardians (studentId, guardianId) VALUES (?, ?)", studentID, guardianID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error linking guardian")
+		}
+		return nil
+	})
+}
+
+// UnlinkStudentGuardian — убирает связь опекуна со студентом; сам опекун остаётся
+func UnlinkStudentGuardian(studentID, guardianID int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM student_guardians WHERE studentId = ? AND guardianId = ?", studentID, guardianID)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error unlinking guardian")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking unlink result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Guardian is not linked to this student")
+	}
+	return nil
+}
+
+// normalizeGuardian — пробелы по краям; пустые телефон, email и заметки — NULL; studentIds без повторов
+func normalizeGuardian(g *mod.Guardian) {
+	g.FirstName = strings.TrimSpace(g.FirstName)
+	g.LastName = strings.TrimSpace(g.LastName)
+	g.Relationship = strings.ToLower(strings.TrimSpace(g.Relationship))
+	for _, s := range []**string{&g.Phone, &g.Email, &g.CustodyNotes} {
+		if *s != nil {
+			v := strings.TrimSpace(**s)
+			*s = &v
+			if v == "" {
+				*s = nil
+			}
+		}
+	}
+	slices.Sort(g.StudentIDs)
+	g.StudentIDs = slices.Compact(g.StudentIDs)
+	if g.StudentIDs == nil {
+		g.StudentIDs = []int{}
+	}
+}
+
+// checkGuardianStudents — все studentIds должны быть существующими (не удалёнными) студентами
+func checkGuardianStudents(q queryer, ids []int) error {
+	var errs []utils.FieldError
+	for _, id := range ids {
+		var n int
+		err := q.QueryRow("SELECT COUNT(*) FROM students WHERE id = ? AND deletedAt IS NULL", id).Scan(&n)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking students")
+		}
+		if n == 0 {
+			errs = append(errs, utils.FieldError{Field: "studentIds", Message: "student " + strconv.Itoa(id) + " not found"})
+		}
+	}
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func setGuardianStudents(ex execer, guardianID int, studentIDs []int) error {
+	for _, studentID := range studentIDs {
+		_, err := ex.Exec("INSERT INTO student_guardians (studentId, guardianId) VALUES (?, ?)", studentID, guardianID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error linking guardian")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	historyStudent = "student"
+	historyTeacher = "teacher"
+	historyExec    = "exec"
+
+	historyCreate  = "create"
+	historyUpdate  = "update"
+	historyDelete  = "delete"
+	historyRestore = "restore"
+	historyPurge   = "purge"
+)
+
+// historyTimeFormat — changedAt пишется из Go с микросекундами, чтобы порядок и asOf не зависели от часового пояса MySQL
+const historyTimeFormat = "2006-01-02 15:04:05.000000"
+
+// actorFromContext — автор изменения из JWT (userId, username); без токена — system
+func actorFromContext(ctx context.Context) (*string, *string) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var actorID, actorName *string
+	if id := ctx.Value(utils.ContextKey("userId")); id != nil {
+		s := fmt.Sprint(id)
+		actorID = &s
+	}
+	if name, ok := ctx.Value(utils.ContextKey("username")).(string); ok && name != "" {
+		actorName = &name
+	}
+	if actorID == nil && actorName == nil {
+		system := "system"
+		actorName = &system
+	}
+	return actorID, actorName
+}
+
+// recordHistory — пишет строку журнала тем же соединением или транзакцией, что и само изменение.
+// before/after — модели до и после (nil, если записи не было или больше нет); обновление без изменений не пишется
+func recordHistory(ctx context.Context, ex execer, entity string, id int, action string, before, after interface{}) error {
+	diff := utils.Diff(before, after)
+	if action == historyUpdate && len(diff) == 0 {
+		return nil
+	}
+
+	snapshot := func(model interface{}) (interface{}, error) {
+		if model == nil {
+			return nil, nil
+		}
+		data, err := json.Marshal(utils.Snapshot(model))
+		return string(data), err
+	}
+	beforeData, err := snapshot(before)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error encoding history")
+	}
+	afterData, err := snapshot(after)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error encoding history")
+	}
+	diffData, err := json.Marshal(diff)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error encoding history")
+	}
+
+	actorID, actorName := actorFromContext(ctx)
+	_, err = ex.Exec("INSERT INTO history (entity, entityId, action, actorId, actorName, changedAt, beforeData, afterData, diff) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		entity, id, action, actorID, actorName, time.Now().Format(historyTimeFormat), beforeData, afterData, string(diffData))
+	if err != nil {
+		return utils.ErrorHandler(err, "Error writing history")
+	}
+	return nil
+}
+
+// withTx — выполняет fn в транзакции, чтобы изменение и строка журнала фиксировались вместе
+func withTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error starting transaction")
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return nil
+}
+
+// GetStudentHistory — журнал изменений студента, новые записи первыми
+func GetStudentHistory(id int) ([]mod.HistoryEntry, error) {
+	return getHistory(historyStudent, id)
+}
+
+// GetTeacherHistory — журнал изменений учителя
+func GetTeacherHistory(id int) ([]mod.HistoryEntry, error) {
+	return getHistory(historyTeacher, id)
+}
+
+// GetExecHistory — журнал изменений exec; пароли и токены в журнал не попадают
+func GetExecHistory(id int) ([]mod.HistoryEntry, error) {
+	return getHistory(historyExec, id)
+}
+
+func getHistory(entity string, id int) ([]mod.HistoryEntry, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, entity, entityId, action, actorId, actorName, changedAt, beforeData, afterData, diff FROM history WHERE entity = ? AND entityId = ? ORDER BY changedAt DESC, id DESC", entity, id)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying history")
+	}
+	defer rows.Close()
+
+	entries := make([]mod.HistoryEntry, 0)
+	for rows.Next() {
+		var e mod.HistoryEntry
+		var before, after, diff []byte
+		err = rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &e.ActorID, &e.ActorName, &e.ChangedAt, &before, &after, &diff)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning history")
+		}
+		e.Before, e.After, e.Diff = before, after, diff
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// StudentAsOf — состояние студента на момент asOf, восстановленное по журналу
+func StudentAsOf(id int, asOf time.Time) (mod.Student, error) {
+	return recordAsOf(historyStudent, id, asOf, func() (mod.Student, error) {
+		return FindStudentById(nil, id, mod.Student{})
+	})
+}
+
+// TeacherAsOf — состояние учителя на момент asOf
+func TeacherAsOf(id int, asOf time.Time) (mod.Teacher, error) {
+	return recordAsOf(historyTeacher, id, asOf, func() (mod.Teacher, error) {
+		return FindTeacherById(nil, id, mod.Teacher{})
+	})
+}
+
+// ExecAsOf — состояние exec на момент asOf
+func ExecAsOf(id int, asOf time.Time) (mod.Exec, error) {
+	return recordAsOf(historyExec, id, asOf, func() (mod.Exec, error) {
+		return FindExecById(nil, id, mod.Exec{})
+	})
+}
+
+// recordAsOf — берёт состояние «после» последнего изменения не позже asOf;
+// если таких нет, но есть более поздние — состояние «до» первого из них (запись существовала до начала журнала);
+// если журнал пуст, запись с тех пор не менялась и текущее состояние совпадает с искомым
+func recordAsOf[T any](entity string, id int, asOf time.Time, current func() (T, error)) (T, error) {
+	var rec T
+	db, err := ConnectDB()
+	if err != nil {
+		return rec, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	at := asOf.In(time.Local).Format(historyTimeFormat)
+	var state []byte
+	err = db.QueryRow("SELECT afterData FROM history WHERE entity = ? AND entityId = ? AND changedAt <= ? ORDER BY changedAt DESC, id DESC LIMIT 1",
+		entity, id, at).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = db.QueryRow("SELECT beforeData FROM history WHERE entity = ? AND entityId = ? AND changedAt > ? ORDER BY changedAt, id LIMIT 1",
+			entity, id, at).Scan(&state)
+		if errors.Is(err, sql.ErrNoRows) {
+			return current()
+		}
+	}
+	if err != nil {
+		return rec, utils.ErrorHandler(err, "Error querying history")
+	}
+	if state == nil {
+		return rec, utils.ErrorHandler(sql.ErrNoRows, "Record did not exist at "+asOf.Format(time.RFC3339))
+	}
+
+	err = utils.RestoreSnapshot(state, &rec)
+	if err != nil {
+		return rec, utils.ErrorHandler(err, "Error decoding history")
+	}
+	return rec, nil
+}
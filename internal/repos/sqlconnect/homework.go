@@ -0,0 +1,869 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/internal/storage"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GetAllHomework — задания с фильтрами (?classId=, ?subject=, ?dueAt[gte]=); учитель видит только задания по своим предметам
+func GetAllHomework(r *http.Request, teacherID *int) ([]mod.Homework, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Homework{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM homework h WHERE 1=1"
+	var args []interface{}
+	if teacherID != nil {
+		query += " AND " + teachesSQL("h")
+		args = append(args, *teacherID, *teacherID)
+	}
+
+	query, args, err = utils.AddFilters(r, mod.Homework{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Homework{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	homework := make([]mod.Homework, 0)
+	for rows.Next() {
+		var h mod.Homework
+		err := rows.Scan(utils.GetScanFields(&h, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		homework = append(homework, h)
+	}
+
+	homework, info := utils.Paginate(r, page, homework)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return homework, info, nil
+}
+
+// FindHomeworkById — задание с приложенными файлами
+func FindHomeworkById(id int, teacherID *int) (mod.Homework, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Homework{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, id, false)
+	if err != nil {
+		return mod.Homework{}, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return mod.Homework{}, err
+	}
+	h.Attachments, err = homeworkAttachments(db, "a.homeworkId = ?", id)
+	if err != nil {
+		return mod.Homework{}, err
+	}
+	return h, nil
+}
+
+func findHomework(q queryer, id int, forUpdate bool) (mod.Homework, error) {
+	query := utils.GenerateSQL(mod.Homework{}, "select")
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	var h mod.Homework
+	err := q.QueryRow(query, id).Scan(utils.GetStructFields(&h, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Homework{}, utils.ErrorHandler(err, "Homework not found")
+		}
+		return mod.Homework{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return h, nil
+}
+
+// homeworkAttachments — файлы заданий по условию where на таблицу homework_attachments a
+func homeworkAttachments(q rowsQueryer, where string, args ...interface{}) ([]mod.HomeworkFile, error) {
+	rows, err := q.Query(`SELECT a.id, a.homeworkId, a.fileName, a.contentType, a.size, a.storageKey, a.uploadedAt
+		FROM homework_attachments a WHERE `+where+" ORDER BY a.id", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying attachments")
+	}
+	defer rows.Close()
+
+	files := make([]mod.HomeworkFile, 0)
+	for rows.Next() {
+		var f mod.HomeworkFile
+		err = rows.Scan(&f.ID, &f.HomeworkID, &f.FileName, &f.ContentType, &f.Size, &f.StorageKey, &f.UploadedAt)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning attachments")
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// SaveHomework — создание заданий; учитель задаёт их только по своим предметам и становится автором
+func SaveHomework(r *http.Request, teacherID *int) ([]mod.Homework, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newHomework []mod.Homework
+	err = json.NewDecoder(r.Body).Decode(&newHomework)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newHomework {
+		normalizeHomework(&newHomework[i], teacherID)
+	}
+	err = utils.ValidateSlice(newHomework)
+	if err != nil {
+		return nil, err
+	}
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		stmt, err := tx.Prepare(utils.GenerateSQL(mod.Homework{}, "insert"))
+		if err != nil {
+			return utils.ErrorHandler(err, "Error preparing statement")
+		}
+		defer stmt.Close()
+
+		for i, h := range newHomework {
+			err = checkHomeworkRefs(tx, h)
+			if err != nil {
+				return withIndex(err, i)
+			}
+			res, err := stmt.Exec(utils.GetStructFields(h, true, false)...)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error inserting homework")
+			}
+			lastId, err := res.LastInsertId()
+			if err != nil {
+				return utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			newHomework[i].ID = int(lastId)
+			newHomework[i].Version = 1
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newHomework, nil
+}
+
+// PatchHomeworkById — частичное обновление задания; maxScore нельзя опустить ниже уже выставленных баллов,
+// у задания, связанного с журналом, новый maxScore переходит и в работу журнала
+func PatchHomeworkById(id int, patch utils.Patch, expectedVersion int, teacherID *int) (mod.Homework, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Homework{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Homework
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := findHomework(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, existing.ClassID, existing.Subject)
+		if err != nil {
+			return err
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		normalizeHomework(&patched, nil)
+		patched.AssessmentID = existing.AssessmentID
+		patched.Attachments = nil
+		if teacherID != nil {
+			patched.TeacherID = existing.TeacherID
+		}
+		err = checkHomeworkRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, patched.ClassID, patched.Subject)
+		if err != nil {
+			return err
+		}
+		err = checkHomeworkMaxScore(tx, existing, patched)
+		if err != nil {
+			return err
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Homework{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating homework")
+		}
+		if existing.AssessmentID != nil && *patched.MaxScore != *existing.MaxScore {
+			_, err = tx.Exec("UPDATE assessments SET maxScore = ?, version = version + 1 WHERE id = ?", *patched.MaxScore, *existing.AssessmentID)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error updating assessment")
+			}
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Homework{}, err
+	}
+	return patched, nil
+}
+
+// checkHomeworkMaxScore — баллы работ (и оценки связанной работы журнала) не должны превышать новый maxScore
+func checkHomeworkMaxScore(q queryer, existing, patched mod.Homework) error {
+	maxScoreErr := func(msg string) error {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: "maxScore", Message: msg}}}
+	}
+	if patched.MaxScore == nil {
+		if existing.AssessmentID != nil {
+			return maxScoreErr("required while the homework is in the gradebook")
+		}
+		var graded int
+		err := q.QueryRow("SELECT COUNT(*) FROM homework_submissions WHERE homeworkId = ? AND score IS NOT NULL", existing.ID).Scan(&graded)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking submissions")
+		}
+		if graded > 0 {
+			return maxScoreErr(strconv.Itoa(graded) + " submissions are already scored")
+		}
+		return nil
+	}
+	if existing.MaxScore == nil || *patched.MaxScore >= *existing.MaxScore {
+		return nil
+	}
+
+	var over int
+	err := q.QueryRow("SELECT COUNT(*) FROM homework_submissions WHERE homeworkId = ? AND score > ?", existing.ID, *patched.MaxScore).Scan(&over)
+	if err == nil && existing.AssessmentID != nil {
+		var overGrades int
+		err = q.QueryRow("SELECT COUNT(*) FROM grades WHERE assessmentId = ? AND score > ?", *existing.AssessmentID, *patched.MaxScore).Scan(&overGrades)
+		over = max(over, overGrades)
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking scores")
+	}
+	if over > 0 {
+		return maxScoreErr(strconv.Itoa(over) + " scores exceed the new maximum")
+	}
+	return nil
+}
+
+// DeleteHomeworkById — удаление задания вместе с файлами и работами студентов; работа журнала остаётся
+func DeleteHomeworkById(id int, teacherID *int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var keys []string
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := findHomework(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, existing.ClassID, existing.Subject)
+		if err != nil {
+			return err
+		}
+
+		rows, err := tx.Query(`SELECT storageKey FROM homework_attachments WHERE homeworkId = ?
+			UNION ALL SELECT storageKey FROM homework_submissions WHERE homeworkId = ?`, id, id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error querying files")
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				rows.Close()
+				return utils.ErrorHandler(err, "Error scanning files")
+			}
+			keys = append(keys, key)
+		}
+		rows.Close()
+
+		_, err = tx.Exec(utils.GenerateSQL(mod.Homework{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting homework")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	removeStoredFiles(keys...)
+	return nil
+}
+
+// normalizeHomework — обрезает пробелы; задание учителя всегда записывается на него самого
+func normalizeHomework(h *mod.Homework, teacherID *int) {
+	h.Title = strings.TrimSpace(h.Title)
+	h.Subject = strings.TrimSpace(h.Subject)
+	h.DueAt = strings.TrimSpace(h.DueAt)
+	if teacherID != nil {
+		id := *teacherID
+		h.TeacherID = &id
+	}
+}
+
+// checkHomeworkRefs — те же проверки класса и учителя, что и у работы журнала
+func checkHomeworkRefs(q queryer, h mod.Homework) error {
+	return checkAssessmentRefs(q, mod.Assessment{ClassID: h.ClassID, Subject: h.Subject, TeacherID: h.TeacherID})
+}
+
+// storeFile — проверяет размер и тип содержимого документа и кладёт его в хранилище под новым ключом в каталоге prefix
+func storeFile(prefix string, file storage.File) (key string, up *storage.Upload, err error) {
+	up, err = storage.Spool(file, storage.MaxFileSize(), storage.DocumentTypes)
+	if err != nil {
+		return "", nil, err
+	}
+	defer up.Close()
+
+	key = storage.NewKey(prefix, file.Name)
+	_, err = storage.Default().Put(key, up)
+	if err != nil {
+		return "", nil, utils.ErrorHandler(err, "Error storing file")
+	}
+	return key, up, nil
+}
+
+// removeStoredFiles — удаляет файлы, на которые больше не ссылается база; ошибки только пишутся в лог
+func removeStoredFiles(keys ...string) {
+	for _, key := range keys {
+		err := storage.Default().Delete(key)
+		if err != nil {
+			log.Println("ERROR: cannot delete stored file", key, err)
+		}
+	}
+}
+
+// fileName — имя файла для базы: без пути и не длиннее 255 символов
+func fileName(name string) string {
+	name = strings.TrimSpace(name[strings.LastIndexAny(name, `/\`)+1:])
+	if name == "" {
+		name = "file"
+	}
+	if r := []rune(name); len(r) > 255 {
+		name = string(r[len(r)-255:])
+	}
+	return name
+}
+
+// AddHomeworkAttachment — прикладывает файл к заданию
+func AddHomeworkAttachment(id int, file storage.File, teacherID *int) (mod.HomeworkFile, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.HomeworkFile{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, id, false)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+
+	key, up, err := storeFile(fmt.Sprintf("homework/%d", id), file)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+	attachment := mod.HomeworkFile{
+		HomeworkID:  id,
+		FileName:    fileName(file.Name),
+		ContentType: up.ContentType,
+		Size:        up.Size,
+		StorageKey:  key,
+		UploadedAt:  *nowTimestamp(),
+	}
+	res, err := db.Exec("INSERT INTO homework_attachments (homeworkId, fileName, contentType, size, storageKey, uploadedAt) VALUES (?, ?, ?, ?, ?, ?)",
+		id, attachment.FileName, attachment.ContentType, attachment.Size, key, attachment.UploadedAt)
+	if err != nil {
+		removeStoredFiles(key)
+		return mod.HomeworkFile{}, utils.ErrorHandler(err, "Error saving attachment")
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return mod.HomeworkFile{}, utils.ErrorHandler(err, "Error getting last insert ID")
+	}
+	attachment.ID = int(lastId)
+	return attachment, nil
+}
+
+// FindHomeworkAttachment — файл задания для скачивания
+func FindHomeworkAttachment(id, attachmentID int, teacherID *int) (mod.HomeworkFile, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.HomeworkFile{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, id, false)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+	files, err := homeworkAttachments(db, "a.homeworkId = ? AND a.id = ?", id, attachmentID)
+	if err != nil {
+		return mod.HomeworkFile{}, err
+	}
+	if len(files) == 0 {
+		return mod.HomeworkFile{}, utils.ErrorHandler(sql.ErrNoRows, "Attachment not found")
+	}
+	return files[0], nil
+}
+
+// DeleteHomeworkAttachment — удаляет файл задания из базы и хранилища
+func DeleteHomeworkAttachment(id, attachmentID int, teacherID *int) error {
+	attachment, err := FindHomeworkAttachment(id, attachmentID, teacherID)
+	if err != nil {
+		return err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec("DELETE FROM homework_attachments WHERE id = ? AND homeworkId = ?", attachmentID, id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting attachment")
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting attachment")
+	}
+	if n == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Attachment not found")
+	}
+	removeStoredFiles(attachment.StorageKey)
+	return nil
+}
+
+// submissionSQL — работы вместе с именем студента
+const submissionSQL = `SELECT hs.id, hs.homeworkId, hs.studentId, s.firstName, s.lastName, hs.fileName, hs.contentType, hs.size,
+	hs.storageKey, hs.comment, hs.submittedAt, hs.late, hs.score, hs.feedback, hs.gradedAt, hs.version, hs.updatedAt
+	FROM homework_submissions hs JOIN students s ON s.id = hs.studentId`
+
+func submissionScanFields(s *mod.HomeworkSubmission) []interface{} {
+	return []interface{}{&s.ID, &s.HomeworkID, &s.StudentID, &s.FirstName, &s.LastName, &s.FileName, &s.ContentType, &s.Size,
+		&s.StorageKey, &s.Comment, &s.SubmittedAt, &s.Late, &s.Score, &s.Feedback, &s.GradedAt, &s.Version, &s.UpdatedAt}
+}
+
+func querySubmissions(q rowsQueryer, where string, args ...interface{}) ([]mod.HomeworkSubmission, error) {
+	rows, err := q.Query(submissionSQL+" WHERE s.deletedAt IS NULL AND "+where+" ORDER BY s.lastName, s.firstName, hs.id", args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying submissions")
+	}
+	defer rows.Close()
+
+	submissions := make([]mod.HomeworkSubmission, 0)
+	for rows.Next() {
+		var s mod.HomeworkSubmission
+		err = rows.Scan(submissionScanFields(&s)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning submissions")
+		}
+		submissions = append(submissions, s)
+	}
+	return submissions, rows.Err()
+}
+
+func findSubmission(q rowsQueryer, homeworkID, studentID int) (mod.HomeworkSubmission, error) {
+	submissions, err := querySubmissions(q, "hs.homeworkId = ? AND hs.studentId = ?", homeworkID, studentID)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	if len(submissions) == 0 {
+		return mod.HomeworkSubmission{}, utils.ErrorHandler(sql.ErrNoRows, "Submission not found")
+	}
+	return submissions[0], nil
+}
+
+// GetHomeworkSubmissions — сданные работы по заданию
+func GetHomeworkSubmissions(id int, teacherID *int) ([]mod.HomeworkSubmission, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, id, false)
+	if err != nil {
+		return nil, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return nil, err
+	}
+	return querySubmissions(db, "hs.homeworkId = ?", id)
+}
+
+// FindSubmission — работа студента по заданию
+func FindSubmission(homeworkID, studentID int, teacherID *int) (mod.HomeworkSubmission, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.HomeworkSubmission{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, homeworkID, false)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	return findSubmission(db, homeworkID, studentID)
+}
+
+// SaveSubmission — сдача (или повторная сдача) работы студентом его класса; после срока работа отмечается late.
+// Оценённую работу заменить нельзя
+func SaveSubmission(homeworkID, studentID int, file storage.File, comment *string, teacherID *int) (mod.HomeworkSubmission, error) {
+	if comment != nil && len([]rune(*comment)) > 1000 {
+		return mod.HomeworkSubmission{}, &utils.ValidationError{Errors: []utils.FieldError{{Field: "comment", Message: "must be at most 1000 characters"}}}
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.HomeworkSubmission{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	h, err := findHomework(db, homeworkID, false)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	err = checkTeaches(db, teacherID, h.ClassID, h.Subject)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	var inClass bool
+	err = db.QueryRow("SELECT EXISTS (SELECT 1 FROM students WHERE id = ? AND classId = ? AND deletedAt IS NULL)", studentID, h.ClassID).Scan(&inClass)
+	if err != nil {
+		return mod.HomeworkSubmission{}, utils.ErrorHandler(err, "Error querying students")
+	}
+	if !inClass {
+		return mod.HomeworkSubmission{}, &utils.ValidationError{Errors: []utils.FieldError{{Field: "studentId", Message: "student is not in class"}}}
+	}
+
+	key, up, err := storeFile(fmt.Sprintf("homework/%d/submissions/%d", homeworkID, studentID), file)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+
+	var submission mod.HomeworkSubmission
+	var oldKey string
+	err = withTx(db, func(tx *sql.Tx) error {
+		var score *float64
+		err := tx.QueryRow("SELECT storageKey, score FROM homework_submissions WHERE homeworkId = ? AND studentId = ? FOR UPDATE",
+			homeworkID, studentID).Scan(&oldKey, &score)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return utils.ErrorHandler(err, "Error querying submission")
+		}
+		if score != nil {
+			return utils.ErrorHandler(utils.ErrInUse, "Submission is already graded")
+		}
+
+		_, err = tx.Exec(`INSERT INTO homework_submissions (homeworkId, studentId, fileName, contentType, size, storageKey, comment, submittedAt, late)
+			SELECT ?, ?, ?, ?, ?, ?, ?, NOW(), NOW() > dueAt FROM homework WHERE id = ?
+			ON DUPLICATE KEY UPDATE fileName = VALUES(fileName), contentType = VALUES(contentType), size = VALUES(size),
+			storageKey = VALUES(storageKey), comment = VALUES(comment), submittedAt = VALUES(submittedAt), late = VALUES(late), version = version + 1`,
+			homeworkID, studentID, fileName(file.Name), up.ContentType, up.Size, key, comment, homeworkID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving submission")
+		}
+		submission, err = findSubmission(tx, homeworkID, studentID)
+		return err
+	})
+	if err != nil {
+		removeStoredFiles(key)
+		return mod.HomeworkSubmission{}, err
+	}
+	if oldKey != "" {
+		removeStoredFiles(oldKey)
+	}
+	return submission, nil
+}
+
+// SaveSubmissionFeedback — отзыв и балл учителя; у задания в журнале балл сразу становится оценкой работы журнала
+func SaveSubmissionFeedback(homeworkID, studentID int, fb mod.SubmissionFeedback, teacherID *int) (mod.HomeworkSubmission, error) {
+	err := utils.Validate(fb)
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.HomeworkSubmission{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var submission mod.HomeworkSubmission
+	err = withTx(db, func(tx *sql.Tx) error {
+		h, err := findHomework(tx, homeworkID, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, h.ClassID, h.Subject)
+		if err != nil {
+			return err
+		}
+		if fb.Score != nil {
+			if h.MaxScore == nil {
+				return &utils.ValidationError{Errors: []utils.FieldError{{Field: "score", Message: "homework has no maxScore"}}}
+			}
+			if *fb.Score > *h.MaxScore {
+				return &utils.ValidationError{Errors: []utils.FieldError{{Field: "score", Message: "must be at most " + strconv.FormatFloat(*h.MaxScore, 'f', -1, 64)}}}
+			}
+		}
+
+		res, err := tx.Exec("UPDATE homework_submissions SET score = ?, feedback = ?, gradedAt = NOW(), version = version + 1 WHERE homeworkId = ? AND studentId = ?",
+			fb.Score, fb.Feedback, homeworkID, studentID)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving feedback")
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error saving feedback")
+		}
+		if n == 0 {
+			return utils.ErrorHandler(sql.ErrNoRows, "Submission not found")
+		}
+
+		if h.AssessmentID != nil {
+			_, err = tx.Exec(`INSERT INTO grades (assessmentId, studentId, score) VALUES (?, ?, ?)
+				ON DUPLICATE KEY UPDATE version = IF(score <=> VALUES(score), version, version + 1), score = VALUES(score)`,
+				*h.AssessmentID, studentID, fb.Score)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error saving grade")
+			}
+		}
+		submission, err = findSubmission(tx, homeworkID, studentID)
+		return err
+	})
+	if err != nil {
+		return mod.HomeworkSubmission{}, err
+	}
+	return submission, nil
+}
+
+// LinkHomeworkGradebook — создаёт по заданию работу журнала и переносит в неё уже выставленные баллы;
+// дальше баллы из отзывов попадают в журнал автоматически. Повторный вызов возвращает уже связанную работу
+func LinkHomeworkGradebook(id int, req mod.HomeworkGradebook, teacherID *int) (mod.Assessment, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Assessment{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var assessment mod.Assessment
+	err = withTx(db, func(tx *sql.Tx) error {
+		h, err := findHomework(tx, id, true)
+		if err != nil {
+			return err
+		}
+		err = checkTeaches(tx, teacherID, h.ClassID, h.Subject)
+		if err != nil {
+			return err
+		}
+		if h.AssessmentID != nil {
+			assessment, err = findAssessment(tx, *h.AssessmentID, false)
+			return err
+		}
+		if h.MaxScore == nil {
+			return &utils.ValidationError{Errors: []utils.FieldError{{Field: "maxScore", Message: "required to add homework to the gradebook"}}}
+		}
+
+		date := h.DueAt[:len("2006-01-02")]
+		term := req.Term
+		if term == 0 {
+			err = tx.QueryRow(`SELECT t.number FROM terms t
+				JOIN academic_years y ON y.id = t.academicYearId
+				JOIN classes c ON c.academicYear = y.name
+				WHERE c.id = ? AND ? BETWEEN t.startDate AND t.endDate`, h.ClassID, date).Scan(&term)
+			if errors.Is(err, sql.ErrNoRows) {
+				return &utils.ValidationError{Errors: []utils.FieldError{{Field: "term", Message: "required, due date is outside the terms of the class's academic year"}}}
+			}
+			if err != nil {
+				return utils.ErrorHandler(err, "Error querying terms")
+			}
+		}
+
+		title := []rune(h.Title)
+		if len(title) > 100 {
+			title = title[:100]
+		}
+		assessment = mod.Assessment{
+			Title:     string(title),
+			Subject:   h.Subject,
+			ClassID:   h.ClassID,
+			TeacherID: h.TeacherID,
+			Date:      date,
+			Term:      term,
+			Weight:    req.Weight,
+			MaxScore:  *h.MaxScore,
+		}
+		normalizeAssessment(&assessment, nil)
+		err = utils.Validate(assessment)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec(utils.GenerateSQL(mod.Assessment{}, "insert"), utils.GetStructFields(assessment, true, false)...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error inserting assessment")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		assessment.ID = int(lastId)
+		assessment.Version = 1
+
+		err = execVersionedUpdate(tx, "UPDATE homework SET assessmentId = ?, version = version + 1 WHERE id = ? AND version = ?", assessment.ID, id, h.Version)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error linking homework")
+		}
+		_, err = tx.Exec(`INSERT INTO grades (assessmentId, studentId, score)
+			SELECT ?, hs.studentId, hs.score FROM homework_submissions hs JOIN students s ON s.id = hs.studentId
+			WHERE hs.homeworkId = ? AND hs.score IS NOT NULL AND s.deletedAt IS NULL`, assessment.ID, id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error copying scores")
+		}
+		return nil
+	})
+	if err != nil {
+		return mod.Assessment{}, err
+	}
+	return assessment, nil
+}
+
+// GetStudentHomework — задания текущего класса студента с его работами, новые сроки первыми
+func GetStudentHomework(studentID int) ([]mod.ChildHomework, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	columns := prefixColumns("h", utils.SelectColumns(mod.Homework{}, nil))
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+` FROM homework h
+		JOIN students s ON s.classId = h.classId
+		WHERE s.id = ? AND s.deletedAt IS NULL ORDER BY h.dueAt DESC, h.id DESC`, studentID)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying homework")
+	}
+	defer rows.Close()
+
+	homework := make([]mod.ChildHomework, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var h mod.ChildHomework
+		err = rows.Scan(utils.GetStructFields(&h.Homework, true, true)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning homework")
+		}
+		h.Attachments = []mod.HomeworkFile{}
+		index[h.ID] = len(homework)
+		homework = append(homework, h)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying homework")
+	}
+	if len(homework) == 0 {
+		return homework, nil
+	}
+
+	attachments, err := homeworkAttachments(db, "a.homeworkId IN (SELECT h.id FROM homework h JOIN students s ON s.classId = h.classId WHERE s.id = ?)", studentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		if i, ok := index[a.HomeworkID]; ok {
+			homework[i].Attachments = append(homework[i].Attachments, a)
+		}
+	}
+
+	submissions, err := querySubmissions(db, "hs.studentId = ?", studentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range submissions {
+		if i, ok := index[sub.HomeworkID]; ok {
+			sub := sub
+			homework[i].Submission = &sub
+		}
+	}
+	return homework, nil
+}
+
+// CheckStudentHomework — задание выдано текущему классу студента; иначе not found
+func CheckStudentHomework(studentID, homeworkID int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var ok bool
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM homework h JOIN students s ON s.classId = h.classId
+		WHERE h.id = ? AND s.id = ? AND s.deletedAt IS NULL)`, homeworkID, studentID).Scan(&ok)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error querying homework")
+	}
+	if !ok {
+		return utils.ErrorHandler(sql.ErrNoRows, "Homework not found")
+	}
+	return nil
+}
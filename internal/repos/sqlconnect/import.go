@@ -0,0 +1,373 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportOptions — параметры импорта из CSV/XLSX
+type ImportOptions struct {
+	Mapping  map[string]string
+	DryRun   bool
+	Progress func(stage string, processed, total int)
+}
+
+// ImportStudents — upsert студентов по email из строк файла
+func ImportStudents(ctx context.Context, rows [][]string, opts ImportOptions) (mod.ImportResult, error) {
+	result, saved, err := importRecords[mod.Student](ctx, historyStudent, rows, opts)
+	for _, s := range saved {
+		indexStudent(s)
+	}
+	return result, err
+}
+
+// ImportTeachers — upsert учителей по email из строк файла
+func ImportTeachers(ctx context.Context, rows [][]string, opts ImportOptions) (mod.ImportResult, error) {
+	result, saved, err := importRecords[mod.Teacher](ctx, historyTeacher, rows, opts)
+	for _, t := range saved {
+		indexTeacher(t)
+	}
+	return result, err
+}
+
+// importPlan — проверенная строка файла, готовая к записи
+type importPlan[T any] struct {
+	row      int
+	record   T
+	existing T
+	found    bool
+	version  int
+}
+
+// importRecords — сначала проверяет все строки (валидация, дубликаты, поиск по email, вместимость классов),
+// затем, если ошибок нет и это не dry run, пишет всё в одной транзакции
+func importRecords[T any](ctx context.Context, entity string, rows [][]string, opts ImportOptions) (mod.ImportResult, []T, error) {
+	var model T
+	result := mod.ImportResult{DryRun: opts.DryRun, Rows: []mod.ImportRow{}, Errors: []mod.ImportRowError{}}
+	if len(rows) == 0 {
+		return result, nil, utils.ErrorHandler(utils.ErrUnsupportedFile, "File is empty")
+	}
+	columns, err := utils.MapColumns(rows[0], model, opts.Mapping)
+	if err != nil {
+		return result, nil, err
+	}
+	result.Mapping, result.Unmapped = columns.Mapping, columns.Unmapped
+	if !columns.HasField("email") {
+		return result, nil, utils.ErrorHandler(utils.ErrInvalidFields, "Email column is required for upsert")
+	}
+
+	data := rows[1:]
+	progress := func(stage string, processed int) {
+		if opts.Progress != nil {
+			opts.Progress(stage, processed, len(data))
+		}
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return result, nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	selectByEmail := strings.Replace(utils.GenerateSQL(model, "select"), "WHERE id = ?", "WHERE email = ?", 1)
+	rowError := func(row int, field, message string) {
+		result.Errors = append(result.Errors, mod.ImportRowError{Row: row, Field: field, Message: message})
+	}
+
+	var plans []importPlan[T]
+	seen := make(map[string]int)
+	for i, cells := range data {
+		rowNum := i + 2
+		progress("validating", i+1)
+
+		// пустые ячейки не затирают существующие значения при обновлении
+		values := make(map[string]string)
+		for col, field := range columns.Fields {
+			if field != "" && col < len(cells) {
+				if v := strings.TrimSpace(cells[col]); v != "" {
+					values[field] = v
+				}
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		result.Total++
+
+		key := strings.ToLower(values["email"])
+		if prev, dup := seen[key]; dup && key != "" {
+			rowError(rowNum, "email", "duplicates row "+strconv.Itoa(prev))
+			result.Failed++
+			continue
+		}
+		seen[key] = rowNum
+
+		var existing T
+		found := false
+		if key != "" {
+			err = db.QueryRow(selectByEmail, values["email"]).Scan(utils.GetStructFields(&existing, true, true)...)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return result, nil, utils.ErrorHandler(err, "Error looking up email")
+			}
+			found = err == nil
+		}
+
+		// ячейки приводятся к типам полей: classId и другие числовые столбцы иначе не декодируются
+		var record T
+		typed, err := utils.CellValues(model, values)
+		if err == nil {
+			var patchBody []byte
+			patchBody, err = json.Marshal(typed)
+			if err != nil {
+				return result, nil, utils.ErrorHandler(err, "Error encoding row")
+			}
+			record, err = patchRecord(existing, utils.Patch{ContentType: utils.ContentTypeMergePatch, Body: patchBody})
+		}
+		if err == nil {
+			var prev interface{} = &existing
+			if !found {
+				prev = nil
+			}
+			err = resolveRecordClass(db, &record, prev)
+		}
+		if err != nil {
+			var verr *utils.ValidationError
+			if !errors.As(err, &verr) {
+				rowError(rowNum, "", err.Error())
+			} else {
+				for _, fe := range verr.Errors {
+					rowError(rowNum, fe.Field, fe.Message)
+				}
+			}
+			result.Failed++
+			continue
+		}
+		plans = append(plans, importPlan[T]{row: rowNum, record: record, existing: existing, found: found, version: *recordInt(&existing, "Version")})
+	}
+
+	// вместимость классов проверяется и при dry run, чтобы он не обещал запись, которая упадёт
+	capacityErrors, err := importCapacityErrors(db, plans)
+	if err != nil {
+		return result, nil, err
+	}
+	if len(capacityErrors) > 0 {
+		full := make(map[int]bool)
+		for _, e := range capacityErrors {
+			full[e.Row] = true
+		}
+		kept := plans[:0]
+		for _, p := range plans {
+			if !full[p.row] {
+				kept = append(kept, p)
+			}
+		}
+		plans = kept
+		result.Failed += len(full)
+		result.Errors = append(result.Errors, capacityErrors...)
+		sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Row < result.Errors[j].Row })
+	}
+
+	if len(result.Errors) > 0 || opts.DryRun {
+		for _, p := range plans {
+			result.Rows = append(result.Rows, importRow(p))
+		}
+		result.Created, result.Updated = countActions(result.Rows)
+		return result, nil, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return result, nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+	insertStmt, err := tx.Prepare(utils.GenerateSQL(model, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return result, nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer insertStmt.Close()
+
+	updateSQL := utils.GenerateSQL(model, "update")
+	saved := make([]T, 0, len(plans))
+	for i, p := range plans {
+		id, version := recordInt(&p.record, "ID"), recordInt(&p.record, "Version")
+		if p.found {
+			err = checkImportCapacity(tx, p)
+			if err != nil {
+				tx.Rollback()
+				return result, nil, err
+			}
+			args := append(utils.GetStructFields(p.record, false, false), *id, p.version)
+			err = execVersionedUpdate(tx, updateSQL, args...)
+			if err != nil {
+				tx.Rollback()
+				return result, nil, utils.ErrorHandler(err, "Error updating row "+strconv.Itoa(p.row))
+			}
+			*version = p.version + 1
+			reflect.ValueOf(&p.record).Elem().FieldByName("UpdatedAt").Set(reflect.ValueOf(nowTimestamp()))
+			err = syncImportEnrollment(tx, p)
+			if err == nil {
+				err = recordHistory(ctx, tx, entity, *id, historyUpdate, p.existing, p.record)
+			}
+		} else {
+			err = checkImportCapacity(tx, p)
+			if err != nil {
+				tx.Rollback()
+				return result, nil, err
+			}
+			var res sql.Result
+			res, err = insertStmt.Exec(utils.GetStructFields(p.record, true, false)...)
+			if err != nil {
+				tx.Rollback()
+				return result, nil, utils.ErrorHandler(err, "Error inserting row "+strconv.Itoa(p.row))
+			}
+			var lastId int64
+			lastId, err = res.LastInsertId()
+			if err != nil {
+				tx.Rollback()
+				return result, nil, utils.ErrorHandler(err, "Error getting last insert ID")
+			}
+			*id = int(lastId)
+			*version = 1
+			err = syncImportEnrollment(tx, p)
+			if err == nil {
+				err = recordHistory(ctx, tx, entity, *id, historyCreate, nil, p.record)
+			}
+		}
+		if err != nil {
+			tx.Rollback()
+			return result, nil, err
+		}
+		saved = append(saved, p.record)
+		result.Rows = append(result.Rows, importRow(p))
+		progress("writing", i+1)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return result, nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+
+	result.Applied = true
+	result.Created, result.Updated = countActions(result.Rows)
+	return result, saved, nil
+}
+
+func importRow[T any](p importPlan[T]) mod.ImportRow {
+	row := mod.ImportRow{Row: p.row, Action: "create", ID: *recordInt(&p.record, "ID")}
+	if p.found {
+		row.Action = "update"
+	}
+	row.Email = reflect.ValueOf(p.record).FieldByName("Email").String()
+	return row
+}
+
+func countActions(rows []mod.ImportRow) (created, updated int) {
+	for _, r := range rows {
+		if r.Action == "update" {
+			updated++
+		} else {
+			created++
+		}
+	}
+	return created, updated
+}
+
+// recordInt — указатель на целочисленное поле записи (ID, Version)
+func recordInt[T any](record *T, name string) *int {
+	return reflect.ValueOf(record).Elem().FieldByName(name).Addr().Interface().(*int)
+}
+
+// importCapacityErrors — вместимость классов для строк файла в порядке записи: переход студента в класс
+// занимает место, уход из прежнего класса освобождает. Строки, для которых места нет, возвращаются ошибками
+func importCapacityErrors[T any](q queryer, plans []importPlan[T]) ([]mod.ImportRowError, error) {
+	type seats struct {
+		capacity, enrolled int
+	}
+	classes := make(map[int]*seats)
+	load := func(id int) (*seats, error) {
+		if c, ok := classes[id]; ok {
+			return c, nil
+		}
+		c := &seats{}
+		err := q.QueryRow("SELECT capacity, (SELECT COUNT(*) FROM students WHERE classId = classes.id AND deletedAt IS NULL) FROM classes WHERE id = ?", id).Scan(&c.capacity, &c.enrolled)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error querying class capacity")
+		}
+		classes[id] = c
+		return c, nil
+	}
+
+	var errs []mod.ImportRowError
+	for _, p := range plans {
+		s, ok := any(p.record).(mod.Student)
+		if !ok {
+			return nil, nil
+		}
+		var prevClass *int
+		if p.found {
+			prevClass = any(p.existing).(mod.Student).ClassID
+		}
+		if sameClassID(s.ClassID, prevClass) {
+			continue
+		}
+		if s.ClassID != nil {
+			c, err := load(*s.ClassID)
+			if err != nil {
+				return nil, err
+			}
+			if c.enrolled >= c.capacity {
+				errs = append(errs, mod.ImportRowError{Row: p.row, Field: "class", Message: fmt.Sprintf("class %s is full (%d of %d)", s.Class, c.enrolled, c.capacity)})
+				continue
+			}
+			c.enrolled++
+		}
+		if prevClass != nil {
+			c, err := load(*prevClass)
+			if err != nil {
+				return nil, err
+			}
+			c.enrolled--
+		}
+	}
+	return errs, nil
+}
+
+// checkImportCapacity — вместимость класса для импортируемых студентов; ошибка указывает строку файла
+func checkImportCapacity[T any](tx *sql.Tx, p importPlan[T]) error {
+	s, ok := any(p.record).(mod.Student)
+	if !ok {
+		return nil
+	}
+	var prev *mod.Student
+	if p.found {
+		existing := any(p.existing).(mod.Student)
+		prev = &existing
+	}
+	err := checkStudentCapacity(tx, s, prev)
+	if err != nil {
+		return utils.ErrorHandler(err, "Row "+strconv.Itoa(p.row))
+	}
+	return nil
+}
+
+// syncImportEnrollment — зачисление импортированного студента в учебный год его класса
+func syncImportEnrollment[T any](tx *sql.Tx, p importPlan[T]) error {
+	s, ok := any(p.record).(mod.Student)
+	if !ok {
+		return nil
+	}
+	var prev *mod.Student
+	if p.found {
+		existing := any(p.existing).(mod.Student)
+		prev = &existing
+	}
+	return syncEnrollment(tx, s, prev)
+}
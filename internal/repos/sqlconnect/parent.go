@@ -0,0 +1,96 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// childSQL — студент с учебным годом и классным руководителем его класса
+var childSQL = `SELECT ` + strings.Join(prefixColumns("s", utils.SelectColumns(mod.Student{}, nil)), ", ") + `, c.academicYear, CONCAT(t.firstName, ' ', t.lastName)
+	FROM students s
+	JOIN student_guardians sg ON sg.studentId = s.id
+	LEFT JOIN classes c ON c.id = s.classId
+	LEFT JOIN teachers t ON t.id = c.homeroomTeacherId AND t.deletedAt IS NULL
+	WHERE sg.guardianId = ? AND s.deletedAt IS NULL`
+
+// GetGuardianChildren — дети опекуна (связанные с ним студенты)
+func GetGuardianChildren(guardianID int) ([]mod.ChildProfile, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(childSQL+" ORDER BY s.lastName, s.firstName, s.id", guardianID)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying children")
+	}
+	defer rows.Close()
+
+	children := make([]mod.ChildProfile, 0)
+	for rows.Next() {
+		var child mod.ChildProfile
+		err = rows.Scan(childScanFields(&child)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning children")
+		}
+		children = append(children, child)
+	}
+	return children, rows.Err()
+}
+
+// FindGuardianChild — профиль ребёнка; чужой студент для опекуна — ErrForbidden
+func FindGuardianChild(guardianID, studentID int) (mod.ChildProfile, error) {
+	err := CheckGuardianChild(guardianID, studentID)
+	if err != nil {
+		return mod.ChildProfile{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.ChildProfile{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var child mod.ChildProfile
+	err = db.QueryRow(childSQL+" AND s.id = ?", guardianID, studentID).Scan(childScanFields(&child)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.ChildProfile{}, utils.ErrorHandler(err, "Student not found")
+		}
+		return mod.ChildProfile{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return child, nil
+}
+
+func childScanFields(child *mod.ChildProfile) []interface{} {
+	fields := utils.GetScanFields(&child.Student, utils.SelectColumns(mod.Student{}, nil))
+	return append(fields, &child.AcademicYear, &child.HomeroomTeacher)
+}
+
+// CheckGuardianChild — студент должен быть связан с опекуном; иначе ErrForbidden, удалённый студент — not found
+func CheckGuardianChild(guardianID, studentID int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var exists, linked bool
+	err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM students WHERE id = ? AND deletedAt IS NULL),
+		EXISTS (SELECT 1 FROM student_guardians WHERE studentId = ? AND guardianId = ?)`,
+		studentID, studentID, guardianID).Scan(&exists, &linked)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking guardian")
+	}
+	if !linked {
+		return utils.ErrorHandler(utils.ErrForbidden, "Student is not linked to this guardian")
+	}
+	if !exists {
+		return utils.ErrorHandler(sql.ErrNoRows, "Student not found")
+	}
+	return nil
+}
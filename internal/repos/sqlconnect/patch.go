@@ -0,0 +1,216 @@
+package sqlconnect
+
+import (
+	"WebProject/pkg/utils"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// patchRecord — применяет patch к JSON-представлению записи, строго декодирует и валидирует результат
+func patchRecord[T any](existing T, patch utils.Patch) (T, error) {
+	var patched T
+	doc, err := json.Marshal(existing)
+	if err != nil {
+		return patched, utils.ErrorHandler(err, "Error encoding record")
+	}
+	patchedDoc, err := patch.Apply(doc)
+	if err != nil {
+		return patched, err
+	}
+	return decodeRecord(existing, patchedDoc)
+}
+
+// decodeRecord — декодирует пропатченный документ и проверяет его правилами модели
+func decodeRecord[T any](existing T, patchedDoc []byte) (T, error) {
+	var patched T
+	err := utils.DecodePatched(patchedDoc, existing, &patched)
+	if err != nil {
+		return patched, err
+	}
+	err = utils.Validate(patched)
+	if err != nil {
+		return patched, err
+	}
+	return patched, nil
+}
+
+// bulkMergeItem — элемент bulk merge patch: id, ожидаемая версия и сам патч без служебных полей
+type bulkMergeItem struct {
+	ID      int
+	Version int
+	Patch   utils.Patch
+}
+
+// parseBulkMergePatch — разбирает массив объектов [{"id": 1, "version": 3, ...поля}] на отдельные merge patch
+func parseBulkMergePatch(body []byte) ([]bulkMergeItem, error) {
+	var raw []map[string]json.RawMessage
+	err := json.Unmarshal(body, &raw)
+	if err != nil {
+		return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Bulk patch must be an array of objects with id")
+	}
+
+	items := make([]bulkMergeItem, 0, len(raw))
+	for i, obj := range raw {
+		var item bulkMergeItem
+		err = json.Unmarshal(obj["id"], &item.ID)
+		if err != nil {
+			var s string
+			if json.Unmarshal(obj["id"], &s) != nil {
+				return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Missing or invalid id in item "+strconv.Itoa(i))
+			}
+			if item.ID, err = strconv.Atoi(s); err != nil {
+				return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Invalid ID format in item "+strconv.Itoa(i))
+			}
+		}
+		if v, ok := obj["version"]; ok {
+			if json.Unmarshal(v, &item.Version) != nil {
+				return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Invalid version in item "+strconv.Itoa(i))
+			}
+		}
+		delete(obj, "id")
+		delete(obj, "version")
+
+		patchBody, err := json.Marshal(obj)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error encoding patch")
+		}
+		item.Patch = utils.Patch{ContentType: utils.ContentTypeMergePatch, Body: patchBody}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// bulkJSONPatchIDs — id записей, затронутых JSON Patch коллекции (пути вида /42/class)
+func bulkJSONPatchIDs(body []byte) ([]int, error) {
+	var ops []utils.PatchOperation
+	err := json.Unmarshal(body, &ops)
+	if err != nil {
+		return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "JSON Patch must be an array of operations")
+	}
+
+	seen := make(map[int]bool)
+	var ids []int
+	for i, op := range ops {
+		paths := []string{op.Path}
+		if op.Op == "move" || op.Op == "copy" {
+			paths = append(paths, op.From)
+		}
+		for _, path := range paths {
+			segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+			id, err := strconv.Atoi(segments[0])
+			if err != nil || !strings.HasPrefix(path, "/") || len(segments) < 2 {
+				return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Operation "+strconv.Itoa(i)+": path must look like /{id}/{field}")
+			}
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, utils.ErrorHandler(errors.New("empty patch"), "JSON Patch has no operations")
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+// applyBulkJSONPatch — применяет JSON Patch к документу {"id": запись, ...} и возвращает пропатченные записи по id
+func applyBulkJSONPatch[T any](existing map[int]T, patch utils.Patch) (map[int]T, error) {
+	collection := make(map[string]T, len(existing))
+	for id, rec := range existing {
+		collection[strconv.Itoa(id)] = rec
+	}
+	doc, err := json.Marshal(collection)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error encoding records")
+	}
+
+	patchedDoc, err := patch.Apply(doc)
+	if err != nil {
+		return nil, err
+	}
+	var patchedRaw map[string]json.RawMessage
+	err = json.Unmarshal(patchedDoc, &patchedRaw)
+	if err != nil {
+		return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Patched collection is not an object")
+	}
+
+	result := make(map[int]T, len(existing))
+	var invalid []utils.FieldError
+	for id, rec := range existing {
+		raw, ok := patchedRaw[strconv.Itoa(id)]
+		if !ok {
+			return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Records cannot be removed with PATCH, use DELETE")
+		}
+		patched, err := decodeRecord(rec, raw)
+		if err != nil {
+			var verr *utils.ValidationError
+			if errors.As(err, &verr) {
+				for _, fe := range verr.Errors {
+					recID := id
+					fe.ID = &recID
+					invalid = append(invalid, fe)
+				}
+				continue
+			}
+			return nil, err
+		}
+		result[id] = patched
+	}
+	if len(patchedRaw) != len(existing) {
+		return nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Records cannot be added with PATCH, use POST")
+	}
+	if len(invalid) > 0 {
+		sort.Slice(invalid, func(i, j int) bool { return *invalid[i].ID < *invalid[j].ID })
+		return nil, &utils.ValidationError{Errors: invalid}
+	}
+	return result, nil
+}
+
+// splitBulkJSONPatch — делит JSON Patch коллекции на отдельные патчи по id (пути /42/class становятся /class);
+// операции move/copy между разными записями в режиме partial не поддерживаются
+func splitBulkJSONPatch(body []byte) ([]int, map[int]utils.Patch, error) {
+	ids, err := bulkJSONPatchIDs(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ops []utils.PatchOperation
+	err = json.Unmarshal(body, &ops)
+	if err != nil {
+		return nil, nil, utils.ErrorHandler(utils.ErrInvalidPatch, "JSON Patch must be an array of operations")
+	}
+
+	// relative — id записи и путь внутри неё
+	relative := func(path string) (int, string) {
+		segments := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+		id, _ := strconv.Atoi(segments[0])
+		return id, "/" + segments[1]
+	}
+
+	grouped := make(map[int][]utils.PatchOperation, len(ids))
+	for i, op := range ops {
+		id, path := relative(op.Path)
+		op.Path = path
+		if op.Op == "move" || op.Op == "copy" {
+			fromID, from := relative(op.From)
+			if fromID != id {
+				return nil, nil, utils.ErrorHandler(utils.ErrInvalidPatch, "Operation "+strconv.Itoa(i)+": move/copy between records is not supported in partial mode")
+			}
+			op.From = from
+		}
+		grouped[id] = append(grouped[id], op)
+	}
+
+	patches := make(map[int]utils.Patch, len(grouped))
+	for id, recOps := range grouped {
+		patchBody, err := json.Marshal(recOps)
+		if err != nil {
+			return nil, nil, utils.ErrorHandler(err, "Error encoding patch")
+		}
+		patches[id] = utils.Patch{ContentType: utils.ContentTypeJSONPatch, Body: patchBody}
+	}
+	return ids, patches, nil
+}
@@ -0,0 +1,59 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/internal/search"
+	"WebProject/pkg/utils"
+)
+
+// BuildSearchIndex — загружает учителей, студентов и execs в поисковый индекс при старте
+func BuildSearchIndex() error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	sources := []struct {
+		typ   string
+		query string
+	}{
+		{search.TypeStudent, "SELECT id, firstName, lastName, email FROM students WHERE deletedAt IS NULL"},
+		{search.TypeTeacher, "SELECT id, firstName, lastName, email FROM teachers WHERE deletedAt IS NULL"},
+		{search.TypeExec, "SELECT id, firstName, lastName, email FROM execs"},
+	}
+
+	for _, src := range sources {
+		rows, err := db.Query(src.query)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error loading search index")
+		}
+		for rows.Next() {
+			doc := search.Document{Type: src.typ}
+			err = rows.Scan(&doc.ID, &doc.FirstName, &doc.LastName, &doc.Email)
+			if err != nil {
+				rows.Close()
+				return utils.ErrorHandler(err, "Error loading search index")
+			}
+			search.Default.Add(doc)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error loading search index")
+		}
+	}
+	return nil
+}
+
+func indexStudent(s mod.Student) {
+	search.Default.Add(search.Document{Type: search.TypeStudent, ID: s.ID, FirstName: s.FirstName, LastName: s.LastName, Email: s.Email})
+}
+
+func indexTeacher(t mod.Teacher) {
+	search.Default.Add(search.Document{Type: search.TypeTeacher, ID: t.ID, FirstName: t.FirstName, LastName: t.LastName, Email: t.Email})
+}
+
+func indexExec(e mod.Exec) {
+	search.Default.Add(search.Document{Type: search.TypeExec, ID: e.ID, FirstName: e.FirstName, LastName: e.LastName, Email: e.Email})
+}
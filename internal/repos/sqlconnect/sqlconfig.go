@@ -1,11 +1,13 @@
 package sqlconnect
 
 import (
+	"WebProject/pkg/utils"
 	"database/sql"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/joho/godotenv"
 	"os"
+	"time"
 )
 
 func ConnectDB() (*sql.DB, error) {
@@ -28,3 +30,59 @@ func ConnectDB() (*sql.DB, error) {
 
 	return db, nil
 }
+
+// countRows — общее количество строк запроса без пагинации
+func countRows(db *sql.DB, query string, args []interface{}) (int, error) {
+	var total int
+	err := db.QueryRow("SELECT COUNT(*) FROM ("+query+") AS t", args...).Scan(&total)
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// execer — общий интерфейс *sql.DB и *sql.Tx для запросов без результата
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// execVersionedUpdate — выполняет UPDATE ... WHERE id = ? AND version = ?
+// Если ни одна строка не изменилась, запись успели изменить параллельно
+func execVersionedUpdate(ex execer, query string, args ...interface{}) error {
+	res, err := ex.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return utils.ErrPreconditionFailed
+	}
+	return nil
+}
+
+// nowTimestamp — текущее время в формате DATETIME для полей, которые заполняет база
+func nowTimestamp() *string {
+	now := time.Now().Format(time.DateTime)
+	return &now
+}
+
+// selectForUpdate — читает запись по id с блокировкой строки до конца транзакции
+func selectForUpdate[T any](tx *sql.Tx, id int) (T, error) {
+	var rec T
+	err := tx.QueryRow(utils.GenerateSQL(rec, "select")+" FOR UPDATE", id).Scan(utils.GetStructFields(&rec, true, true)...)
+	return rec, err
+}
+
+// queryer — общий интерфейс *sql.DB и *sql.Tx для запросов одной строки
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rowsQueryer — queryer, который умеет и многострочные запросы
+type rowsQueryer interface {
+	queryer
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
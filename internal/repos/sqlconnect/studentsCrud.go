@@ -2,31 +2,45 @@ package sqlconnect
 
 import (
 	mod "WebProject/internal/models"
+	"WebProject/internal/search"
 	"WebProject/pkg/utils"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"reflect"
 	"strconv"
+	"strings"
 )
 
-func GetAllStudents(r *http.Request) ([]mod.Student, error) {
-	query := "SELECT * FROM Students WHERE 1=1"
+func GetAllStudents(r *http.Request) ([]mod.Student, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Student{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM students WHERE deletedAt IS NULL"
 	var args []interface{}
 
-	query, args = utils.AddFilters(r, query, args)
-	query = utils.AddSorting(r, query)
+	query, args, err = utils.AddFilters(r, mod.Student{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Student{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
 
 	db, err := ConnectDB()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error querying DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
 	}
 	defer rows.Close()
 
@@ -34,20 +48,29 @@ func GetAllStudents(r *http.Request) ([]mod.Student, error) {
 
 	for rows.Next() {
 		var Student mod.Student
-		err := rows.Scan(utils.GetStructFields(&Student, true, true)...)
+		err := rows.Scan(utils.GetScanFields(&Student, columns)...)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error scanning DB")
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
 		}
 		StudentList = append(StudentList, Student)
 	}
-	return StudentList, nil
+
+	StudentList, info := utils.Paginate(r, page, StudentList)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return StudentList, info, nil
 }
 
 // FindStudentById — найти студента по ID
-func FindStudentById(err error, id int, Student mod.Student) error {
+func FindStudentById(err error, id int, Student mod.Student) (mod.Student, error) {
 	db, err := ConnectDB()
 	if err != nil {
-		return utils.ErrorHandler(err, "Error connecting to DB")
+		return mod.Student{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
@@ -57,12 +80,12 @@ func FindStudentById(err error, id int, Student mod.Student) error {
 	).Scan(utils.GetStructFields(&Student, true, true)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return utils.ErrorHandler(err, "Student not found")
+			return mod.Student{}, utils.ErrorHandler(err, "Student not found")
 		}
-		return utils.ErrorHandler(err, "Error querying DB")
+		return mod.Student{}, utils.ErrorHandler(err, "Error querying DB")
 	}
 
-	return nil
+	return Student, nil
 }
 
 // SaveStudents — вставка новых студентов из JSON
@@ -79,30 +102,127 @@ func SaveStudents(r *http.Request) ([]mod.Student, error) {
 		return nil, utils.ErrorHandler(err, "Error decoding JSON")
 	}
 
-	stmt, err := db.Prepare(utils.GenerateSQL(mod.Student{}, "insert"))
+	err = utils.ValidateSlice(newStudents)
 	if err != nil {
+		return nil, err
+	}
+	for i := range newStudents {
+		err = resolveStudentClass(db, &newStudents[i], nil)
+		if err != nil {
+			return nil, withIndex(err, i)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Student{}, "insert"))
+	if err != nil {
+		tx.Rollback()
 		return nil, utils.ErrorHandler(err, "Error preparing statement")
 	}
 	defer stmt.Close()
 
 	addedStudents := make([]mod.Student, len(newStudents))
 	for i, Student := range newStudents {
-		res, err := stmt.Exec(utils.GetStructFields(Student, true, false)...)
-		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error inserting Student")
+		err = checkStudentCapacity(tx, Student, nil)
+		if err == nil {
+			Student, err = insertStudent(stmt, Student)
+		}
+		if err == nil {
+			err = syncEnrollment(tx, Student, nil)
+		}
+		if err == nil {
+			err = recordHistory(r.Context(), tx, historyStudent, Student.ID, historyCreate, nil, Student)
 		}
-		lastId, err := res.LastInsertId()
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+			tx.Rollback()
+			return nil, err
 		}
-		Student.ID = int(lastId)
 		addedStudents[i] = Student
 	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	for _, Student := range addedStudents {
+		indexStudent(Student)
+	}
 	return addedStudents, nil
 }
 
+// SaveStudentsPartial — вставка в режиме partial: каждая запись валидируется и сохраняется отдельно
+func SaveStudentsPartial(r *http.Request) ([]utils.BulkItemResult, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newStudents []mod.Student
+	err = json.NewDecoder(r.Body).Decode(&newStudents)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+
+	stmt, err := db.Prepare(utils.GenerateSQL(mod.Student{}, "insert"))
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	results := make([]utils.BulkItemResult, 0, len(newStudents))
+	for i, Student := range newStudents {
+		err = utils.Validate(Student)
+		if err == nil {
+			err = resolveStudentClass(db, &Student, nil)
+		}
+		if err == nil {
+			err = withTx(db, func(tx *sql.Tx) error {
+				err = checkStudentCapacity(tx, Student, nil)
+				if err != nil {
+					return err
+				}
+				Student, err = insertStudent(tx.Stmt(stmt), Student)
+				if err != nil {
+					return err
+				}
+				err = syncEnrollment(tx, Student, nil)
+				if err != nil {
+					return err
+				}
+				return recordHistory(r.Context(), tx, historyStudent, Student.ID, historyCreate, nil, Student)
+			})
+		}
+		if err != nil {
+			results = append(results, utils.BulkFailure(i, 0, err))
+			continue
+		}
+		indexStudent(Student)
+		results = append(results, utils.BulkSuccess(i, Student.ID, http.StatusCreated))
+	}
+	return results, nil
+}
+
+// insertStudent — вставка одной записи подготовленным запросом
+func insertStudent(stmt *sql.Stmt, Student mod.Student) (mod.Student, error) {
+	res, err := stmt.Exec(utils.GetStructFields(Student, true, false)...)
+	if err != nil {
+		return mod.Student{}, utils.ErrorHandler(err, "Error inserting Student")
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return mod.Student{}, utils.ErrorHandler(err, "Error getting last insert ID")
+	}
+	Student.ID = int(lastId)
+	Student.Version = 1
+	return Student, nil
+}
+
 // UpdateStudentById — полное обновление студента по ID
-func UpdateStudentById(err error, id int, updatedStudent mod.Student) (mod.Student, error) {
+func UpdateStudentById(ctx context.Context, err error, id int, updatedStudent mod.Student, expectedVersion int) (mod.Student, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		return mod.Student{}, utils.ErrorHandler(err, "Error connecting to DB")
@@ -118,19 +238,50 @@ func UpdateStudentById(err error, id int, updatedStudent mod.Student) (mod.Stude
 		return mod.Student{}, utils.ErrorHandler(err, "Error querying Student")
 	}
 
+	err = utils.CheckVersion(expectedVersion, existingStudent.Version)
+	if err != nil {
+		return mod.Student{}, err
+	}
+
 	updatedStudent.ID = existingStudent.ID
+	err = utils.Validate(updatedStudent)
+	if err != nil {
+		return mod.Student{}, err
+	}
+	err = resolveStudentClass(db, &updatedStudent, &existingStudent)
+	if err != nil {
+		return mod.Student{}, err
+	}
+
 	fields := utils.GetStructFields(updatedStudent, false, false)
-	fields = append(fields, updatedStudent.ID) // для WHERE id = ?
+	fields = append(fields, updatedStudent.ID, existingStudent.Version) // для WHERE id = ? AND version = ?
 
-	_, err = db.Exec(utils.GenerateSQL(mod.Student{}, "update"), fields...)
+	updatedStudent.Version = existingStudent.Version + 1
+	updatedStudent.UpdatedAt = nowTimestamp()
+	err = withTx(db, func(tx *sql.Tx) error {
+		err := checkStudentCapacity(tx, updatedStudent, &existingStudent)
+		if err != nil {
+			return err
+		}
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Student{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating Student")
+		}
+		err = syncEnrollment(tx, updatedStudent, &existingStudent)
+		if err != nil {
+			return err
+		}
+		return recordHistory(ctx, tx, historyStudent, id, historyUpdate, existingStudent, updatedStudent)
+	})
 	if err != nil {
-		return mod.Student{}, utils.ErrorHandler(err, "Error updating Student")
+		return mod.Student{}, err
 	}
+	indexStudent(updatedStudent)
 	return updatedStudent, nil
 }
 
-// PatchStudentById — частичное обновление по ID
-func PatchStudentById(err error, id int, updates map[string]interface{}) (mod.Student, error) {
+// PatchStudentById — частичное обновление по ID (JSON Merge Patch или JSON Patch)
+func PatchStudentById(ctx context.Context, err error, id int, patch utils.Patch, expectedVersion int) (mod.Student, error) {
 	var existingStudent mod.Student
 
 	db, err := ConnectDB()
@@ -147,37 +298,50 @@ func PatchStudentById(err error, id int, updates map[string]interface{}) (mod.St
 		return mod.Student{}, utils.ErrorHandler(err, "Error fetching Student")
 	}
 
-	StudentVal := reflect.ValueOf(&existingStudent).Elem()
-	StudentType := StudentVal.Type()
+	err = utils.CheckVersion(expectedVersion, existingStudent.Version)
+	if err != nil {
+		return mod.Student{}, err
+	}
 
-	for k, v := range updates {
-		for i := 0; i < StudentVal.NumField(); i++ {
-			field := StudentType.Field(i)
-			if field.Tag.Get("json") == k {
-				if StudentVal.Field(i).CanSet() {
-					val := reflect.ValueOf(v)
-					if val.Type().ConvertibleTo(StudentVal.Field(i).Type()) {
-						StudentVal.Field(i).Set(val.Convert(StudentVal.Field(i).Type()))
-					} else {
-						return mod.Student{}, utils.ErrorHandler(errors.New("type mismatch"), "Invalid JSON value for field "+k)
-					}
-				}
-			}
-		}
+	patchedStudent, err := patchRecord(existingStudent, patch)
+	if err != nil {
+		return mod.Student{}, err
+	}
+	err = resolveStudentClass(db, &patchedStudent, &existingStudent)
+	if err != nil {
+		return mod.Student{}, err
 	}
 
-	fields := utils.GetStructFields(existingStudent, false, false)
-	fields = append(fields, existingStudent.ID)
+	fields := utils.GetStructFields(patchedStudent, false, false)
+	fields = append(fields, existingStudent.ID, existingStudent.Version)
 
-	_, err = db.Exec(utils.GenerateSQL(mod.Student{}, "update"), fields...)
+	patchedStudent.Version++
+	patchedStudent.UpdatedAt = nowTimestamp()
+	err = withTx(db, func(tx *sql.Tx) error {
+		err := checkStudentCapacity(tx, patchedStudent, &existingStudent)
+		if err != nil {
+			return err
+		}
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Student{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating Student")
+		}
+		err = syncEnrollment(tx, patchedStudent, &existingStudent)
+		if err != nil {
+			return err
+		}
+		return recordHistory(ctx, tx, historyStudent, id, historyUpdate, existingStudent, patchedStudent)
+	})
 	if err != nil {
-		return mod.Student{}, utils.ErrorHandler(err, "Error updating Student")
+		return mod.Student{}, err
 	}
-	return existingStudent, nil
+	indexStudent(patchedStudent)
+	return patchedStudent, nil
 }
 
 // PatchAllStudents — частичное обновление множества студентов (транзакция)
-func PatchAllStudents(err error, updates []map[string]interface{}) error {
+// Merge patch: [{"id": 1, "version": 3, ...поля}], JSON Patch: операции с путями /{id}/{field}
+func PatchAllStudents(ctx context.Context, err error, patch utils.Patch) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error connecting to DB")
@@ -189,95 +353,161 @@ func PatchAllStudents(err error, updates []map[string]interface{}) error {
 		return utils.ErrorHandler(err, "Error starting transaction")
 	}
 
-	for _, update := range updates {
-		var id int
-		switch v := update["id"].(type) {
-		case string:
-			id, err = strconv.Atoi(v)
+	existing := make(map[int]mod.Student)
+	load := func(id int) (mod.Student, error) {
+		if rec, ok := existing[id]; ok {
+			return rec, nil
+		}
+		var rec mod.Student
+		err := tx.QueryRow(utils.GenerateSQL(mod.Student{}, "select")+" FOR UPDATE", id).Scan(utils.GetStructFields(&rec, true, true)...)
+		if err != nil {
+			return mod.Student{}, utils.ErrorHandler(err, "Student with ID "+strconv.Itoa(id)+" not found or error fetching")
+		}
+		existing[id] = rec
+		return rec, nil
+	}
+
+	// сначала применяем и валидируем все патчи, и только потом пишем в базу
+	var ids []int
+	patched := make(map[int]mod.Student)
+	if patch.IsJSONPatch() {
+		ids, err = bulkJSONPatchIDs(patch.Body)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, id := range ids {
+			_, err = load(id)
 			if err != nil {
 				tx.Rollback()
-				return utils.ErrorHandler(err, "Invalid ID format")
+				return err
 			}
-		case float64:
-			id = int(v)
-		default:
+		}
+		patched, err = applyBulkJSONPatch(existing, patch)
+		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(errors.New("missing or invalid id"), "Invalid ID")
+			return err
 		}
-
-		var existingStudent mod.Student
-		err = tx.QueryRow(utils.GenerateSQL(mod.Student{}, "select"), id).Scan(utils.GetStructFields(&existingStudent, true, true)...)
+	} else {
+		items, err := parseBulkMergePatch(patch.Body)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "Student not found or error fetching")
+			return err
 		}
 
-		StudentVal := reflect.ValueOf(&existingStudent).Elem()
-		StudentType := StudentVal.Type()
+		var invalid []utils.FieldError
+		for index, item := range items {
+			current, ok := patched[item.ID]
+			if !ok {
+				current, err = load(item.ID)
+				if err != nil {
+					tx.Rollback()
+					return err
+				}
+				ids = append(ids, item.ID)
+			}
 
-		for k, v := range update {
-			if k == "id" {
-				continue
+			err = utils.CheckVersion(item.Version, existing[item.ID].Version)
+			if err != nil {
+				tx.Rollback()
+				return utils.ErrorHandler(utils.ErrPreconditionFailed, "Version mismatch for Student with ID "+strconv.Itoa(item.ID))
 			}
-			for i := 0; i < StudentVal.NumField(); i++ {
-				field := StudentType.Field(i)
-				if field.Tag.Get("json") == k {
-					fieldVal := StudentVal.Field(i)
-					if fieldVal.CanSet() {
-						val := reflect.ValueOf(v)
-						if val.Type().ConvertibleTo(fieldVal.Type()) {
-							fieldVal.Set(val.Convert(fieldVal.Type()))
-						} else {
-							tx.Rollback()
-							return utils.ErrorHandler(errors.New("type mismatch"), "Invalid JSON value for field "+k)
-						}
+
+			result, err := patchRecord(current, item.Patch)
+			if err != nil {
+				var verr *utils.ValidationError
+				if errors.As(err, &verr) {
+					for _, fe := range verr.Errors {
+						fe.Index = &index
+						invalid = append(invalid, fe)
 					}
-					break
+					continue
 				}
+				tx.Rollback()
+				return err
 			}
+			patched[item.ID] = result
 		}
 
-		fields := utils.GetStructFields(existingStudent, true, false)
-		fields = append(fields, id)
+		if len(invalid) > 0 {
+			tx.Rollback()
+			return &utils.ValidationError{Errors: invalid}
+		}
+	}
 
-		_, err = tx.Exec(utils.GenerateSQL(mod.Student{}, "update"), fields...)
+	for _, id := range ids {
+		rec, prev := patched[id], existing[id]
+		err = resolveStudentClass(tx, &rec, &prev)
+		if err == nil {
+			err = checkStudentCapacity(tx, rec, &prev)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		fields := utils.GetStructFields(rec, false, false)
+		fields = append(fields, id, existing[id].Version)
+
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Student{}, "update"), fields...)
 		if err != nil {
 			tx.Rollback()
 			return utils.ErrorHandler(err, "Error updating Student with ID "+strconv.Itoa(id))
 		}
+		rec.Version++
+		rec.UpdatedAt = nowTimestamp()
+		patched[id] = rec
+
+		err = syncEnrollment(tx, rec, &prev)
+		if err == nil {
+			err = recordHistory(ctx, tx, historyStudent, id, historyUpdate, existing[id], rec)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error committing transaction")
 	}
+	for _, id := range ids {
+		indexStudent(patched[id])
+	}
 	return nil
 }
 
 // DeleteStudentById — удаление по ID
-func DeleteStudentById(err error, id int) error {
+func DeleteStudentById(ctx context.Context, err error, id int) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
-	res, err := db.Exec(utils.GenerateSQL(mod.Student{}, "delete"), id)
-	if err != nil {
-		return utils.ErrorHandler(err, "Error deleting Student")
-	}
-	rows, err := res.RowsAffected()
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Student](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Student not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching Student")
+		}
+		_, err = tx.Exec(utils.GenerateSQL(mod.Student{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting Student")
+		}
+		return recordHistory(ctx, tx, historyStudent, id, historyDelete, existing, nil)
+	})
 	if err != nil {
-		return utils.ErrorHandler(err, "Error checking deletion result")
-	}
-	if rows == 0 {
-		return utils.ErrorHandler(errors.New("no rows affected"), "Student not found")
+		return err
 	}
+	search.Default.Remove(search.TypeStudent, id)
 	return nil
 }
 
 // DeleteStudents — удаление множества учителей по списку ID
-func DeleteStudents(err error, ids []int) ([]int, error) {
+func DeleteStudents(ctx context.Context, err error, ids []int) ([]int, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		return nil, utils.ErrorHandler(err, "Error connecting to DB")
@@ -298,26 +528,49 @@ func DeleteStudents(err error, ids []int) ([]int, error) {
 
 	var deletedIds []int
 	for _, id := range ids {
-		res, err := stmt.Exec(id)
+		existing, err := selectForUpdate[mod.Student](tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "Error executing delete")
+			return nil, utils.ErrorHandler(err, "Error fetching Student with ID "+strconv.Itoa(id))
 		}
-		rowsAf, err := res.RowsAffected()
+		_, err = stmt.Exec(id)
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "Error checking rows affected")
+			return nil, utils.ErrorHandler(err, "Error executing delete")
 		}
-		if rowsAf > 0 {
-			deletedIds = append(deletedIds, id)
+		err = recordHistory(ctx, tx, historyStudent, id, historyDelete, existing, nil)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
 		}
+		deletedIds = append(deletedIds, id)
 	}
 	err = tx.Commit()
 	if err != nil {
 		return nil, utils.ErrorHandler(err, "Error committing transaction")
 	}
+	for _, id := range deletedIds {
+		search.Default.Remove(search.TypeStudent, id)
+	}
 	if len(deletedIds) < 1 {
 		return nil, utils.ErrorHandler(errors.New("no deletions"), "No Students were deleted")
 	}
 	return deletedIds, nil
 }
+
+// PatchAllStudentsPartial — bulk PATCH в режиме partial
+func PatchAllStudentsPartial(ctx context.Context, patch utils.Patch) ([]utils.BulkItemResult, error) {
+	return patchPartial(patch, func(id int, p utils.Patch, expectedVersion int) (mod.Student, error) {
+		return PatchStudentById(ctx, nil, id, p, expectedVersion)
+	})
+}
+
+// DeleteStudentsPartial — bulk DELETE в режиме partial
+func DeleteStudentsPartial(ctx context.Context, ids []int) []utils.BulkItemResult {
+	return deletePartial(ids, func(id int) error {
+		return DeleteStudentById(ctx, nil, id)
+	})
+}
@@ -2,32 +2,46 @@ package sqlconnect
 
 import (
 	mod "WebProject/internal/models"
+	"WebProject/internal/search"
 	"WebProject/pkg/utils"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
-	"reflect"
 	"strconv"
+	"strings"
 )
 
 // GetAllTeachers — получаем список учителей с фильтрами и сортировкой
-func GetAllTeachers(r *http.Request) ([]mod.Teacher, error) {
-	query := "SELECT * FROM teachers WHERE 1=1"
+func GetAllTeachers(r *http.Request) ([]mod.Teacher, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Teacher{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM teachers WHERE deletedAt IS NULL"
 	var args []interface{}
 
-	query, args = utils.AddFilters(r, query, args)
-	query = utils.AddSorting(r, query)
+	query, args, err = utils.AddFilters(r, mod.Teacher{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Teacher{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
 
 	db, err := ConnectDB()
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
 	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error querying DB")
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
 	}
 	defer rows.Close()
 
@@ -35,35 +49,44 @@ func GetAllTeachers(r *http.Request) ([]mod.Teacher, error) {
 
 	for rows.Next() {
 		var teacher mod.Teacher
-		err := rows.Scan(utils.GetStructFields(&teacher, true, true)...)
+		err := rows.Scan(utils.GetScanFields(&teacher, columns)...)
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error scanning DB")
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
 		}
 		teacherList = append(teacherList, teacher)
 	}
-	return teacherList, nil
+
+	teacherList, info := utils.Paginate(r, page, teacherList)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return teacherList, info, nil
 }
 
 // FindTeacherById — найти учителя по ID
-func FindTeacherById(err error, id int, teacher mod.Teacher) error {
+func FindTeacherById(err error, id int, teacher mod.Teacher) (mod.Teacher, error) {
 	db, err := ConnectDB()
 	if err != nil {
-		return utils.ErrorHandler(err, "Error connecting to DB")
+		return mod.Teacher{}, utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
 	err = db.QueryRow(
-		utils.GenerateSQL(mod.Student{}, "select"),
+		utils.GenerateSQL(mod.Teacher{}, "select"),
 		id,
-	).Scan(utils.GetStructFields(teacher, true, true)...)
+	).Scan(utils.GetStructFields(&teacher, true, true)...)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return utils.ErrorHandler(err, "Student not found")
+			return mod.Teacher{}, utils.ErrorHandler(err, "Teacher not found")
 		}
-		return utils.ErrorHandler(err, "Error querying DB")
+		return mod.Teacher{}, utils.ErrorHandler(err, "Error querying DB")
 	}
 
-	return nil
+	return teacher, nil
 }
 
 // SaveTeachers — вставка новых учителей из JSON
@@ -80,30 +103,113 @@ func SaveTeachers(r *http.Request) ([]mod.Teacher, error) {
 		return nil, utils.ErrorHandler(err, "Error decoding JSON")
 	}
 
-	stmt, err := db.Prepare(utils.GenerateSQL(mod.Teacher{}, "insert"))
+	err = utils.ValidateSlice(newTeachers)
+	if err != nil {
+		return nil, err
+	}
+	for i := range newTeachers {
+		err = resolveTeacherClass(db, &newTeachers[i])
+		if err != nil {
+			return nil, withIndex(err, i)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Teacher{}, "insert"))
 	if err != nil {
+		tx.Rollback()
 		return nil, utils.ErrorHandler(err, "Error preparing statement")
 	}
 	defer stmt.Close()
 
 	addedTeachers := make([]mod.Teacher, len(newTeachers))
 	for i, teacher := range newTeachers {
-		res, err := stmt.Exec(utils.GetStructFields(teacher, false, false)...)
-		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error inserting teacher")
+		teacher, err = insertTeacher(stmt, teacher)
+		if err == nil {
+			err = recordHistory(r.Context(), tx, historyTeacher, teacher.ID, historyCreate, nil, teacher)
 		}
-		lastId, err := res.LastInsertId()
 		if err != nil {
-			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+			tx.Rollback()
+			return nil, err
 		}
-		teacher.ID = int(lastId)
 		addedTeachers[i] = teacher
 	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	for _, teacher := range addedTeachers {
+		indexTeacher(teacher)
+	}
 	return addedTeachers, nil
 }
 
+// SaveTeachersPartial — вставка в режиме partial: каждая запись валидируется и сохраняется отдельно
+func SaveTeachersPartial(r *http.Request) ([]utils.BulkItemResult, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newTeachers []mod.Teacher
+	err = json.NewDecoder(r.Body).Decode(&newTeachers)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+
+	stmt, err := db.Prepare(utils.GenerateSQL(mod.Teacher{}, "insert"))
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	results := make([]utils.BulkItemResult, 0, len(newTeachers))
+	for i, teacher := range newTeachers {
+		err = utils.Validate(teacher)
+		if err == nil {
+			err = resolveTeacherClass(db, &teacher)
+		}
+		if err == nil {
+			err = withTx(db, func(tx *sql.Tx) error {
+				teacher, err = insertTeacher(tx.Stmt(stmt), teacher)
+				if err != nil {
+					return err
+				}
+				return recordHistory(r.Context(), tx, historyTeacher, teacher.ID, historyCreate, nil, teacher)
+			})
+		}
+		if err != nil {
+			results = append(results, utils.BulkFailure(i, 0, err))
+			continue
+		}
+		indexTeacher(teacher)
+		results = append(results, utils.BulkSuccess(i, teacher.ID, http.StatusCreated))
+	}
+	return results, nil
+}
+
+// insertTeacher — вставка одной записи подготовленным запросом
+func insertTeacher(stmt *sql.Stmt, teacher mod.Teacher) (mod.Teacher, error) {
+	res, err := stmt.Exec(utils.GetStructFields(teacher, true, false)...)
+	if err != nil {
+		return mod.Teacher{}, utils.ErrorHandler(err, "Error inserting teacher")
+	}
+	lastId, err := res.LastInsertId()
+	if err != nil {
+		return mod.Teacher{}, utils.ErrorHandler(err, "Error getting last insert ID")
+	}
+	teacher.ID = int(lastId)
+	teacher.Version = 1
+	return teacher, nil
+}
+
 // UpdateTeacherById — полное обновление учителя по ID
-func UpdateTeacherById(err error, id int, updatedTeacher mod.Teacher) (mod.Teacher, error) {
+func UpdateTeacherById(ctx context.Context, err error, id int, updatedTeacher mod.Teacher, expectedVersion int) (mod.Teacher, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		return mod.Teacher{}, utils.ErrorHandler(err, "Error connecting to DB")
@@ -119,19 +225,42 @@ func UpdateTeacherById(err error, id int, updatedTeacher mod.Teacher) (mod.Teach
 		return mod.Teacher{}, utils.ErrorHandler(err, "Error querying teacher")
 	}
 
+	err = utils.CheckVersion(expectedVersion, existingTeacher.Version)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
+
 	updatedTeacher.ID = existingTeacher.ID
+	err = utils.Validate(updatedTeacher)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
+	err = resolveTeacherClass(db, &updatedTeacher)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
+
 	fields := utils.GetStructFields(updatedTeacher, false, false)
-	fields = append(fields, updatedTeacher.ID) // для WHERE id = ?
+	fields = append(fields, updatedTeacher.ID, existingTeacher.Version) // для WHERE id = ? AND version = ?
 
-	_, err = db.Exec(utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
+	updatedTeacher.Version = existingTeacher.Version + 1
+	updatedTeacher.UpdatedAt = nowTimestamp()
+	err = withTx(db, func(tx *sql.Tx) error {
+		err := execVersionedUpdate(tx, utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating teacher")
+		}
+		return recordHistory(ctx, tx, historyTeacher, id, historyUpdate, existingTeacher, updatedTeacher)
+	})
 	if err != nil {
-		return mod.Teacher{}, utils.ErrorHandler(err, "Error updating teacher")
+		return mod.Teacher{}, err
 	}
+	indexTeacher(updatedTeacher)
 	return updatedTeacher, nil
 }
 
-// PatchTeacherById — частичное обновление по ID
-func PatchTeacherById(err error, id int, updates map[string]interface{}) (mod.Teacher, error) {
+// PatchTeacherById — частичное обновление по ID (JSON Merge Patch или JSON Patch)
+func PatchTeacherById(ctx context.Context, err error, id int, patch utils.Patch, expectedVersion int) (mod.Teacher, error) {
 	var existingTeacher mod.Teacher
 
 	db, err := ConnectDB()
@@ -148,37 +277,42 @@ func PatchTeacherById(err error, id int, updates map[string]interface{}) (mod.Te
 		return mod.Teacher{}, utils.ErrorHandler(err, "Error fetching teacher")
 	}
 
-	teacherVal := reflect.ValueOf(&existingTeacher).Elem()
-	teacherType := teacherVal.Type()
+	err = utils.CheckVersion(expectedVersion, existingTeacher.Version)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
 
-	for k, v := range updates {
-		for i := 0; i < teacherVal.NumField(); i++ {
-			field := teacherType.Field(i)
-			if field.Tag.Get("json") == k {
-				if teacherVal.Field(i).CanSet() {
-					val := reflect.ValueOf(v)
-					if val.Type().ConvertibleTo(teacherVal.Field(i).Type()) {
-						teacherVal.Field(i).Set(val.Convert(teacherVal.Field(i).Type()))
-					} else {
-						return mod.Teacher{}, utils.ErrorHandler(errors.New("type mismatch"), "Invalid JSON value for field "+k)
-					}
-				}
-			}
-		}
+	patchedTeacher, err := patchRecord(existingTeacher, patch)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
+	err = resolveTeacherClass(db, &patchedTeacher)
+	if err != nil {
+		return mod.Teacher{}, err
 	}
 
-	fields := utils.GetStructFields(existingTeacher, false, false)
-	fields = append(fields, existingTeacher.ID)
+	fields := utils.GetStructFields(patchedTeacher, false, false)
+	fields = append(fields, existingTeacher.ID, existingTeacher.Version)
 
-	_, err = db.Exec(utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
+	patchedTeacher.Version++
+	patchedTeacher.UpdatedAt = nowTimestamp()
+	err = withTx(db, func(tx *sql.Tx) error {
+		err := execVersionedUpdate(tx, utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating teacher")
+		}
+		return recordHistory(ctx, tx, historyTeacher, id, historyUpdate, existingTeacher, patchedTeacher)
+	})
 	if err != nil {
-		return mod.Teacher{}, utils.ErrorHandler(err, "Error updating teacher")
+		return mod.Teacher{}, err
 	}
-	return existingTeacher, nil
+	indexTeacher(patchedTeacher)
+	return patchedTeacher, nil
 }
 
 // PatchAllTeachers — частичное обновление множества учителей (транзакция)
-func PatchAllTeachers(err error, updates []map[string]interface{}) error {
+// Merge patch: [{"id": 1, "version": 3, ...поля}], JSON Patch: операции с путями /{id}/{field}
+func PatchAllTeachers(ctx context.Context, err error, patch utils.Patch) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error connecting to DB")
@@ -190,95 +324,155 @@ func PatchAllTeachers(err error, updates []map[string]interface{}) error {
 		return utils.ErrorHandler(err, "Error starting transaction")
 	}
 
-	for _, update := range updates {
-		var id int
-		switch v := update["id"].(type) {
-		case string:
-			id, err = strconv.Atoi(v)
+	existing := make(map[int]mod.Teacher)
+	load := func(id int) (mod.Teacher, error) {
+		if rec, ok := existing[id]; ok {
+			return rec, nil
+		}
+		var rec mod.Teacher
+		err := tx.QueryRow(utils.GenerateSQL(mod.Teacher{}, "select")+" FOR UPDATE", id).Scan(utils.GetStructFields(&rec, true, true)...)
+		if err != nil {
+			return mod.Teacher{}, utils.ErrorHandler(err, "Teacher with ID "+strconv.Itoa(id)+" not found or error fetching")
+		}
+		existing[id] = rec
+		return rec, nil
+	}
+
+	// сначала применяем и валидируем все патчи, и только потом пишем в базу
+	var ids []int
+	patched := make(map[int]mod.Teacher)
+	if patch.IsJSONPatch() {
+		ids, err = bulkJSONPatchIDs(patch.Body)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		for _, id := range ids {
+			_, err = load(id)
 			if err != nil {
 				tx.Rollback()
-				return utils.ErrorHandler(err, "Invalid ID format")
+				return err
 			}
-		case float64:
-			id = int(v)
-		default:
+		}
+		patched, err = applyBulkJSONPatch(existing, patch)
+		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(errors.New("missing or invalid id"), "Invalid ID")
+			return err
 		}
-
-		var existingTeacher mod.Teacher
-		err = tx.QueryRow(utils.GenerateSQL(mod.Teacher{}, "select"), id).Scan(utils.GetStructFields(&existingTeacher, true, true)...)
+	} else {
+		items, err := parseBulkMergePatch(patch.Body)
 		if err != nil {
 			tx.Rollback()
-			return utils.ErrorHandler(err, "Teacher not found or error fetching")
+			return err
 		}
 
-		teacherVal := reflect.ValueOf(&existingTeacher).Elem()
-		teacherType := teacherVal.Type()
+		var invalid []utils.FieldError
+		for index, item := range items {
+			current, ok := patched[item.ID]
+			if !ok {
+				current, err = load(item.ID)
+				if err != nil {
+					tx.Rollback()
+					return err
+				}
+				ids = append(ids, item.ID)
+			}
 
-		for k, v := range update {
-			if k == "id" {
-				continue
+			err = utils.CheckVersion(item.Version, existing[item.ID].Version)
+			if err != nil {
+				tx.Rollback()
+				return utils.ErrorHandler(utils.ErrPreconditionFailed, "Version mismatch for teacher with ID "+strconv.Itoa(item.ID))
 			}
-			for i := 0; i < teacherVal.NumField(); i++ {
-				field := teacherType.Field(i)
-				if field.Tag.Get("json") == k {
-					fieldVal := teacherVal.Field(i)
-					if fieldVal.CanSet() {
-						val := reflect.ValueOf(v)
-						if val.Type().ConvertibleTo(fieldVal.Type()) {
-							fieldVal.Set(val.Convert(fieldVal.Type()))
-						} else {
-							tx.Rollback()
-							return utils.ErrorHandler(errors.New("type mismatch"), "Invalid JSON value for field "+k)
-						}
+
+			result, err := patchRecord(current, item.Patch)
+			if err != nil {
+				var verr *utils.ValidationError
+				if errors.As(err, &verr) {
+					for _, fe := range verr.Errors {
+						fe.Index = &index
+						invalid = append(invalid, fe)
 					}
-					break
+					continue
 				}
+				tx.Rollback()
+				return err
 			}
+			patched[item.ID] = result
+		}
+
+		if len(invalid) > 0 {
+			tx.Rollback()
+			return &utils.ValidationError{Errors: invalid}
 		}
+	}
 
-		fields := utils.GetStructFields(existingTeacher, false, false)
-		fields = append(fields, id)
+	for _, id := range ids {
+		rec := patched[id]
+		err = resolveTeacherClass(tx, &rec)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		fields := utils.GetStructFields(rec, false, false)
+		fields = append(fields, id, existing[id].Version)
 
-		_, err = tx.Exec(utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Teacher{}, "update"), fields...)
 		if err != nil {
 			tx.Rollback()
 			return utils.ErrorHandler(err, "Error updating teacher with ID "+strconv.Itoa(id))
 		}
+		rec.Version++
+		rec.UpdatedAt = nowTimestamp()
+		patched[id] = rec
+
+		err = recordHistory(ctx, tx, historyTeacher, id, historyUpdate, existing[id], rec)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	err = tx.Commit()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error committing transaction")
 	}
+	for _, id := range ids {
+		indexTeacher(patched[id])
+	}
 	return nil
 }
 
 // DeleteTeacherById — удаление по ID
-func DeleteTeacherById(err error, id int) error {
+func DeleteTeacherById(ctx context.Context, err error, id int) error {
 	db, err := ConnectDB()
 	if err != nil {
 		return utils.ErrorHandler(err, "Error connecting to DB")
 	}
 	defer db.Close()
 
-	res, err := db.Exec(utils.GenerateSQL(mod.Teacher{}, "delete"), id)
-	if err != nil {
-		return utils.ErrorHandler(err, "Error deleting teacher")
-	}
-	rows, err := res.RowsAffected()
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Teacher](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Teacher not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching teacher")
+		}
+		_, err = tx.Exec(utils.GenerateSQL(mod.Teacher{}, "delete"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error deleting Teacher")
+		}
+		return recordHistory(ctx, tx, historyTeacher, id, historyDelete, existing, nil)
+	})
 	if err != nil {
-		return utils.ErrorHandler(err, "Error checking deletion result")
-	}
-	if rows == 0 {
-		return utils.ErrorHandler(errors.New("no rows affected"), "Teacher not found")
+		return err
 	}
+	search.Default.Remove(search.TypeTeacher, id)
 	return nil
 }
 
 // DeleteTeachers — удаление множества учителей по списку ID
-func DeleteTeachers(err error, ids []int) ([]int, error) {
+func DeleteTeachers(ctx context.Context, err error, ids []int) ([]int, error) {
 	db, err := ConnectDB()
 	if err != nil {
 		return nil, utils.ErrorHandler(err, "Error connecting to DB")
@@ -299,70 +493,49 @@ func DeleteTeachers(err error, ids []int) ([]int, error) {
 
 	var deletedIds []int
 	for _, id := range ids {
-		res, err := stmt.Exec(id)
+		existing, err := selectForUpdate[mod.Teacher](tx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "Error executing delete")
+			return nil, utils.ErrorHandler(err, "Error fetching Teacher with ID "+strconv.Itoa(id))
 		}
-		rowsAf, err := res.RowsAffected()
+		_, err = stmt.Exec(id)
 		if err != nil {
 			tx.Rollback()
-			return nil, utils.ErrorHandler(err, "Error checking rows affected")
+			return nil, utils.ErrorHandler(err, "Error executing delete")
 		}
-		if rowsAf > 0 {
-			deletedIds = append(deletedIds, id)
+		err = recordHistory(ctx, tx, historyTeacher, id, historyDelete, existing, nil)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
 		}
+		deletedIds = append(deletedIds, id)
 	}
 	err = tx.Commit()
 	if err != nil {
 		return nil, utils.ErrorHandler(err, "Error committing transaction")
 	}
+	for _, id := range deletedIds {
+		search.Default.Remove(search.TypeTeacher, id)
+	}
 	if len(deletedIds) < 1 {
 		return nil, utils.ErrorHandler(errors.New("no deletions"), "No teachers were deleted")
 	}
 	return deletedIds, nil
 }
 
-// FindStudentsByTeacherId - нахождение студентов по классу у определенного учителя
-func FindStudentsByTeacherId(w http.ResponseWriter, err error, id int) ([]mod.Student, error) {
-	db, err := ConnectDB()
-	if err != nil {
-		return nil, utils.ErrorHandler(err, "Error connecting to DB")
-	}
-	defer db.Close()
-
-	var class string
-	err = db.QueryRow("Select class from teachers where id = ?", id).Scan(&class)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, utils.ErrorHandler(errors.New("no teachers found"), "Teacher not found")
-		}
-		http.Error(w, "DB query error", http.StatusInternalServerError)
-		return nil, utils.ErrorHandler(err, "Error querying DB")
-	}
-
-	rows, err := db.Query("SELECT * from students where class = ?", class)
-	if err != nil {
-		http.Error(w, "DB query error", http.StatusInternalServerError)
-		return nil, utils.ErrorHandler(err, "Error querying DB")
-	}
-	defer rows.Close()
-
-	students := make([]mod.Student, 0)
-	for rows.Next() {
-		var s mod.Student
-		err = rows.Scan(&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class)
-		if err != nil {
-			http.Error(w, "DB scan error", http.StatusInternalServerError)
-			return nil, utils.ErrorHandler(err, "Error querying DB")
-		}
-		students = append(students, s)
-	}
+// PatchAllTeachersPartial — bulk PATCH в режиме partial
+func PatchAllTeachersPartial(ctx context.Context, patch utils.Patch) ([]utils.BulkItemResult, error) {
+	return patchPartial(patch, func(id int, p utils.Patch, expectedVersion int) (mod.Teacher, error) {
+		return PatchTeacherById(ctx, nil, id, p, expectedVersion)
+	})
+}
 
-	err = rows.Err()
-	if err != nil {
-		http.Error(w, "DB rows error", http.StatusInternalServerError)
-		return nil, utils.ErrorHandler(err, "Error querying DB")
-	}
-	return students, nil
+// DeleteTeachersPartial — bulk DELETE в режиме partial
+func DeleteTeachersPartial(ctx context.Context, ids []int) []utils.BulkItemResult {
+	return deletePartial(ids, func(id int) error {
+		return DeleteTeacherById(ctx, nil, id)
+	})
 }
@@ -0,0 +1,799 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// openEnd — validTo пустой: урок действует бессрочно
+const openEnd = "9999-12-31"
+
+// isoWeekday — день недели по ISO 8601: понедельник 1, воскресенье 7
+func isoWeekday(t time.Time) int {
+	if t.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(t.Weekday())
+}
+
+// GetAllLessons — уроки расписания с фильтрами (?classId=, ?teacherId=, ?weekday=)
+func GetAllLessons(r *http.Request) ([]mod.Lesson, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Lesson{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM lessons WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Lesson{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Lesson{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	lessons := make([]mod.Lesson, 0)
+	for rows.Next() {
+		var l mod.Lesson
+		err := rows.Scan(utils.GetScanFields(&l, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		lessons = append(lessons, l)
+	}
+
+	lessons, info := utils.Paginate(r, page, lessons)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return lessons, info, nil
+}
+
+// FindLessonById — урок по ID
+func FindLessonById(id int) (mod.Lesson, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Lesson{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var l mod.Lesson
+	err = db.QueryRow(utils.GenerateSQL(mod.Lesson{}, "select"), id).Scan(utils.GetStructFields(&l, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Lesson{}, utils.ErrorHandler(err, "Lesson not found")
+		}
+		return mod.Lesson{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return l, nil
+}
+
+// SaveLessons — создание уроков из JSON (транзакция); пересечение с занятым учителем, кабинетом или классом — конфликт
+func SaveLessons(r *http.Request) ([]mod.Lesson, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newLessons []mod.Lesson
+	err = json.NewDecoder(r.Body).Decode(&newLessons)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newLessons {
+		normalizeLesson(&newLessons[i])
+	}
+	err = utils.ValidateSlice(newLessons)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Lesson{}, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i, l := range newLessons {
+		err = checkLessonRefs(tx, l)
+		if err == nil {
+			err = checkLessonConflicts(tx, l)
+		}
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
+		res, err := stmt.Exec(utils.GetStructFields(l, true, false)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error inserting lesson")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		newLessons[i].ID = int(lastId)
+		newLessons[i].Version = 1
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return newLessons, nil
+}
+
+// PatchLessonById — частичное обновление урока; перенос урока с будущими заменами запрещён
+func PatchLessonById(id int, patch utils.Patch, expectedVersion int) (mod.Lesson, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Lesson{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var patched mod.Lesson
+	err = withTx(db, func(tx *sql.Tx) error {
+		existing, err := selectForUpdate[mod.Lesson](tx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return utils.ErrorHandler(err, "Lesson not found")
+			}
+			return utils.ErrorHandler(err, "Error fetching lesson")
+		}
+		err = utils.CheckVersion(expectedVersion, existing.Version)
+		if err != nil {
+			return err
+		}
+
+		patched, err = patchRecord(existing, patch)
+		if err != nil {
+			return err
+		}
+		normalizeLesson(&patched)
+		err = checkLessonRefs(tx, patched)
+		if err != nil {
+			return err
+		}
+		err = checkLessonConflicts(tx, patched)
+		if err != nil {
+			return err
+		}
+
+		// замены привязаны к дню и уроку: после переноса они бы указывали на пустой слот
+		var stale int
+		validTo := openEnd
+		if patched.ValidTo != nil {
+			validTo = *patched.ValidTo
+		}
+		err = tx.QueryRow(`SELECT COUNT(*) FROM substitutions WHERE lessonId = ? AND date >= CURDATE()
+			AND (? OR date < ? OR date > ?)`,
+			id, patched.Weekday != existing.Weekday || patched.Period != existing.Period || patched.TeacherID != existing.TeacherID,
+			patched.ValidFrom, validTo).Scan(&stale)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking substitutions")
+		}
+		if stale > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Lesson has %d upcoming substitutions; delete them before moving the lesson", stale))
+		}
+
+		fields := utils.GetStructFields(patched, false, false)
+		fields = append(fields, id, existing.Version)
+		err = execVersionedUpdate(tx, utils.GenerateSQL(mod.Lesson{}, "update"), fields...)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error updating lesson")
+		}
+		patched.Version++
+		patched.UpdatedAt = nowTimestamp()
+		return nil
+	})
+	if err != nil {
+		return mod.Lesson{}, err
+	}
+	return patched, nil
+}
+
+// DeleteLessonById — удаление урока вместе с его заменами
+func DeleteLessonById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Lesson{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting lesson")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error retrieving delete result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Lesson not found")
+	}
+	return nil
+}
+
+func normalizeLesson(l *mod.Lesson) {
+	l.Subject = strings.TrimSpace(l.Subject)
+	if l.Room != nil {
+		room := strings.ToUpper(strings.TrimSpace(*l.Room))
+		l.Room = &room
+		if room == "" {
+			l.Room = nil
+		}
+	}
+}
+
+// checkLessonRefs — класс, учитель и номер урока существуют, учитель ведёт предмет в этом классе, период действия корректен
+func checkLessonRefs(q queryer, l mod.Lesson) error {
+	var errs []utils.FieldError
+	var n int
+	err := q.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", l.ClassID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking class")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "classId", Message: "class not found"})
+	}
+	err = q.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", l.TeacherID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "teacherId", Message: "teacher not found"})
+	} else {
+		err = checkTeaches(q, &l.TeacherID, l.ClassID, l.Subject)
+		if errors.Is(err, utils.ErrForbidden) {
+			errs = append(errs, utils.FieldError{Field: "teacherId", Message: "teacher is not assigned to " + l.Subject + " in this class"})
+		} else if err != nil {
+			return err
+		}
+	}
+	err = q.QueryRow("SELECT COUNT(*) FROM periods WHERE number = ?", l.Period).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking period")
+	}
+	if n == 0 {
+		errs = append(errs, utils.FieldError{Field: "period", Message: "no such period in the bell schedule"})
+	}
+	if _, err := time.Parse(time.DateOnly, l.ValidFrom); err != nil {
+		errs = append(errs, utils.FieldError{Field: "validFrom", Message: "invalid date"})
+	}
+	if l.ValidTo != nil {
+		if _, err := time.Parse(time.DateOnly, *l.ValidTo); err != nil {
+			errs = append(errs, utils.FieldError{Field: "validTo", Message: "invalid date"})
+		} else if *l.ValidTo < l.ValidFrom {
+			errs = append(errs, utils.FieldError{Field: "validTo", Message: "must not be before validFrom"})
+		}
+	}
+	if len(errs) > 0 {
+		return &utils.ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// checkLessonConflicts — в тот же день и урок при пересекающихся периодах действия учитель, кабинет и класс должны быть свободны
+func checkLessonConflicts(q queryer, l mod.Lesson) error {
+	validTo := openEnd
+	if l.ValidTo != nil {
+		validTo = *l.ValidTo
+	}
+	var other mod.Lesson
+	err := q.QueryRow(`SELECT id, classId, teacherId, room FROM lessons
+		WHERE weekday = ? AND period = ? AND id <> ?
+		AND (teacherId = ? OR classId = ? OR room = ?)
+		AND validFrom <= ? AND COALESCE(validTo, ?) >= ?
+		LIMIT 1 FOR UPDATE`,
+		l.Weekday, l.Period, l.ID, l.TeacherID, l.ClassID, l.Room, validTo, openEnd, l.ValidFrom).
+		Scan(&other.ID, &other.ClassID, &other.TeacherID, &other.Room)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking timetable conflicts")
+	}
+
+	var what string
+	switch {
+	case other.TeacherID == l.TeacherID:
+		what = "teacher is already teaching"
+	case other.ClassID == l.ClassID:
+		what = "class already has a lesson"
+	default:
+		what = "room " + *l.Room + " is already booked"
+	}
+	return utils.ErrorHandler(utils.ErrScheduleConflict, fmt.Sprintf("Weekday %d, period %d: %s (lesson %d)", l.Weekday, l.Period, what, other.ID))
+}
+
+// GetAllSubstitutions — замены с фильтрами (?date=, ?lessonId=, ?substituteTeacherId=)
+func GetAllSubstitutions(r *http.Request) ([]mod.Substitution, utils.PageInfo, error) {
+	columns, err := utils.QueryColumns(r, mod.Substitution{})
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	query := "SELECT " + strings.Join(columns, ", ") + " FROM substitutions WHERE 1=1"
+	var args []interface{}
+
+	query, args, err = utils.AddFilters(r, mod.Substitution{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+	countQuery, countArgs := query, args
+
+	query, args, page, err := utils.AddPagination(r, mod.Substitution{}, query, args)
+	if err != nil {
+		return nil, utils.PageInfo{}, err
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	defer rows.Close()
+
+	substitutions := make([]mod.Substitution, 0)
+	for rows.Next() {
+		var s mod.Substitution
+		err := rows.Scan(utils.GetScanFields(&s, columns)...)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error scanning DB")
+		}
+		substitutions = append(substitutions, s)
+	}
+
+	substitutions, info := utils.Paginate(r, page, substitutions)
+	if page.WithTotal {
+		total, err := countRows(db, countQuery, countArgs)
+		if err != nil {
+			return nil, utils.PageInfo{}, utils.ErrorHandler(err, "Error counting rows")
+		}
+		info.Total = &total
+	}
+	return substitutions, info, nil
+}
+
+// FindSubstitutionById — замена по ID
+func FindSubstitutionById(id int) (mod.Substitution, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return mod.Substitution{}, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var s mod.Substitution
+	err = db.QueryRow(utils.GenerateSQL(mod.Substitution{}, "select"), id).Scan(utils.GetStructFields(&s, true, true)...)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return mod.Substitution{}, utils.ErrorHandler(err, "Substitution not found")
+		}
+		return mod.Substitution{}, utils.ErrorHandler(err, "Error querying DB")
+	}
+	return s, nil
+}
+
+// SaveSubstitutions — замены на отсутствие учителя (транзакция): дата должна приходиться на день урока,
+// заменяющий учитель должен быть свободен в это время; без substituteTeacherId урок отменяется
+func SaveSubstitutions(r *http.Request, userID *int) ([]mod.Substitution, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var newSubstitutions []mod.Substitution
+	err = json.NewDecoder(r.Body).Decode(&newSubstitutions)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error decoding JSON")
+	}
+	for i := range newSubstitutions {
+		newSubstitutions[i].CreatedBy = userID
+	}
+	err = utils.ValidateSlice(newSubstitutions)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error starting transaction")
+	}
+
+	stmt, err := tx.Prepare(utils.GenerateSQL(mod.Substitution{}, "insert"))
+	if err != nil {
+		tx.Rollback()
+		return nil, utils.ErrorHandler(err, "Error preparing statement")
+	}
+	defer stmt.Close()
+
+	for i, s := range newSubstitutions {
+		err = checkSubstitution(tx, s)
+		if err != nil {
+			tx.Rollback()
+			return nil, withIndex(err, i)
+		}
+		res, err := stmt.Exec(utils.GetStructFields(s, true, false)...)
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error inserting substitution")
+		}
+		lastId, err := res.LastInsertId()
+		if err != nil {
+			tx.Rollback()
+			return nil, utils.ErrorHandler(err, "Error getting last insert ID")
+		}
+		newSubstitutions[i].ID = int(lastId)
+		newSubstitutions[i].Version = 1
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error committing transaction")
+	}
+	return newSubstitutions, nil
+}
+
+// checkSubstitution — урок проходит в эту дату, замены на неё ещё нет, заменяющий учитель существует и свободен
+func checkSubstitution(tx *sql.Tx, s mod.Substitution) error {
+	fail := func(field, msg string) error {
+		return &utils.ValidationError{Errors: []utils.FieldError{{Field: field, Message: msg}}}
+	}
+	lesson, err := selectForUpdate[mod.Lesson](tx, s.LessonID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fail("lessonId", "lesson not found")
+	}
+	if err != nil {
+		return utils.ErrorHandler(err, "Error fetching lesson")
+	}
+	day, err := time.Parse(time.DateOnly, s.Date)
+	if err != nil {
+		return fail("date", "invalid date")
+	}
+	if isoWeekday(day) != lesson.Weekday {
+		return fail("date", "lesson does not take place on this weekday")
+	}
+	if s.Date < lesson.ValidFrom || (lesson.ValidTo != nil && s.Date > *lesson.ValidTo) {
+		return fail("date", "lesson is not scheduled on this date")
+	}
+
+	var n int
+	err = tx.QueryRow("SELECT COUNT(*) FROM substitutions WHERE lessonId = ? AND date = ?", s.LessonID, s.Date).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking substitutions")
+	}
+	if n > 0 {
+		return utils.ErrorHandler(utils.ErrScheduleConflict, "Lesson already has a substitution on "+s.Date)
+	}
+
+	if s.SubstituteTeacherID == nil {
+		return nil
+	}
+	if *s.SubstituteTeacherID == lesson.TeacherID {
+		return fail("substituteTeacherId", "substitute must differ from the regular teacher")
+	}
+	err = tx.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", *s.SubstituteTeacherID).Scan(&n)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking teacher")
+	}
+	if n == 0 {
+		return fail("substituteTeacherId", "teacher not found")
+	}
+
+	// занят: свой урок в это время (если его самого не заменили) или другая замена на тот же урок дня
+	var busy bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM lessons l WHERE l.teacherId = ? AND l.weekday = ? AND l.period = ?
+			AND l.validFrom <= ? AND COALESCE(l.validTo, ?) >= ?
+			AND NOT EXISTS (SELECT 1 FROM substitutions x WHERE x.lessonId = l.id AND x.date = ?))
+		OR EXISTS (SELECT 1 FROM substitutions x JOIN lessons l ON l.id = x.lessonId
+			WHERE x.substituteTeacherId = ? AND x.date = ? AND l.period = ?)`,
+		*s.SubstituteTeacherID, lesson.Weekday, lesson.Period, s.Date, openEnd, s.Date, s.Date,
+		*s.SubstituteTeacherID, s.Date, lesson.Period).Scan(&busy)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error checking substitute availability")
+	}
+	if busy {
+		return utils.ErrorHandler(utils.ErrScheduleConflict, fmt.Sprintf("Teacher %d is busy on %s, period %d", *s.SubstituteTeacherID, s.Date, lesson.Period))
+	}
+	return nil
+}
+
+// DeleteSubstitutionById — отмена замены: урок снова ведёт основной учитель
+func DeleteSubstitutionById(id int) error {
+	db, err := ConnectDB()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	res, err := db.Exec(utils.GenerateSQL(mod.Substitution{}, "delete"), id)
+	if err != nil {
+		return utils.ErrorHandler(err, "Error deleting substitution")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return utils.ErrorHandler(err, "Error retrieving delete result")
+	}
+	if rows == 0 {
+		return utils.ErrorHandler(sql.ErrNoRows, "Substitution not found")
+	}
+	return nil
+}
+
+// GetPeriods — расписание звонков
+func GetPeriods() ([]mod.Period, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT number, TIME_FORMAT(startTime, '%H:%i'), TIME_FORMAT(endTime, '%H:%i') FROM periods ORDER BY number")
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying periods")
+	}
+	defer rows.Close()
+
+	periods := make([]mod.Period, 0)
+	for rows.Next() {
+		var p mod.Period
+		err = rows.Scan(&p.Number, &p.StartTime, &p.EndTime)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning periods")
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
+
+// ReplacePeriods — новое расписание звонков целиком; номера, на которые ссылаются уроки, удалить нельзя
+func ReplacePeriods(periods []mod.Period) ([]mod.Period, error) {
+	var errs []utils.FieldError
+	seen := make(map[int]bool)
+	for i, p := range periods {
+		index := i
+		start, errStart := time.Parse("15:04", p.StartTime)
+		end, errEnd := time.Parse("15:04", p.EndTime)
+		switch {
+		case p.Number < 1 || p.Number > 12:
+			errs = append(errs, utils.FieldError{Index: &index, Field: "number", Message: "must be between 1 and 12"})
+		case seen[p.Number]:
+			errs = append(errs, utils.FieldError{Index: &index, Field: "number", Message: "duplicate period"})
+		case errStart != nil || errEnd != nil:
+			errs = append(errs, utils.FieldError{Index: &index, Field: "startTime", Message: "times must be HH:MM"})
+		case !end.After(start):
+			errs = append(errs, utils.FieldError{Index: &index, Field: "endTime", Message: "must be after startTime"})
+		}
+		seen[p.Number] = true
+	}
+	sorted := append([]mod.Period(nil), periods...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+	for i := 1; i < len(sorted) && len(errs) == 0; i++ {
+		if sorted[i].StartTime < sorted[i-1].EndTime {
+			errs = append(errs, utils.FieldError{Field: "startTime", Message: fmt.Sprintf("period %d overlaps period %d", sorted[i].Number, sorted[i-1].Number)})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &utils.ValidationError{Errors: errs}
+	}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		var used []int
+		rows, err := tx.Query("SELECT DISTINCT period FROM lessons")
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking lessons")
+		}
+		for rows.Next() {
+			var p int
+			if err := rows.Scan(&p); err != nil {
+				rows.Close()
+				return utils.ErrorHandler(err, "Error scanning lessons")
+			}
+			if !seen[p] {
+				used = append(used, p)
+			}
+		}
+		rows.Close()
+		if len(used) > 0 {
+			return utils.ErrorHandler(utils.ErrInUse, fmt.Sprintf("Periods %v are used by lessons", used))
+		}
+
+		_, err = tx.Exec("DELETE FROM periods")
+		if err != nil {
+			return utils.ErrorHandler(err, "Error replacing periods")
+		}
+		for _, p := range sorted {
+			_, err = tx.Exec("INSERT INTO periods (number, startTime, endTime) VALUES (?, ?, ?)", p.Number, p.StartTime, p.EndTime)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error replacing periods")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sorted, nil
+}
+
+// slotSQL — урок с названием класса, именем учителя и временем звонка
+var slotSQL = "SELECT " + strings.Join(prefixColumns("l", utils.SelectColumns(mod.Lesson{}, nil)), ", ") +
+	", c.name, CONCAT(t.firstName, ' ', t.lastName), COALESCE(TIME_FORMAT(p.startTime, '%H:%i'), ''), COALESCE(TIME_FORMAT(p.endTime, '%H:%i'), '')" +
+	" FROM lessons l JOIN classes c ON c.id = l.classId JOIN teachers t ON t.id = l.teacherId LEFT JOIN periods p ON p.number = l.period"
+
+func querySlots(q interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}, query string, args ...interface{}) ([]mod.TimetableSlot, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying timetable")
+	}
+	defer rows.Close()
+
+	slots := make([]mod.TimetableSlot, 0)
+	for rows.Next() {
+		var s mod.TimetableSlot
+		fields := append(utils.GetStructFields(&s.Lesson, true, true), &s.Class, &s.Teacher, &s.StartTime, &s.EndTime)
+		err = rows.Scan(fields...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning timetable")
+		}
+		slots = append(slots, s)
+	}
+	return slots, rows.Err()
+}
+
+// GetTeacherTimetable — расписание учителя: шаблон недели на сегодня или, если задана week (любая дата недели),
+// уроки этой недели по датам с учётом замен — в том числе уроки, где учитель замещает коллегу
+func GetTeacherTimetable(teacherID int, week string) ([]mod.TimetableSlot, error) {
+	return timetable("teacher", teacherID, week)
+}
+
+// GetClassTimetable — расписание класса; week — как в GetTeacherTimetable
+func GetClassTimetable(classID int, week string) ([]mod.TimetableSlot, error) {
+	return timetable("class", classID, week)
+}
+
+func timetable(owner string, id int, week string) ([]mod.TimetableSlot, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var n int
+	column := "l.classId"
+	if owner == "teacher" {
+		column = "l.teacherId"
+		err = db.QueryRow("SELECT COUNT(*) FROM teachers WHERE id = ? AND deletedAt IS NULL", id).Scan(&n)
+	} else {
+		err = db.QueryRow("SELECT COUNT(*) FROM classes WHERE id = ?", id).Scan(&n)
+	}
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying DB")
+	}
+	if n == 0 {
+		return nil, utils.ErrorHandler(sql.ErrNoRows, strings.ToUpper(owner[:1])+owner[1:]+" not found")
+	}
+
+	if week == "" {
+		today := time.Now().Format(time.DateOnly)
+		return querySlots(db, slotSQL+" WHERE "+column+" = ? AND l.validFrom <= ? AND COALESCE(l.validTo, ?) >= ? ORDER BY l.weekday, l.period",
+			id, today, openEnd, today)
+	}
+
+	day, err := time.ParseInLocation(time.DateOnly, week, time.Local)
+	if err != nil {
+		return nil, utils.ErrorHandler(utils.ErrInvalidFilter, "week must be YYYY-MM-DD")
+	}
+	monday := day.AddDate(0, 0, 1-isoWeekday(day))
+	from, to := monday.Format(time.DateOnly), monday.AddDate(0, 0, 6).Format(time.DateOnly)
+	return datedSlots(db, owner, column, id, from, to)
+}
+
+// datedSlots — уроки владельца с датами в пределах [from, to] и заменами на эти даты
+func datedSlots(db *sql.DB, owner, column string, id int, from, to string) ([]mod.TimetableSlot, error) {
+	query := slotSQL + " WHERE (" + column + " = ?"
+	args := []interface{}{id}
+	if owner == "teacher" {
+		query += " OR l.id IN (SELECT lessonId FROM substitutions WHERE substituteTeacherId = ? AND date BETWEEN ? AND ?)"
+		args = append(args, id, from, to)
+	}
+	query += ") AND l.validFrom <= ? AND COALESCE(l.validTo, ?) >= ?"
+	args = append(args, to, openEnd, from)
+	templates, err := querySlots(db, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make(map[string]mod.Substitution)
+	columns := prefixColumns("x", utils.SelectColumns(mod.Substitution{}, nil))
+	rows, err := db.Query("SELECT "+strings.Join(columns, ", ")+" FROM substitutions x WHERE x.date BETWEEN ? AND ?", from, to)
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying substitutions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s mod.Substitution
+		err = rows.Scan(utils.GetStructFields(&s, true, true)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning substitutions")
+		}
+		subs[fmt.Sprintf("%d|%s", s.LessonID, s.Date)] = s
+	}
+	if err = rows.Err(); err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying substitutions")
+	}
+
+	start, _ := time.Parse(time.DateOnly, from)
+	slots := make([]mod.TimetableSlot, 0, len(templates))
+	for _, t := range templates {
+		date := start.AddDate(0, 0, t.Weekday-1).Format(time.DateOnly)
+		if date < t.ValidFrom || (t.ValidTo != nil && date > *t.ValidTo) {
+			continue
+		}
+		slot := t
+		slot.Date = date
+		if s, ok := subs[fmt.Sprintf("%d|%s", t.ID, date)]; ok {
+			sub := s
+			slot.Substitution = &sub
+		}
+		// урок коллеги попадает в расписание учителя только в день его замены
+		if owner == "teacher" && t.TeacherID != id && (slot.Substitution == nil || slot.Substitution.SubstituteTeacherID == nil || *slot.Substitution.SubstituteTeacherID != id) {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+	sort.SliceStable(slots, func(i, j int) bool {
+		if slots[i].Date != slots[j].Date {
+			return slots[i].Date < slots[j].Date
+		}
+		return slots[i].Period < slots[j].Period
+	})
+	return slots, nil
+}
@@ -0,0 +1,193 @@
+package sqlconnect
+
+import (
+	mod "WebProject/internal/models"
+	"WebProject/pkg/utils"
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TrashRetentionDays — сколько дней мягко удалённые записи хранятся до окончательного удаления (TRASH_RETENTION_DAYS)
+func TrashRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return 30
+	}
+	return days
+}
+
+// GetTrash — удалённые студенты и учителя, последние удалённые первыми; kind ограничивает выборку одной сущностью
+func GetTrash(kind string) (mod.Trash, error) {
+	trash := mod.Trash{Students: []mod.Student{}, Teachers: []mod.Teacher{}, RetentionDays: TrashRetentionDays()}
+
+	db, err := ConnectDB()
+	if err != nil {
+		return trash, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	if kind == "" || kind == "students" {
+		trash.Students, err = deletedRecords[mod.Student](db, "students")
+		if err != nil {
+			return trash, err
+		}
+	}
+	if kind == "" || kind == "teachers" {
+		trash.Teachers, err = deletedRecords[mod.Teacher](db, "teachers")
+		if err != nil {
+			return trash, err
+		}
+	}
+	return trash, nil
+}
+
+func deletedRecords[T any](db *sql.DB, table string) ([]T, error) {
+	var model T
+	columns := utils.SelectColumns(model, nil)
+	rows, err := db.Query("SELECT " + strings.Join(columns, ", ") + " FROM " + table + " WHERE deletedAt IS NOT NULL ORDER BY deletedAt DESC, id")
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error querying trash")
+	}
+	defer rows.Close()
+
+	records := make([]T, 0)
+	for rows.Next() {
+		var rec T
+		err = rows.Scan(utils.GetScanFields(&rec, columns)...)
+		if err != nil {
+			return nil, utils.ErrorHandler(err, "Error scanning trash")
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// RestoreStudentById — возвращает студента из корзины
+func RestoreStudentById(ctx context.Context, id int) (mod.Student, error) {
+	// место в классе могли занять, пока студент был в корзине
+	student, err := restoreRecord(ctx, historyStudent, id, "Deleted student not found", func(tx *sql.Tx, s mod.Student) error {
+		return checkStudentCapacity(tx, s, nil)
+	})
+	if err != nil {
+		return mod.Student{}, err
+	}
+	indexStudent(student)
+	return student, nil
+}
+
+// RestoreTeacherById — возвращает учителя из корзины
+func RestoreTeacherById(ctx context.Context, id int) (mod.Teacher, error) {
+	teacher, err := restoreRecord[mod.Teacher](ctx, historyTeacher, id, "Deleted teacher not found", nil)
+	if err != nil {
+		return mod.Teacher{}, err
+	}
+	indexTeacher(teacher)
+	return teacher, nil
+}
+
+// restoreRecord — снимает отметку удаления; check (если задан) может отменить восстановление
+func restoreRecord[T any](ctx context.Context, entity string, id int, notFound string, check func(tx *sql.Tx, rec T) error) (T, error) {
+	var restored T
+	db, err := ConnectDB()
+	if err != nil {
+		return restored, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	err = withTx(db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(utils.GenerateSQL(restored, "restore"), id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error restoring record")
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return utils.ErrorHandler(err, "Error checking restore result")
+		}
+		if rows == 0 {
+			return utils.ErrorHandler(sql.ErrNoRows, notFound)
+		}
+		restored, err = selectForUpdate[T](tx, id)
+		if err != nil {
+			return utils.ErrorHandler(err, "Error fetching restored record")
+		}
+		if check != nil {
+			err = check(tx, restored)
+			if err != nil {
+				return err
+			}
+		}
+		return recordHistory(ctx, tx, entity, id, historyRestore, nil, restored)
+	})
+	return restored, err
+}
+
+// PurgeTrash — окончательно удаляет записи, пролежавшие в корзине дольше retentionDays; в журнал пишется purge
+func PurgeTrash(retentionDays int) (int64, error) {
+	db, err := ConnectDB()
+	if err != nil {
+		return 0, utils.ErrorHandler(err, "Error connecting to DB")
+	}
+	defer db.Close()
+
+	var purged int64
+	var released []string
+	defer func() { removeStoredFiles(released...) }()
+	for _, src := range []struct{ table, entity, owner string }{{"students", historyStudent, ownerStudent}, {"teachers", historyTeacher, ownerTeacher}} {
+		err = withTx(db, func(tx *sql.Tx) error {
+			rows, err := tx.Query("SELECT id FROM "+src.table+" WHERE deletedAt IS NOT NULL AND deletedAt < NOW() - INTERVAL ? DAY FOR UPDATE", retentionDays)
+			if err != nil {
+				return utils.ErrorHandler(err, "Error selecting expired "+src.table)
+			}
+			var ids []int
+			for rows.Next() {
+				var id int
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return utils.ErrorHandler(err, "Error scanning expired "+src.table)
+				}
+				ids = append(ids, id)
+			}
+			rows.Close()
+
+			var keys []string
+			for _, id := range ids {
+				owned, err := deleteOwnerAttachments(tx, src.owner, id)
+				if err != nil {
+					return err
+				}
+				keys = append(keys, owned...)
+				_, err = tx.Exec("DELETE FROM "+src.table+" WHERE id = ?", id)
+				if err != nil {
+					return utils.ErrorHandler(err, "Error purging "+src.table)
+				}
+				err = recordHistory(context.Background(), tx, src.entity, id, historyPurge, nil, nil)
+				if err != nil {
+					return err
+				}
+			}
+			purged += int64(len(ids))
+			released = append(released, keys...)
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// PurgeTrashPeriodically — фоновая очистка корзины; запускается из main в отдельной горутине
+func PurgeTrashPeriodically(every time.Duration) {
+	for {
+		purged, err := PurgeTrash(TrashRetentionDays())
+		if err == nil && purged > 0 {
+			log.Printf("Trash purge: %d records deleted permanently", purged)
+		}
+		time.Sleep(every)
+	}
+}
@@ -0,0 +1,242 @@
+package search
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	TypeStudent = "student"
+	TypeTeacher = "teacher"
+	TypeExec    = "exec"
+)
+
+// Document — запись, попадающая в поисковый индекс
+type Document struct {
+	Type      string `json:"type"`
+	ID        int    `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Email     string `json:"email"`
+}
+
+// Result — найденный документ с оценкой релевантности
+type Result struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+type docKey struct {
+	typ string
+	id  int
+}
+
+// Index — инвертированный индекс по именам и email в памяти процесса
+type Index struct {
+	mu    sync.RWMutex
+	docs  map[docKey]Document
+	terms map[string]map[docKey]struct{}
+}
+
+// Default — общий индекс приложения, обновляется из sqlconnect
+var Default = NewIndex()
+
+func NewIndex() *Index {
+	return &Index{
+		docs:  make(map[docKey]Document),
+		terms: make(map[string]map[docKey]struct{}),
+	}
+}
+
+// Add — добавляет или заменяет документ
+func (idx *Index) Add(doc Document) {
+	key := docKey{doc.Type, doc.ID}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.removeLocked(key)
+	idx.docs[key] = doc
+	for _, term := range documentTerms(doc) {
+		postings, ok := idx.terms[term]
+		if !ok {
+			postings = make(map[docKey]struct{})
+			idx.terms[term] = postings
+		}
+		postings[key] = struct{}{}
+	}
+}
+
+// Remove — удаляет документ из индекса
+func (idx *Index) Remove(typ string, id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.removeLocked(docKey{typ, id})
+}
+
+func (idx *Index) removeLocked(key docKey) {
+	doc, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+	for _, term := range documentTerms(doc) {
+		delete(idx.terms[term], key)
+		if len(idx.terms[term]) == 0 {
+			delete(idx.terms, term)
+		}
+	}
+	delete(idx.docs, key)
+}
+
+// Search — ищет документы, совпадающие со всеми словами запроса (точно, по префиксу или с опечаткой);
+// types оставляет только документы этих типов
+func (idx *Index) Search(query string, limit int, types ...string) []Result {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[docKey]float64
+	for _, qt := range queryTerms {
+		termScores := make(map[docKey]float64)
+		for term, postings := range idx.terms {
+			score := matchScore(qt, term)
+			if score == 0 {
+				continue
+			}
+			for key := range postings {
+				if score > termScores[key] {
+					termScores[key] = score
+				}
+			}
+		}
+
+		if scores == nil {
+			scores = termScores
+			continue
+		}
+		for key, total := range scores {
+			s, ok := termScores[key]
+			if !ok {
+				delete(scores, key)
+				continue
+			}
+			scores[key] = total + s
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for key, score := range scores {
+		if len(types) > 0 && !slices.Contains(types, key.typ) {
+			continue
+		}
+		results = append(results, Result{Document: idx.docs[key], Score: score})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		if results[i].LastName != results[j].LastName {
+			return results[i].LastName < results[j].LastName
+		}
+		return results[i].ID < results[j].ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// matchScore — 3 за точное совпадение, 2 за префикс, меньше 1 за совпадение с опечатками
+func matchScore(queryTerm, term string) float64 {
+	if term == queryTerm {
+		return 3
+	}
+	if strings.HasPrefix(term, queryTerm) {
+		return 2
+	}
+
+	maxDist := 0
+	switch n := len([]rune(queryTerm)); {
+	case n >= 7:
+		maxDist = 2
+	case n >= 4:
+		maxDist = 1
+	}
+	if maxDist == 0 {
+		return 0
+	}
+
+	// опечатка в начале слова: сравниваем и целиком, и с префиксом той же длины
+	dist := levenshtein(queryTerm, term, maxDist)
+	runes := []rune(term)
+	if qn := len([]rune(queryTerm)); len(runes) > qn {
+		if d := levenshtein(queryTerm, string(runes[:qn]), maxDist); d < dist {
+			dist = d
+		}
+	}
+	if dist > maxDist {
+		return 0
+	}
+	return 1 - float64(dist)*0.25
+}
+
+// levenshtein — расстояние редактирования с ранним выходом, если оно заведомо больше max
+func levenshtein(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func documentTerms(doc Document) []string {
+	terms := tokenize(doc.FirstName + " " + doc.LastName + " " + doc.Email)
+	if email := strings.ToLower(strings.TrimSpace(doc.Email)); email != "" {
+		terms = append(terms, email)
+	}
+	return terms
+}
+
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool)
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
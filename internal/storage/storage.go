@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ErrNotFound — файла с таким ключом нет в хранилище
+var ErrNotFound = errors.New("file not found")
+
+// ErrInvalidKey — ключ выходит за пределы хранилища
+var ErrInvalidKey = errors.New("invalid storage key")
+
+// Storage — хранилище файлов по ключу вида homework/12/3f9a...-report.pdf
+type Storage interface {
+	// Put — записывает содержимое r под ключом key и возвращает размер в байтах
+	Put(key string, r io.Reader) (int64, error)
+	// Open — содержимое файла; ErrNotFound, если файла нет
+	Open(key string) (io.ReadCloser, error)
+	// Delete — удаляет файл; отсутствие файла ошибкой не считается
+	Delete(key string) error
+}
+
+// File — загружаемый файл: имя от клиента, тип содержимого и данные
+type File struct {
+	Name        string
+	ContentType string
+	Body        io.Reader
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultStorage Storage
+)
+
+// Default — хранилище приложения: STORAGE_BACKEND=s3 — бакет S3 (см. s3FromEnv),
+// иначе каталог STORAGE_DIR (по умолчанию ./storage)
+func Default() Storage {
+	defaultOnce.Do(func() {
+		if os.Getenv("STORAGE_BACKEND") == "s3" {
+			defaultStorage = s3FromEnv()
+			return
+		}
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "storage"
+		}
+		defaultStorage = NewLocal(dir)
+	})
+	return defaultStorage
+}
+
+// checkKey — ключи с .., пустыми сегментами и абсолютные пути отклоняются
+func checkKey(key string) error {
+	if key == "" || path.Clean("/"+key) != "/"+key {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// Local — файлы в каталоге на диске
+type Local struct {
+	root string
+}
+
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// path — путь к файлу на диске
+func (s *Local) path(key string) (string, error) {
+	err := checkKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put — пишет во временный файл рядом и переименовывает, чтобы недописанный файл не был виден под ключом
+func (s *Local) Put(key string, r io.Reader) (int64, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	err = os.MkdirAll(filepath.Dir(p), 0o750)
+	if err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *Local) Open(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *Local) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// NewKey — уникальный ключ в каталоге prefix с безопасной для файловой системы версией имени файла
+func NewKey(prefix, fileName string) string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	name := unsafeNameChars.ReplaceAllString(filepath.Base(strings.ReplaceAll(fileName, `\`, "/")), "_")
+	name = strings.Trim(name, "._")
+	if len(name) > 100 {
+		name = name[len(name)-100:]
+	}
+	if name == "" {
+		name = "file"
+	}
+	return prefix + "/" + hex.EncodeToString(buf) + "-" + name
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"WebProject/pkg/utils"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PhotoTypes — допустимые типы фотографий
+var PhotoTypes = []string{"image/jpeg", "image/png", "image/gif", "image/webp"}
+
+// DocumentTypes — допустимые типы документов: изображения, PDF, текст и офисные форматы
+var DocumentTypes = append(slices.Clone(PhotoTypes),
+	"application/pdf",
+	"text/plain",
+	"text/csv",
+	"application/zip",
+	"application/msword",
+	"application/vnd.ms-excel",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	"application/vnd.oasis.opendocument.text",
+	"application/vnd.oasis.opendocument.spreadsheet",
+)
+
+// extensionTypes — типы по расширению для форматов, которые http.DetectContentType не различает
+// (docx/xlsx определяются как zip, doc/xls — как octet-stream)
+var extensionTypes = map[string]string{
+	".csv":  "text/csv",
+	".doc":  "application/msword",
+	".xls":  "application/vnd.ms-excel",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".odt":  "application/vnd.oasis.opendocument.text",
+	".ods":  "application/vnd.oasis.opendocument.spreadsheet",
+}
+
+// MaxFileSize — предельный размер загружаемого файла: FILE_MAX_SIZE_MB, по умолчанию 10 МБ
+func MaxFileSize() int64 {
+	mb, err := strconv.Atoi(os.Getenv("FILE_MAX_SIZE_MB"))
+	if err != nil || mb <= 0 {
+		mb = 10
+	}
+	return int64(mb) << 20
+}
+
+// Upload — загруженный файл во временном файле: размер, sha256 и тип, определённый по содержимому
+type Upload struct {
+	Name        string
+	ContentType string
+	Size        int64
+	SHA256      string
+	tmp         *os.File
+}
+
+// Spool — сохраняет файл во временный каталог, проверяя размер и тип содержимого.
+// Тип клиента не учитывается: он определяется по первым байтам файла и должен входить в allowed
+func Spool(file File, maxSize int64, allowed []string) (*Upload, error) {
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, utils.ErrorHandler(err, "Error creating temporary file")
+	}
+	up := &Upload{Name: file.Name, tmp: tmp}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(file.Body, maxSize+1))
+	if err != nil {
+		up.Close()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, utils.ErrorHandler(utils.ErrFileTooLarge, tooLargeMessage(maxSize))
+		}
+		return nil, utils.ErrorHandler(err, "Error reading upload")
+	}
+	if size > maxSize {
+		up.Close()
+		return nil, utils.ErrorHandler(utils.ErrFileTooLarge, tooLargeMessage(maxSize))
+	}
+	if size == 0 {
+		up.Close()
+		return nil, &utils.ValidationError{Errors: []utils.FieldError{{Field: "file", Message: "is empty"}}}
+	}
+	up.Size = size
+	up.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		up.Close()
+		return nil, utils.ErrorHandler(err, "Error reading upload")
+	}
+	up.ContentType = sniffContentType(head[:n], file.Name)
+	if !slices.Contains(allowed, up.ContentType) {
+		up.Close()
+		return nil, utils.ErrorHandler(utils.ErrUnsupportedMediaType, "File type "+up.ContentType+" is not allowed")
+	}
+	_, err = tmp.Seek(0, io.SeekStart)
+	if err != nil {
+		up.Close()
+		return nil, utils.ErrorHandler(err, "Error reading upload")
+	}
+	return up, nil
+}
+
+func tooLargeMessage(maxSize int64) string {
+	return "File is larger than " + strconv.FormatInt(maxSize>>20, 10) + " MB"
+}
+
+// sniffContentType — тип по содержимому; для zip, octet-stream и простого текста уточняется по расширению
+func sniffContentType(head []byte, name string) string {
+	detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	byExt, ok := extensionTypes[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return detected
+	}
+	switch {
+	case detected == "application/zip" && (strings.Contains(byExt, "openxmlformats") || strings.Contains(byExt, "opendocument")):
+		return byExt
+	case detected == "application/octet-stream" && (strings.HasPrefix(byExt, "application/vnd.ms-") || byExt == "application/msword"):
+		return byExt
+	case detected == "text/plain" && strings.HasPrefix(byExt, "text/"):
+		return byExt
+	}
+	return detected
+}
+
+// Read — содержимое загруженного файла; Upload передаётся в Storage.Put как источник
+func (u *Upload) Read(p []byte) (int, error) {
+	return u.tmp.Read(p)
+}
+
+// Close — удаляет временный файл
+func (u *Upload) Close() error {
+	u.tmp.Close()
+	return os.Remove(u.tmp.Name())
+}
+
+// BlobKey — ключ содержимого по его sha256: одинаковые файлы хранятся один раз
+func BlobKey(sum string) string {
+	return "blobs/" + sum[:2] + "/" + sum
+}
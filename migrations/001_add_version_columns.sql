@@ -0,0 +1,12 @@
+-- Версия записи и время последнего изменения для ETag / If-Match
+ALTER TABLE teachers
+    ADD COLUMN version INT NOT NULL DEFAULT 1,
+    ADD COLUMN updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE students
+    ADD COLUMN version INT NOT NULL DEFAULT 1,
+    ADD COLUMN updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
+
+ALTER TABLE execs
+    ADD COLUMN version INT NOT NULL DEFAULT 1,
+    ADD COLUMN updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP;
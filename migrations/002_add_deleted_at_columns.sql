@@ -0,0 +1,8 @@
+-- Мягкое удаление: DELETE помечает запись, окончательно её удаляет фоновая очистка корзины
+ALTER TABLE teachers
+    ADD COLUMN deletedAt DATETIME NULL DEFAULT NULL,
+    ADD INDEX idx_teachers_deleted_at (deletedAt);
+
+ALTER TABLE students
+    ADD COLUMN deletedAt DATETIME NULL DEFAULT NULL,
+    ADD INDEX idx_students_deleted_at (deletedAt);
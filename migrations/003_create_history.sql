@@ -0,0 +1,14 @@
+-- Журнал изменений teachers/students/execs: кто, когда и что поменял
+CREATE TABLE history (
+    id         BIGINT AUTO_INCREMENT PRIMARY KEY,
+    entity     VARCHAR(20) NOT NULL,
+    entityId   INT NOT NULL,
+    action     VARCHAR(20) NOT NULL,
+    actorId    VARCHAR(64) NULL,
+    actorName  VARCHAR(100) NULL,
+    changedAt  DATETIME(6) NOT NULL,
+    beforeData JSON NULL,
+    afterData  JSON NULL,
+    diff       JSON NULL,
+    INDEX idx_history_entity (entity, entityId, changedAt)
+);
@@ -0,0 +1,41 @@
+-- Классы как отдельная сущность: студенты ссылаются на класс через classId вместо свободной строки
+CREATE TABLE classes (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(3) NOT NULL,
+    gradeLevel TINYINT NOT NULL,
+    academicYear CHAR(9) NOT NULL,
+    homeroomTeacherId INT NULL,
+    capacity INT NOT NULL DEFAULT 30,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_classes_name_year (name, academicYear),
+    CONSTRAINT fk_classes_homeroom_teacher FOREIGN KEY (homeroomTeacherId) REFERENCES teachers (id) ON DELETE SET NULL
+);
+
+-- существующие строки классов: регистр и пробелы нормализуются ("9a " → "9A"),
+-- учебный год — текущий (с сентября), вместимость не меньше текущего числа студентов
+SET @year := YEAR(CURDATE()) - (MONTH(CURDATE()) < 9);
+
+INSERT INTO classes (name, gradeLevel, academicYear, homeroomTeacherId, capacity)
+SELECT c.name,
+       CAST(REGEXP_SUBSTR(c.name, '^[0-9]+') AS UNSIGNED),
+       CONCAT(@year, '-', @year + 1),
+       (SELECT MIN(t.id) FROM teachers t WHERE UPPER(TRIM(t.class)) = c.name AND t.deletedAt IS NULL),
+       GREATEST(30, (SELECT COUNT(*) FROM students s WHERE UPPER(TRIM(s.class)) = c.name AND s.deletedAt IS NULL))
+FROM (
+    SELECT DISTINCT UPPER(TRIM(class)) AS name FROM students WHERE class IS NOT NULL AND TRIM(class) <> ''
+    UNION
+    SELECT DISTINCT UPPER(TRIM(class)) FROM teachers WHERE class IS NOT NULL AND TRIM(class) <> ''
+) AS c;
+
+ALTER TABLE students
+    ADD COLUMN classId INT NULL,
+    ADD CONSTRAINT fk_students_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE SET NULL;
+
+UPDATE students s
+JOIN classes c ON c.name = UPPER(TRIM(s.class))
+SET s.classId = c.id, s.class = c.name;
+
+UPDATE teachers t
+JOIN classes c ON c.name = UPPER(TRIM(t.class))
+SET t.class = c.name;
@@ -0,0 +1,21 @@
+-- Назначения учителей: учитель ведёт предмет в классе, у учителя может быть много назначений
+CREATE TABLE assignments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    teacherId INT NOT NULL,
+    classId INT NOT NULL,
+    subject VARCHAR(50) NOT NULL,
+    hoursPerWeek TINYINT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_assignments_teacher_class_subject (teacherId, classId, subject),
+    INDEX idx_assignments_class (classId),
+    CONSTRAINT fk_assignments_teacher FOREIGN KEY (teacherId) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_assignments_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE RESTRICT
+);
+
+-- единственные класс и предмет из карточки учителя становятся его первым назначением
+INSERT INTO assignments (teacherId, classId, subject)
+SELECT t.id, c.id, t.subject
+FROM teachers t
+JOIN classes c ON c.id = (SELECT c2.id FROM classes c2 WHERE c2.name = t.class ORDER BY c2.academicYear DESC LIMIT 1)
+WHERE t.subject IS NOT NULL AND t.subject <> '';
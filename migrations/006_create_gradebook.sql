@@ -0,0 +1,36 @@
+-- Учётные записи учителей: exec с ролью teacher связан с карточкой учителя
+ALTER TABLE execs
+    ADD COLUMN teacherId INT NULL,
+    ADD CONSTRAINT fk_execs_teacher FOREIGN KEY (teacherId) REFERENCES teachers (id) ON DELETE SET NULL;
+
+-- Журнал: работы класса по предмету и оценки студентов за них
+CREATE TABLE assessments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(100) NOT NULL,
+    subject VARCHAR(50) NOT NULL,
+    classId INT NOT NULL,
+    teacherId INT NULL,
+    date DATE NOT NULL,
+    term TINYINT NOT NULL,
+    weight DECIMAL(4, 2) NOT NULL DEFAULT 1,
+    maxScore DECIMAL(7, 2) NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_assessments_class_subject_term (classId, subject, term),
+    CONSTRAINT fk_assessments_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_assessments_teacher FOREIGN KEY (teacherId) REFERENCES teachers (id) ON DELETE SET NULL
+);
+
+CREATE TABLE grades (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    assessmentId INT NOT NULL,
+    studentId INT NOT NULL,
+    score DECIMAL(7, 2) NULL,
+    comment VARCHAR(500) NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_grades_assessment_student (assessmentId, studentId),
+    INDEX idx_grades_student (studentId),
+    CONSTRAINT fk_grades_assessment FOREIGN KEY (assessmentId) REFERENCES assessments (id) ON DELETE CASCADE,
+    CONSTRAINT fk_grades_student FOREIGN KEY (studentId) REFERENCES students (id) ON DELETE CASCADE
+);
@@ -0,0 +1,17 @@
+-- Ежедневная посещаемость: одна отметка на студента в день, класс фиксируется на момент отметки
+CREATE TABLE attendance (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    studentId INT NOT NULL,
+    classId INT NOT NULL,
+    date DATE NOT NULL,
+    status ENUM('present', 'absent', 'late', 'excused') NOT NULL,
+    reason VARCHAR(255) NULL,
+    recordedBy INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_attendance_student_date (studentId, date),
+    INDEX idx_attendance_class_date (classId, date),
+    CONSTRAINT fk_attendance_student FOREIGN KEY (studentId) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_attendance_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_attendance_recorded_by FOREIGN KEY (recordedBy) REFERENCES execs (id) ON DELETE SET NULL
+);
@@ -0,0 +1,50 @@
+-- Расписание: звонки, еженедельные уроки и замены на конкретные даты
+CREATE TABLE periods (
+    number TINYINT PRIMARY KEY,
+    startTime TIME NOT NULL,
+    endTime TIME NOT NULL
+);
+
+INSERT INTO periods (number, startTime, endTime) VALUES
+    (1, '08:30:00', '09:15:00'),
+    (2, '09:25:00', '10:10:00'),
+    (3, '10:30:00', '11:15:00'),
+    (4, '11:35:00', '12:20:00'),
+    (5, '12:30:00', '13:15:00'),
+    (6, '13:25:00', '14:10:00'),
+    (7, '14:20:00', '15:05:00'),
+    (8, '15:15:00', '16:00:00');
+
+CREATE TABLE lessons (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    classId INT NOT NULL,
+    subject VARCHAR(50) NOT NULL,
+    teacherId INT NOT NULL,
+    room VARCHAR(20) NULL,
+    weekday TINYINT NOT NULL,
+    period TINYINT NOT NULL,
+    validFrom DATE NOT NULL,
+    validTo DATE NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_lessons_slot (weekday, period),
+    INDEX idx_lessons_teacher (teacherId, weekday, period),
+    CONSTRAINT fk_lessons_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_lessons_teacher FOREIGN KEY (teacherId) REFERENCES teachers (id) ON DELETE CASCADE
+);
+
+CREATE TABLE substitutions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    lessonId INT NOT NULL,
+    date DATE NOT NULL,
+    substituteTeacherId INT NULL,
+    reason VARCHAR(255) NULL,
+    createdBy INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_substitutions_lesson_date (lessonId, date),
+    INDEX idx_substitutions_teacher_date (substituteTeacherId, date),
+    CONSTRAINT fk_substitutions_lesson FOREIGN KEY (lessonId) REFERENCES lessons (id) ON DELETE CASCADE,
+    CONSTRAINT fk_substitutions_teacher FOREIGN KEY (substituteTeacherId) REFERENCES teachers (id) ON DELETE CASCADE,
+    CONSTRAINT fk_substitutions_created_by FOREIGN KEY (createdBy) REFERENCES execs (id) ON DELETE SET NULL
+);
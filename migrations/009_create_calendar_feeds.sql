@@ -0,0 +1,25 @@
+-- Каникулы и праздники — исключения из еженедельного расписания
+CREATE TABLE holidays (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    startDate DATE NOT NULL,
+    endDate DATE NOT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_holidays_dates (startDate, endDate)
+);
+
+-- Подписки на расписание в формате iCalendar: в базе хранится только sha256 токена из ссылки
+CREATE TABLE calendar_feeds (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    ownerType ENUM('teacher', 'class') NOT NULL,
+    ownerId INT NOT NULL,
+    tokenHash CHAR(64) NOT NULL,
+    createdBy INT NULL,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    revokedAt DATETIME NULL,
+    lastAccessedAt DATETIME NULL,
+    UNIQUE KEY uq_calendar_feeds_token (tokenHash),
+    INDEX idx_calendar_feeds_owner (ownerType, ownerId),
+    CONSTRAINT fk_calendar_feeds_created_by FOREIGN KEY (createdBy) REFERENCES execs (id) ON DELETE SET NULL
+);
@@ -0,0 +1,61 @@
+-- Учебные годы и четверти как сущности; зачисления фиксируют класс студента в каждом учебном году
+CREATE TABLE academic_years (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    name CHAR(9) NOT NULL,
+    startDate DATE NOT NULL,
+    endDate DATE NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'planned',
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_academic_years_name (name)
+);
+
+CREATE TABLE terms (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    academicYearId INT NOT NULL,
+    number TINYINT NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    startDate DATE NOT NULL,
+    endDate DATE NOT NULL,
+    UNIQUE KEY uq_terms_year_number (academicYearId, number),
+    CONSTRAINT fk_terms_year FOREIGN KEY (academicYearId) REFERENCES academic_years (id) ON DELETE CASCADE
+);
+
+CREATE TABLE enrollments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    studentId INT NOT NULL,
+    academicYearId INT NOT NULL,
+    classId INT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'enrolled',
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_enrollments_student_year (studentId, academicYearId),
+    INDEX idx_enrollments_year_class (academicYearId, classId),
+    CONSTRAINT fk_enrollments_student FOREIGN KEY (studentId) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_year FOREIGN KEY (academicYearId) REFERENCES academic_years (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_enrollments_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE SET NULL
+);
+
+-- учебные годы существующих классов: с 1 сентября по 31 августа; текущий год (с сентября) — current,
+-- прошедшие — closed, будущие — planned
+SET @year := YEAR(CURDATE()) - (MONTH(CURDATE()) < 9);
+
+INSERT INTO academic_years (name, startDate, endDate, status)
+SELECT y.name,
+       CONCAT(LEFT(y.name, 4), '-09-01'),
+       CONCAT(RIGHT(y.name, 4), '-08-31'),
+       CASE
+           WHEN CAST(LEFT(y.name, 4) AS UNSIGNED) < @year THEN 'closed'
+           WHEN CAST(LEFT(y.name, 4) AS UNSIGNED) = @year THEN 'current'
+           ELSE 'planned'
+       END
+FROM (SELECT DISTINCT academicYear AS name FROM classes) AS y;
+
+-- класс может относиться только к заведённому учебному году
+ALTER TABLE classes
+    ADD CONSTRAINT fk_classes_academic_year FOREIGN KEY (academicYear) REFERENCES academic_years (name) ON DELETE RESTRICT;
+
+INSERT INTO enrollments (studentId, academicYearId, classId, status)
+SELECT s.id, y.id, c.id, 'enrolled'
+FROM students s
+JOIN classes c ON c.id = s.classId
+JOIN academic_years y ON y.name = c.academicYear;
@@ -0,0 +1,23 @@
+-- Родители и опекуны: один опекун может быть связан с несколькими студентами (братья и сёстры)
+CREATE TABLE guardians (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    firstName VARCHAR(50) NOT NULL,
+    lastName VARCHAR(50) NOT NULL,
+    phone VARCHAR(20) NULL,
+    email VARCHAR(100) NULL,
+    relationship VARCHAR(20) NOT NULL,
+    emergencyContact BOOLEAN NOT NULL DEFAULT FALSE,
+    custodyNotes VARCHAR(500) NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_guardians_email (email)
+);
+
+CREATE TABLE student_guardians (
+    studentId INT NOT NULL,
+    guardianId INT NOT NULL,
+    PRIMARY KEY (studentId, guardianId),
+    INDEX idx_student_guardians_guardian (guardianId),
+    CONSTRAINT fk_student_guardians_student FOREIGN KEY (studentId) REFERENCES students (id) ON DELETE CASCADE,
+    CONSTRAINT fk_student_guardians_guardian FOREIGN KEY (guardianId) REFERENCES guardians (id) ON DELETE CASCADE
+);
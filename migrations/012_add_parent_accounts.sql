@@ -0,0 +1,4 @@
+-- Учётные записи родителей: exec с ролью parent связан с карточкой опекуна
+ALTER TABLE execs
+    ADD COLUMN guardianId INT NULL,
+    ADD CONSTRAINT fk_execs_guardian FOREIGN KEY (guardianId) REFERENCES guardians (id) ON DELETE SET NULL;
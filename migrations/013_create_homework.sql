@@ -0,0 +1,53 @@
+-- Домашние задания: задание классу по предмету со сроком сдачи, файлы учителя и работы студентов.
+-- Сами файлы лежат в хранилище (STORAGE_DIR), в базе — только ключ, имя, тип и размер
+CREATE TABLE homework (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    classId INT NOT NULL,
+    subject VARCHAR(50) NOT NULL,
+    teacherId INT NULL,
+    title VARCHAR(200) NOT NULL,
+    instructions TEXT NULL,
+    dueAt DATETIME NOT NULL,
+    maxScore DECIMAL(7, 2) NULL,
+    assessmentId INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_homework_class_due (classId, dueAt),
+    CONSTRAINT fk_homework_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_homework_teacher FOREIGN KEY (teacherId) REFERENCES teachers (id) ON DELETE SET NULL,
+    CONSTRAINT fk_homework_assessment FOREIGN KEY (assessmentId) REFERENCES assessments (id) ON DELETE SET NULL
+);
+
+CREATE TABLE homework_attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    homeworkId INT NOT NULL,
+    fileName VARCHAR(255) NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storageKey VARCHAR(300) NOT NULL,
+    uploadedAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_homework_attachments_homework FOREIGN KEY (homeworkId) REFERENCES homework (id) ON DELETE CASCADE
+);
+
+-- одна работа студента на задание; повторная сдача заменяет файл, late пересчитывается
+CREATE TABLE homework_submissions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    homeworkId INT NOT NULL,
+    studentId INT NOT NULL,
+    fileName VARCHAR(255) NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storageKey VARCHAR(300) NOT NULL,
+    comment VARCHAR(1000) NULL,
+    submittedAt DATETIME NOT NULL,
+    late BOOLEAN NOT NULL DEFAULT FALSE,
+    score DECIMAL(7, 2) NULL,
+    feedback VARCHAR(2000) NULL,
+    gradedAt DATETIME NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_homework_submissions_homework_student (homeworkId, studentId),
+    INDEX idx_homework_submissions_student (studentId),
+    CONSTRAINT fk_homework_submissions_homework FOREIGN KEY (homeworkId) REFERENCES homework (id) ON DELETE CASCADE,
+    CONSTRAINT fk_homework_submissions_student FOREIGN KEY (studentId) REFERENCES students (id) ON DELETE CASCADE
+);
@@ -0,0 +1,27 @@
+-- Файлы: содержимое хранится один раз по sha256 (ключ blobs/ab/ab...), вложения ссылаются на него.
+-- Файл без вложений удаляется вместе с последним вложением
+CREATE TABLE files (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    sha256 CHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    contentType VARCHAR(100) NOT NULL,
+    storageKey VARCHAR(300) NOT NULL,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY uq_files_sha256 (sha256)
+);
+
+-- Вложения карточек студентов, учителей и учётных записей; фотография (kind = photo) у владельца одна
+CREATE TABLE attachments (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    fileId INT NOT NULL,
+    ownerType ENUM('student', 'teacher', 'exec') NOT NULL,
+    ownerId INT NOT NULL,
+    kind ENUM('photo', 'document') NOT NULL DEFAULT 'document',
+    fileName VARCHAR(255) NOT NULL,
+    uploadedBy INT NULL,
+    createdAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX idx_attachments_owner (ownerType, ownerId, kind),
+    INDEX idx_attachments_file (fileId),
+    CONSTRAINT fk_attachments_file FOREIGN KEY (fileId) REFERENCES files (id) ON DELETE RESTRICT,
+    CONSTRAINT fk_attachments_uploaded_by FOREIGN KEY (uploadedBy) REFERENCES execs (id) ON DELETE SET NULL
+);
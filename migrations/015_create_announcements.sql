@@ -0,0 +1,38 @@
+-- Объявления: аудитория — вся школа (school), класс (class, classId), все учителя (teachers)
+-- или выбранные учётные записи (execs, announcement_recipients). Видны с publishAt до expiresAt;
+-- с sendEmail рассылаются письмом после публикации, emailedAt — когда рассылка ушла
+CREATE TABLE announcements (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    audience ENUM('school', 'class', 'teachers', 'execs') NOT NULL,
+    classId INT NULL,
+    publishAt DATETIME NOT NULL,
+    expiresAt DATETIME NULL,
+    sendEmail BOOLEAN NOT NULL DEFAULT FALSE,
+    emailedAt DATETIME NULL,
+    createdBy INT NULL,
+    version INT NOT NULL DEFAULT 1,
+    updatedAt DATETIME NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    INDEX idx_announcements_publish (publishAt, expiresAt),
+    CONSTRAINT fk_announcements_class FOREIGN KEY (classId) REFERENCES classes (id) ON DELETE CASCADE,
+    CONSTRAINT fk_announcements_created_by FOREIGN KEY (createdBy) REFERENCES execs (id) ON DELETE SET NULL
+);
+
+CREATE TABLE announcement_recipients (
+    announcementId INT NOT NULL,
+    execId INT NOT NULL,
+    PRIMARY KEY (announcementId, execId),
+    CONSTRAINT fk_announcement_recipients_announcement FOREIGN KEY (announcementId) REFERENCES announcements (id) ON DELETE CASCADE,
+    CONSTRAINT fk_announcement_recipients_exec FOREIGN KEY (execId) REFERENCES execs (id) ON DELETE CASCADE
+);
+
+-- Отметки о прочтении: одна на учётную запись
+CREATE TABLE announcement_reads (
+    announcementId INT NOT NULL,
+    execId INT NOT NULL,
+    readAt DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (announcementId, execId),
+    CONSTRAINT fk_announcement_reads_announcement FOREIGN KEY (announcementId) REFERENCES announcements (id) ON DELETE CASCADE,
+    CONSTRAINT fk_announcement_reads_exec FOREIGN KEY (execId) REFERENCES execs (id) ON DELETE CASCADE
+);
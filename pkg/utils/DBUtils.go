@@ -8,60 +8,40 @@ import (
 )
 
 // addSorting — добавляет ORDER BY в запрос из параметров ?sortBy=field:asc
-func AddSorting(r *http.Request, query string) string {
-	sortParams := r.URL.Query()["sortBy"]
-	if len(sortParams) > 0 {
-		query += " ORDER BY"
-		for i, param := range sortParams {
-			parts := strings.Split(param, ":")
-			if len(parts) != 2 {
-				continue
-			}
-			field, order := parts[0], parts[1]
-			if !IsValidSortOrder(order) || !IsValidSortField(field) {
-				continue
-			}
-			if i > 0 {
-				query += ","
-			}
-			query += fmt.Sprintf(" %s %s", field, order)
+func AddSorting(r *http.Request, model interface{}, query string) (string, error) {
+	sortFields, err := ParseSortBy(r, model)
+	if err != nil {
+		return "", err
+	}
+	if len(sortFields) > 0 {
+		var order []string
+		for _, s := range sortFields {
+			order = append(order, s.Field+" "+sortDirection(s.Desc, false))
 		}
+		query += " ORDER BY " + strings.Join(order, ", ")
 	}
-	return query
+	return query, nil
 }
 
 func IsValidSortOrder(sort string) bool {
 	return sort == "asc" || sort == "desc"
 }
 
-func IsValidSortField(field string) bool {
-	validFields := map[string]bool{
-		"firstName": true,
-		"lastName":  true,
-		"email":     true,
-		"class":     true,
-		"subject":   true,
+// getSortFields — поля модели с тегом sort:"true": имя из json → колонка из db
+func getSortFields(model interface{}) map[string]string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	return validFields[field]
-}
-
-// addFilters — добавляет WHERE фильтры по параметрам
-func AddFilters(r *http.Request, query string, args []interface{}) (string, []interface{}) {
-	params := map[string]string{
-		"firstName": "firstName",
-		"lastName":  "lastName",
-		"email":     "email",
-		"class":     "class",
-		"subject":   "subject",
-	}
-	for k, dbField := range params {
-		value := r.URL.Query().Get(k)
-		if value != "" {
-			query += " AND " + dbField + " = ?"
-			args = append(args, value)
+	fields := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if t.Field(i).Tag.Get("sort") != "true" || dbTag == "" {
+			continue
 		}
+		fields[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = dbTag
 	}
-	return query, args
+	return fields
 }
 
 // getDBFields — получает список db тегов без id (только для insert/update)
@@ -80,22 +60,53 @@ func getDBFields(model interface{}) []string {
 	return fields
 }
 
+// tableNames — таблицы, имена которых не выводятся из имени типа
+var tableNames = map[string]string{
+	"execdto":      "execs",
+	"attendance":   "attendance",
+	"academicyear": "academic_years",
+	"homework":     "homework",
+}
+
+// TableName — имя таблицы модели: имя типа во множественном числе (Student → students, Class → classes)
+func TableName(model interface{}) string {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name := strings.ToLower(t.Name())
+	if table, ok := tableNames[name]; ok {
+		return table
+	}
+	if strings.HasSuffix(name, "s") {
+		return name + "es"
+	}
+	return name + "s"
+}
+
 // GenerateSQL — универсальный генератор SQL запросов по модели и типу запроса
 func GenerateSQL(model interface{}, queryType string) string {
 	t := reflect.TypeOf(model)
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-	tableName := strings.ToLower(t.Name()) + "s"
-	if tableName == "execdtos" {
-		tableName = "execs"
-	}
+	tableName := TableName(model)
 
-	var fields []string
+	var fields, writable []string
+	versioned, softDelete := false, false
 	for i := 0; i < t.NumField(); i++ {
 		dbTag := t.Field(i).Tag.Get("db")
 		if dbTag != "" && dbTag != "-" {
 			fields = append(fields, dbTag)
+			if !isReadOnly(t.Field(i)) {
+				writable = append(writable, dbTag)
+			}
+			if dbTag == "version" {
+				versioned = true
+			}
+			if dbTag == "deletedAt" {
+				softDelete = true
+			}
 		}
 	}
 
@@ -103,37 +114,75 @@ func GenerateSQL(model interface{}, queryType string) string {
 		return ""
 	}
 
+	// для моделей с deletedAt удалённые записи не видны select/update, а delete только помечает запись
+	alive := ""
+	if softDelete {
+		alive = " AND deletedAt IS NULL"
+	}
+
 	switch strings.ToLower(queryType) {
 	case "insert":
-		placeholders := strings.Repeat("?, ", len(fields))
+		placeholders := strings.Repeat("?, ", len(writable))
 		placeholders = strings.TrimSuffix(placeholders, ", ")
 		return "INSERT " + "INTO " + tableName +
-			" (" + strings.Join(fields, ", ") + ")" +
+			" (" + strings.Join(writable, ", ") + ")" +
 			" VALUES (" + placeholders + ")"
 
 	case "update":
+		// для моделей с version: UPDATE ... WHERE id = ? AND version = ? — оптимистичная блокировка
 		var setParts []string
-		for _, f := range fields {
+		for _, f := range writable {
 			if f != "id" {
 				setParts = append(setParts, f+" = ?")
 			}
 		}
+		if versioned {
+			return "UPDATE " + tableName +
+				" SET " + strings.Join(setParts, ", ") + ", version = version + 1" +
+				" WHERE id = ? AND version = ?" + alive
+		}
 		return "UPDATE " + tableName +
 			" SET " + strings.Join(setParts, ", ") +
-			" WHERE id = ?"
+			" WHERE id = ?" + alive
 
 	case "delete":
 		fmt.Println("Deleting")
+		if softDelete {
+			return "UPDATE " + tableName + " SET deletedAt = NOW()" + versionBump(versioned) + " WHERE id = ?" + alive
+		}
+		return "DELETE " + "FROM " + tableName + " WHERE id = ?"
+
+	case "restore":
+		return "UPDATE " + tableName + " SET deletedAt = NULL" + versionBump(versioned) + " WHERE id = ? AND deletedAt IS NOT NULL"
+
+	case "purge":
 		return "DELETE " + "FROM " + tableName + " WHERE id = ?"
 
 	case "select":
 		return "SELECT " + strings.Join(fields, ", ") +
-			" FROM " + tableName + " WHERE id = ?"
+			" FROM " + tableName + " WHERE id = ?" + alive
 	default:
 		return ""
 	}
 }
 
+func versionBump(versioned bool) string {
+	if versioned {
+		return ", version = version + 1"
+	}
+	return ""
+}
+
+// NotDeleted — условие, скрывающее мягко удалённые записи, для запросов, собранных вручную
+func NotDeleted(model interface{}) string {
+	for _, f := range getDBFields(model) {
+		if f == "deletedAt" {
+			return " AND deletedAt IS NULL"
+		}
+	}
+	return ""
+}
+
 // GetStructFields — получает слайс интерфейсов для Scan или Exec по модели
 // includeID — включать ли id поле
 // forScan — возвращать адреса для Scan (true) или значения для Exec (false)
@@ -159,6 +208,9 @@ func GetStructFields(model interface{}, includeID bool, forScan bool) []interfac
 		if dbTag == "id" && !includeID {
 			continue
 		}
+		if !forScan && isReadOnly(t.Field(i)) {
+			continue
+		}
 
 		fieldVal := v.Field(i)
 		if forScan {
@@ -169,3 +221,8 @@ func GetStructFields(model interface{}, includeID bool, forScan bool) []interfac
 	}
 	return fields
 }
+
+// isReadOnly — поле заполняется базой (version, updatedAt) и не пишется из запроса
+func isReadOnly(field reflect.StructField) bool {
+	return field.Tag.Get("readonly") == "true"
+}
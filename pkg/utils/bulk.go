@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+)
+
+// BulkItemResult — результат обработки одного элемента bulk-запроса в режиме mode=partial
+type BulkItemResult struct {
+	Index  int          `json:"index"`
+	ID     int          `json:"id,omitempty"`
+	Status int          `json:"status"`
+	Code   string       `json:"code,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// BulkSuccess — успешно обработанный элемент
+func BulkSuccess(index, id, status int) BulkItemResult {
+	return BulkItemResult{Index: index, ID: id, Status: status}
+}
+
+// BulkFailure — элемент с ошибкой: статус и код определяются по типу ошибки
+func BulkFailure(index, id int, err error) BulkItemResult {
+	status, code := ErrorStatus(err, http.StatusInternalServerError)
+	res := BulkItemResult{Index: index, ID: id, Status: status, Code: code, Error: err.Error()}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		res.Error = "Validation failed"
+		res.Errors = validationErr.Errors
+	}
+	return res
+}
+
+// IsPartialMode — ?mode=partial включает независимую обработку элементов; по умолчанию всё в одной транзакции
+func IsPartialMode(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("mode") {
+	case "", "atomic":
+		return false, nil
+	case "partial":
+		return true, nil
+	}
+	return false, ErrorHandler(errors.New("invalid mode"), "mode must be atomic or partial")
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ColumnMapping — соответствие столбцов файла полям модели (json имена); пустое поле — столбец пропускается
+type ColumnMapping struct {
+	Fields   []string          `json:"-"`
+	Mapping  map[string]string `json:"mapping"`
+	Unmapped []string          `json:"unmapped"`
+}
+
+// MapColumns — сопоставляет заголовки файла полям модели.
+// Заголовки сравниваются с json и db тегами без учёта регистра, пробелов и подчёркиваний
+// ("First Name" -> firstName). Явный mapping переопределяет автоматическое сопоставление;
+// пустое значение или "-" в нём означает «пропустить столбец».
+func MapColumns(header []string, model interface{}, mapping map[string]string) (ColumnMapping, error) {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	byName := make(map[string]string)
+	writable := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if jsonName == "" || jsonName == "-" || jsonName == "id" || isReadOnly(f) {
+			continue
+		}
+		writable[jsonName] = true
+		byName[normalizeColumn(jsonName)] = jsonName
+		if db := f.Tag.Get("db"); db != "" {
+			byName[normalizeColumn(db)] = jsonName
+		}
+	}
+
+	result := ColumnMapping{
+		Fields:   make([]string, len(header)),
+		Mapping:  make(map[string]string),
+		Unmapped: []string{},
+	}
+	for col := range mapping {
+		found := false
+		for _, h := range header {
+			if strings.TrimSpace(h) == col {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return result, ErrorHandler(ErrInvalidFields, "Mapped column "+col+" is not in the file")
+		}
+	}
+
+	used := make(map[string]string)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		field, explicit := mapping[h]
+		if explicit {
+			if field == "-" {
+				field = ""
+			}
+			if field != "" && !writable[field] {
+				return result, ErrorHandler(ErrInvalidFields, "Column "+h+" is mapped to unknown field "+field)
+			}
+		} else {
+			field = byName[normalizeColumn(h)]
+		}
+
+		if field == "" {
+			if h != "" {
+				result.Unmapped = append(result.Unmapped, h)
+			}
+			continue
+		}
+		if prev, ok := used[field]; ok {
+			return result, ErrorHandler(ErrInvalidFields, "Columns "+prev+" and "+h+" both map to "+field)
+		}
+		used[field] = h
+		result.Fields[i] = field
+		result.Mapping[h] = field
+	}
+	return result, nil
+}
+
+// HasField — поле модели сопоставлено какому-либо столбцу
+func (m ColumnMapping) HasField(field string) bool {
+	for _, f := range m.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// CellValues — значения ячеек строки, приведённые к типам полей модели (целые, дробные, булевы, указатели на них),
+// чтобы их можно было применить как merge patch. Нераспознанные значения возвращаются ошибками валидации полей
+func CellValues(model interface{}, values map[string]string) (map[string]interface{}, error) {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	kinds := make(map[string]reflect.Kind)
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i).Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		kinds[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = ft.Kind()
+	}
+
+	typed := make(map[string]interface{}, len(values))
+	var errs []FieldError
+	for field, v := range values {
+		switch kinds[field] {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				// Excel может сохранить целое как 12.0
+				f, ferr := strconv.ParseFloat(v, 64)
+				if ferr != nil || f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+					errs = append(errs, FieldError{Field: field, Message: "must be an integer, got " + strconv.Quote(v)})
+					continue
+				}
+				n = int64(f)
+			}
+			typed[field] = n
+		case reflect.Float32, reflect.Float64:
+			f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+			if err != nil {
+				errs = append(errs, FieldError{Field: field, Message: "must be a number, got " + strconv.Quote(v)})
+				continue
+			}
+			typed[field] = f
+		case reflect.Bool:
+			b, err := strconv.ParseBool(strings.ToLower(v))
+			if err != nil {
+				errs = append(errs, FieldError{Field: field, Message: "must be true or false, got " + strconv.Quote(v)})
+				continue
+			}
+			typed[field] = b
+		default:
+			typed[field] = v
+		}
+	}
+	if len(errs) > 0 {
+		return nil, &ValidationError{Errors: errs}
+	}
+	return typed, nil
+}
+
+func normalizeColumn(name string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
@@ -1,13 +1,26 @@
 package utils
 
 import (
-	"fmt"
 	"log"
 	"os"
 )
 
+// appError — сообщение для клиента; исходная ошибка доступна через errors.Is/As, но наружу не выводится
+type appError struct {
+	message string
+	cause   error
+}
+
+func (e *appError) Error() string {
+	return e.message
+}
+
+func (e *appError) Unwrap() error {
+	return e.cause
+}
+
 func ErrorHandler(err error, message string) error {
 	errLogger := log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
 	errLogger.Println(message, err)
-	return fmt.Errorf(message)
+	return &appError{message: message, cause: err}
 }
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Ошибки-признаки: оборачиваются через ErrorHandler, статус ответа по ним выбирает ErrorStatus
+var (
+	// ErrInvalidPagination — неверные limit, cursor или sortBy
+	ErrInvalidPagination = errors.New("invalid pagination parameters")
+	// ErrInvalidFilter — фильтр по полю или оператору, которые модель не разрешает
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrInvalidFields — в ?fields есть неизвестное поле
+	ErrInvalidFields = errors.New("invalid fields")
+	// ErrPreconditionFailed — If-Match не совпал с текущей версией записи
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrPreconditionRequired — запрос изменяет запись без обязательного If-Match
+	ErrPreconditionRequired = errors.New("precondition required")
+	// ErrInvalidPatch — тело PATCH не является корректным merge patch или JSON Patch
+	ErrInvalidPatch = errors.New("invalid patch document")
+	// ErrPatchTestFailed — операция test из JSON Patch не прошла
+	ErrPatchTestFailed = errors.New("patch test operation failed")
+	// ErrUnsupportedMediaType — Content-Type тела PATCH не поддерживается
+	ErrUnsupportedMediaType = errors.New("unsupported patch media type")
+	// ErrUnsupportedFile — файл импорта не CSV и не XLSX
+	ErrUnsupportedFile = errors.New("unsupported spreadsheet file")
+	// ErrFileTooLarge — загружаемый файл больше допустимого размера
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrInvalidExportFormat — неизвестный ?format выгрузки
+	ErrInvalidExportFormat = errors.New("invalid export format")
+	// ErrInvalidAsOf — ?asOf не является датой или моментом времени
+	ErrInvalidAsOf = errors.New("invalid asOf")
+	// ErrCapacityExceeded — в классе (группе) не осталось мест
+	ErrCapacityExceeded = errors.New("capacity exceeded")
+	// ErrInUse — запись нельзя удалить, пока на неё ссылаются другие
+	ErrInUse = errors.New("record is in use")
+	// ErrScheduleConflict — учитель, кабинет или класс уже заняты в это время
+	ErrScheduleConflict = errors.New("schedule conflict")
+	// ErrForbidden — пользователь вошёл, но не имеет доступа к конкретной записи (например, учитель к чужому классу)
+	ErrForbidden = errors.New("forbidden")
+)
+
+// errorKinds — HTTP-статус и машинный код для известных ошибок
+var errorKinds = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{sql.ErrNoRows, http.StatusNotFound, "not_found"},
+	{ErrInvalidPagination, http.StatusBadRequest, "invalid_pagination"},
+	{ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+	{ErrInvalidFields, http.StatusBadRequest, "invalid_fields"},
+	{ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{ErrPreconditionRequired, http.StatusPreconditionRequired, "precondition_required"},
+	{ErrInvalidPatch, http.StatusBadRequest, "invalid_patch"},
+	{ErrPatchTestFailed, http.StatusConflict, "patch_test_failed"},
+	{ErrUnsupportedMediaType, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{ErrUnsupportedFile, http.StatusBadRequest, "unsupported_file"},
+	{ErrFileTooLarge, http.StatusRequestEntityTooLarge, "file_too_large"},
+	{ErrInvalidExportFormat, http.StatusBadRequest, "invalid_format"},
+	{ErrInvalidAsOf, http.StatusBadRequest, "invalid_as_of"},
+	{ErrCapacityExceeded, http.StatusConflict, "capacity_exceeded"},
+	{ErrInUse, http.StatusConflict, "in_use"},
+	{ErrScheduleConflict, http.StatusConflict, "schedule_conflict"},
+	{ErrForbidden, http.StatusForbidden, "forbidden"},
+}
+
+// ErrorStatus — HTTP-статус и код ошибки; fallback используется для неизвестных ошибок
+func ErrorStatus(err error, fallback int) (int, string) {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest, "validation_failed"
+	}
+	for _, k := range errorKinds {
+		if errors.Is(err, k.err) {
+			return k.status, k.code
+		}
+	}
+	return fallback, strings.ReplaceAll(strings.ToLower(http.StatusText(fallback)), " ", "_")
+}
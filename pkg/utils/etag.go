@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ETag — значение заголовка ETag для версии записи
+func ETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// IfMatchVersion — ожидаемая версия из заголовка If-Match; 0 если заголовка нет или If-Match: *
+func IfMatchVersion(r *http.Request) (int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return 0, ErrorHandler(ErrPreconditionFailed, "If-Match does not match current version")
+	}
+	return version, nil
+}
+
+// RequireIfMatchVersion — как IfMatchVersion, но без заголовка If-Match отвечает 428: перезаписать запись
+// без проверки версии можно только явным If-Match: *
+func RequireIfMatchVersion(r *http.Request) (int, error) {
+	if strings.TrimSpace(r.Header.Get("If-Match")) == "" {
+		return 0, ErrorHandler(ErrPreconditionRequired, "If-Match header is required: send the ETag of the record, or * to overwrite")
+	}
+	return IfMatchVersion(r)
+}
+
+// CheckVersion — сравнивает ожидаемую версию с текущей; expected = 0 означает «без проверки»
+func CheckVersion(expected, current int) error {
+	if expected != 0 && expected != current {
+		return ErrorHandler(ErrPreconditionFailed, "Record was modified by another request, current version is "+strconv.Itoa(current))
+	}
+	return nil
+}
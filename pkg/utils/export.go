@@ -0,0 +1,332 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const ContentTypeNDJSON = "application/x-ndjson"
+
+// ExportColumn — колонка выгрузки: db имя для SELECT и json имя для заголовка
+type ExportColumn struct {
+	DB   string
+	Name string
+}
+
+// ExportColumns — колонки выгрузки из ?fields (или все поля модели) без полей с тегом export:"-"
+func ExportColumns(r *http.Request, model interface{}) ([]ExportColumn, error) {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	fields, err := ParseFields(r, model)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool)
+	for _, f := range fields {
+		wanted[f] = true
+	}
+
+	var columns []ExportColumn
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		dbTag := f.Tag.Get("db")
+		if dbTag == "" || dbTag == "-" {
+			continue
+		}
+		if f.Tag.Get("export") == "-" {
+			if wanted[dbTag] {
+				return nil, ErrorHandler(ErrInvalidFields, "Field "+dbTag+" cannot be exported")
+			}
+			continue
+		}
+		if len(fields) > 0 && dbTag != "id" && !wanted[dbTag] {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = dbTag
+		}
+		columns = append(columns, ExportColumn{DB: dbTag, Name: name})
+	}
+	return columns, nil
+}
+
+// ExportValues — значения колонок записи: sql.Null* и nil-указатели превращаются в nil
+func ExportValues(model interface{}, columns []ExportColumn) []interface{} {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	t := v.Type()
+
+	byColumn := make(map[string]reflect.Value)
+	for i := 0; i < t.NumField(); i++ {
+		byColumn[t.Field(i).Tag.Get("db")] = v.Field(i)
+	}
+
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		values[i] = exportValue(byColumn[c.DB])
+	}
+	return values
+}
+
+func exportValue(v reflect.Value) interface{} {
+	switch val := v.Interface().(type) {
+	case sql.NullString:
+		if !val.Valid {
+			return nil
+		}
+		return val.String
+	case sql.NullInt64:
+		if !val.Valid {
+			return nil
+		}
+		return val.Int64
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+// ExportWriter — потоковая запись строк выгрузки в выбранном формате
+type ExportWriter interface {
+	WriteHeader(columns []ExportColumn) error
+	WriteRow(values []interface{}) error
+	// Flush — отправляет накопленные строки клиенту, не дожидаясь конца выгрузки
+	Flush() error
+	Close() error
+}
+
+// ExportFormat — Content-Type и расширение файла для ?format=csv|ndjson|xlsx
+func ExportFormat(format string) (contentType, ext string, err error) {
+	switch format {
+	case "", "csv":
+		return ContentTypeCSV + "; charset=utf-8", "csv", nil
+	case "ndjson":
+		return ContentTypeNDJSON, "ndjson", nil
+	case "xlsx":
+		return ContentTypeXLSX, "xlsx", nil
+	}
+	return "", "", ErrorHandler(ErrInvalidExportFormat, "Unsupported format "+format+", use csv, ndjson or xlsx")
+}
+
+// NewExportWriter — писатель для формата, уже проверенного через ExportFormat
+func NewExportWriter(format string, w io.Writer) ExportWriter {
+	switch format {
+	case "ndjson":
+		return &ndjsonExportWriter{out: w, buf: bufio.NewWriter(w)}
+	case "xlsx":
+		return &xlsxExportWriter{out: w, zip: zip.NewWriter(w)}
+	}
+	return &csvExportWriter{out: w, csv: csv.NewWriter(w)}
+}
+
+// flushHTTP — проталкивает данные клиенту, если под писателем http.ResponseWriter
+func flushHTTP(w io.Writer) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func exportString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+type csvExportWriter struct {
+	out io.Writer
+	csv *csv.Writer
+}
+
+func (c *csvExportWriter) WriteHeader(columns []ExportColumn) error {
+	header := make([]string, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	return c.csv.Write(header)
+}
+
+func (c *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = exportString(v)
+	}
+	return c.csv.Write(record)
+}
+
+func (c *csvExportWriter) Flush() error {
+	c.csv.Flush()
+	flushHTTP(c.out)
+	return c.csv.Error()
+}
+
+func (c *csvExportWriter) Close() error {
+	return c.Flush()
+}
+
+type ndjsonExportWriter struct {
+	out     io.Writer
+	buf     *bufio.Writer
+	columns []ExportColumn
+}
+
+func (n *ndjsonExportWriter) WriteHeader(columns []ExportColumn) error {
+	n.columns = columns
+	return nil
+}
+
+// WriteRow — одна строка = один JSON-объект; ключи в порядке колонок
+func (n *ndjsonExportWriter) WriteRow(values []interface{}) error {
+	n.buf.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(n.columns[i].Name)
+		val, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.buf.Write(key)
+		n.buf.WriteByte(':')
+		n.buf.Write(val)
+	}
+	_, err := n.buf.WriteString("}\n")
+	return err
+}
+
+func (n *ndjsonExportWriter) Flush() error {
+	err := n.buf.Flush()
+	flushHTTP(n.out)
+	return err
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return n.Flush()
+}
+
+// xlsxExportWriter — лист пишется в zip по мере поступления строк (inline-строки, без sharedStrings),
+// остальные части книги добавляются при Close
+type xlsxExportWriter struct {
+	out   io.Writer
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+func (x *xlsxExportWriter) WriteHeader(columns []ExportColumn) error {
+	sheet, err := x.zip.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = sheet
+	_, err = io.WriteString(x.sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, len(columns))
+	for i, col := range columns {
+		header[i] = col.Name
+	}
+	return x.WriteRow(header)
+}
+
+func (x *xlsxExportWriter) WriteRow(values []interface{}) error {
+	x.row++
+	var sb strings.Builder
+	sb.WriteString(`<row r="` + strconv.Itoa(x.row) + `">`)
+	for i, v := range values {
+		ref := xlsxColumnName(i) + strconv.Itoa(x.row)
+		switch val := v.(type) {
+		case nil:
+			continue
+		case int, int64:
+			sb.WriteString(`<c r="` + ref + `"><v>` + fmt.Sprint(val) + `</v></c>`)
+		case bool:
+			b := "0"
+			if val {
+				b = "1"
+			}
+			sb.WriteString(`<c r="` + ref + `" t="b"><v>` + b + `</v></c>`)
+		default:
+			sb.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&sb, []byte(exportString(val)))
+			sb.WriteString(`</t></is></c>`)
+		}
+	}
+	sb.WriteString(`</row>`)
+	_, err := io.WriteString(x.sheet, sb.String())
+	return err
+}
+
+func (x *xlsxExportWriter) Flush() error {
+	err := x.zip.Flush()
+	flushHTTP(x.out)
+	return err
+}
+
+func (x *xlsxExportWriter) Close() error {
+	_, err := io.WriteString(x.sheet, `</sheetData></worksheet>`)
+	if err != nil {
+		return err
+	}
+	parts := []struct{ name, body string }{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+	}
+	for _, p := range parts {
+		f, err := x.zip.Create(p.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, xml.Header+p.body)
+		if err != nil {
+			return err
+		}
+	}
+	err = x.zip.Close()
+	flushHTTP(x.out)
+	return err
+}
+
+// xlsxColumnName — буквенное имя столбца: 0 -> A, 26 -> AA
+func xlsxColumnName(i int) string {
+	name := ""
+	for i >= 0 {
+		name = string(rune('A'+i%26)) + name
+		i = i/26 - 1
+	}
+	return name
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ParseFields — разбирает ?fields=firstName,lastName и проверяет поля по db тегам модели
+// Пустой результат означает «все поля»
+func ParseFields(r *http.Request, model interface{}) ([]string, error) {
+	param := r.URL.Query().Get("fields")
+	if param == "" {
+		return nil, nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, f := range getDBFields(model) {
+		allowed[f] = true
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, f := range strings.Split(param, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || f == "id" || seen[f] {
+			continue
+		}
+		if !allowed[f] {
+			return nil, ErrorHandler(ErrInvalidFields, "Unknown field "+f)
+		}
+		seen[f] = true
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// ParseInclude — разбирает ?include=students и проверяет по списку допустимых связей
+func ParseInclude(r *http.Request, allowed ...string) (map[string]bool, error) {
+	include := make(map[string]bool)
+	param := r.URL.Query().Get("include")
+	if param == "" {
+		return include, nil
+	}
+	for _, rel := range strings.Split(param, ",") {
+		rel = strings.TrimSpace(rel)
+		ok := false
+		for _, a := range allowed {
+			if a == rel {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, ErrorHandler(ErrInvalidFields, "Cannot include "+rel)
+		}
+		include[rel] = true
+	}
+	return include, nil
+}
+
+// SelectColumns — список колонок для SELECT: id и запрошенные поля (или все db поля модели)
+func SelectColumns(model interface{}, fields []string) []string {
+	if len(fields) == 0 {
+		return append([]string{"id"}, getDBFields(model)...)
+	}
+	columns := []string{"id"}
+	seen := map[string]bool{"id": true}
+	for _, f := range fields {
+		if !seen[f] {
+			seen[f] = true
+			columns = append(columns, f)
+		}
+	}
+	return columns
+}
+
+// GetScanFields — адреса полей модели для Scan в порядке указанных колонок
+func GetScanFields(model interface{}, columns []string) []interface{} {
+	v := reflect.ValueOf(model).Elem()
+	t := v.Type()
+
+	byColumn := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if dbTag != "" && dbTag != "-" {
+			byColumn[dbTag] = v.Field(i).Addr().Interface()
+		}
+	}
+
+	fields := make([]interface{}, 0, len(columns))
+	for _, c := range columns {
+		fields = append(fields, byColumn[c])
+	}
+	return fields
+}
+
+// ProjectFields — оставляет в ответе только id и запрошенные поля, ключи — json теги
+func ProjectFields(model interface{}, fields []string) map[string]interface{} {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	t := v.Type()
+
+	wanted := map[string]bool{"id": true}
+	for _, f := range fields {
+		wanted[f] = true
+	}
+
+	projected := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if len(fields) > 0 && !wanted[dbTag] {
+			continue
+		}
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		projected[name] = v.Field(i).Interface()
+	}
+	return projected
+}
+
+// QueryColumns — колонки для списка: запрошенные ?fields, поля сортировки и extra, если они есть в модели
+func QueryColumns(r *http.Request, model interface{}, extra ...string) ([]string, error) {
+	fields, err := ParseFields(r, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return SelectColumns(model, nil), nil
+	}
+
+	known := make(map[string]bool)
+	for _, f := range getDBFields(model) {
+		known[f] = true
+	}
+	sortFields, err := ParseSortBy(r, model)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sortFields {
+		if known[s.Field] {
+			fields = append(fields, s.Field)
+		}
+	}
+	for _, f := range extra {
+		if known[f] {
+			fields = append(fields, f)
+		}
+	}
+	return SelectColumns(model, fields), nil
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// filterOperators — SQL-шаблоны операторов фильтра ?field[op]=value
+var filterOperators = map[string]string{
+	"eq":    " = ?",
+	"ne":    " <> ?",
+	"gt":    " > ?",
+	"gte":   " >= ?",
+	"lt":    " < ?",
+	"lte":   " <= ?",
+	"like":  " LIKE ?",
+	"nlike": " NOT LIKE ?",
+	"in":    " IN ",
+	"nin":   " NOT IN ",
+	"null":  "",
+}
+
+var filterParamRegexp = regexp.MustCompile(`^(\w+)\[(\w+)\]$`)
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+
+// filterField — поле модели, доступное для фильтрации, и разрешённые операторы из тега filter:"eq,like,in"
+type filterField struct {
+	column    string
+	operators map[string]bool
+}
+
+// addFilters — добавляет WHERE фильтры по параметрам
+// Поддерживаются field=value и field[op]=value, набор полей и операторов задаётся тегом filter в модели
+func AddFilters(r *http.Request, model interface{}, query string, args []interface{}) (string, []interface{}, error) {
+	fields := getFilterFields(model)
+	params := r.URL.Query()
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name, op := k, "eq"
+		if m := filterParamRegexp.FindStringSubmatch(k); m != nil {
+			name, op = m[1], m[2]
+		}
+
+		field, ok := fields[name]
+		if !ok {
+			if name != k {
+				return "", nil, ErrorHandler(ErrInvalidFilter, "Filtering by "+name+" is not allowed")
+			}
+			continue
+		}
+		if _, known := filterOperators[op]; !known || !field.operators[op] {
+			return "", nil, ErrorHandler(ErrInvalidFilter, "Operator "+op+" is not allowed for "+name)
+		}
+
+		for _, value := range params[k] {
+			switch op {
+			case "null":
+				switch value {
+				case "true":
+					query += " AND " + field.column + " IS NULL"
+				case "false":
+					query += " AND " + field.column + " IS NOT NULL"
+				default:
+					return "", nil, ErrorHandler(ErrInvalidFilter, "Value of "+k+" must be true or false")
+				}
+			case "in", "nin":
+				values := strings.Split(value, ",")
+				placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+				query += " AND " + field.column + filterOperators[op] + "(" + placeholders + ")"
+				for _, v := range values {
+					args = append(args, strings.TrimSpace(v))
+				}
+			case "like", "nlike":
+				query += " AND " + field.column + filterOperators[op]
+				args = append(args, likeEscaper.Replace(value))
+			default:
+				if value == "" {
+					continue
+				}
+				query += " AND " + field.column + filterOperators[op]
+				args = append(args, value)
+			}
+		}
+	}
+	return query, args, nil
+}
+
+// getFilterFields — поля модели с тегом filter, ключ — имя параметра (json тег)
+func getFilterFields(model interface{}) map[string]filterField {
+	t := reflect.TypeOf(model)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	fields := make(map[string]filterField)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("filter")
+		dbTag := t.Field(i).Tag.Get("db")
+		if tag == "" || tag == "-" || dbTag == "" {
+			continue
+		}
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		ops := make(map[string]bool)
+		for _, op := range strings.Split(tag, ",") {
+			ops[op] = true
+		}
+		fields[name] = filterField{column: dbTag, operators: ops}
+	}
+	return fields
+}
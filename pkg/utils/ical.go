@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ICalEvent — VEVENT календаря. Время «плавающее» (без часового пояса): урок в 08:30 остаётся в 08:30 у любого клиента
+type ICalEvent struct {
+	UID          string
+	Summary      string
+	Location     string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	RRule        string
+	ExDates      []time.Time
+	RecurrenceID *time.Time
+	Status       string
+}
+
+const (
+	icalDateTime = "20060102T150405"
+	icalLineMax  = 75
+)
+
+// ICalWeekday — день недели ISO (1 — понедельник) в формате BYDAY
+func ICalWeekday(isoDay int) string {
+	return [...]string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}[(isoDay+6)%7]
+}
+
+// ICalDateTime — DATE-TIME без часового пояса
+func ICalDateTime(t time.Time) string {
+	return t.Format(icalDateTime)
+}
+
+// WriteICal — календарь по RFC 5545: строки CRLF, перенос длинных строк по 75 октетов, экранирование текста
+func WriteICal(w io.Writer, name string, events []ICalEvent) error {
+	bw := bufio.NewWriter(w)
+	line := func(s string) {
+		writeFolded(bw, s)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//WebProject//Timetable//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icalText(name))
+
+	stamp := time.Now().UTC().Format(icalDateTime) + "Z"
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		if e.RecurrenceID != nil {
+			line("RECURRENCE-ID:" + ICalDateTime(*e.RecurrenceID))
+		}
+		line("DTSTART:" + ICalDateTime(e.Start))
+		line("DTEND:" + ICalDateTime(e.End))
+		if e.RRule != "" {
+			line("RRULE:" + e.RRule)
+		}
+		if len(e.ExDates) > 0 {
+			dates := make([]string, len(e.ExDates))
+			for i, d := range e.ExDates {
+				dates[i] = ICalDateTime(d)
+			}
+			line("EXDATE:" + strings.Join(dates, ","))
+		}
+		line("SUMMARY:" + icalText(e.Summary))
+		if e.Location != "" {
+			line("LOCATION:" + icalText(e.Location))
+		}
+		if e.Description != "" {
+			line("DESCRIPTION:" + icalText(e.Description))
+		}
+		if e.Status != "" {
+			line("STATUS:" + e.Status)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return bw.Flush()
+}
+
+// icalText — экранирование TEXT: обратный слэш, точка с запятой, запятая и перевод строки
+func icalText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeFolded — строка длиннее 75 октетов переносится CRLF с пробелом, не разрывая символы UTF-8
+func writeFolded(w *bufio.Writer, s string) {
+	limit := icalLineMax
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut])
+		w.WriteString("\r\n ")
+		s = s[cut:]
+		// продолжение начинается с пробела, который тоже занимает октет
+		limit = icalLineMax - 1
+	}
+	w.WriteString(s)
+	w.WriteString("\r\n")
+}
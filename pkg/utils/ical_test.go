@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func folded(s string) string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	writeFolded(w, s)
+	w.Flush()
+	return buf.String()
+}
+
+func TestWriteFolded(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"short line", "SUMMARY:Math", "SUMMARY:Math\r\n"},
+		{"exactly 75 octets", strings.Repeat("a", 75), strings.Repeat("a", 75) + "\r\n"},
+		{"76 octets", strings.Repeat("a", 76), strings.Repeat("a", 75) + "\r\n a\r\n"},
+		{
+			name: "continuation counts the leading space",
+			in:   strings.Repeat("a", 75+74+1),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 74) + "\r\n a\r\n",
+		},
+		{
+			// двухбайтовая «й» занимает октеты 75 и 76: перенос до неё
+			name: "does not split a UTF-8 character",
+			in:   strings.Repeat("a", 74) + "й",
+			want: strings.Repeat("a", 74) + "\r\n й\r\n",
+		},
+		{
+			name: "four-byte character at the boundary",
+			in:   strings.Repeat("a", 73) + "😀",
+			want: strings.Repeat("a", 73) + "\r\n 😀\r\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := folded(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteFoldedLongUTF8(t *testing.T) {
+	inputs := []string{
+		"DESCRIPTION:" + strings.Repeat("Домашнее задание ", 20),
+		"SUMMARY:" + strings.Repeat("ab😀", 40),
+		"LOCATION:" + strings.Repeat("я", 200),
+	}
+	for _, in := range inputs {
+		out := folded(in)
+		if !strings.HasSuffix(out, "\r\n") {
+			t.Fatalf("output does not end with CRLF: %q", out)
+		}
+		lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+		for i, l := range lines {
+			if len(l) > icalLineMax {
+				t.Errorf("line %d is %d octets: %q", i, len(l), l)
+			}
+			if !utf8.ValidString(l) {
+				t.Errorf("line %d splits a UTF-8 character: %q", i, l)
+			}
+			if i > 0 && !strings.HasPrefix(l, " ") {
+				t.Errorf("continuation line %d does not start with a space: %q", i, l)
+			}
+		}
+		if unfolded := strings.ReplaceAll(strings.TrimSuffix(out, "\r\n"), "\r\n ", ""); unfolded != in {
+			t.Errorf("unfolding does not restore the line:\n got %q\nwant %q", unfolded, in)
+		}
+	}
+}
+
+func TestICalText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Math", "Math"},
+		{`a\b`, `a\\b`},
+		{"a;b", `a\;b`},
+		{"a,b", `a\,b`},
+		{"line 1\nline 2", `line 1\nline 2`},
+		{"line 1\r\nline 2", `line 1\nline 2`},
+		{`room 1; floor 2, wing \A`, `room 1\; floor 2\, wing \\A`},
+		{"a:b", "a:b"},
+	}
+	for _, tt := range tests {
+		if got := icalText(tt.in); got != tt.want {
+			t.Errorf("icalText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestICalWeekday(t *testing.T) {
+	want := []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+	for i, w := range want {
+		if got := ICalWeekday(i + 1); got != w {
+			t.Errorf("ICalWeekday(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestWriteICal(t *testing.T) {
+	start := time.Date(2025, 1, 6, 8, 30, 0, 0, time.UTC)
+	moved := time.Date(2025, 1, 20, 8, 30, 0, 0, time.UTC)
+	events := []ICalEvent{
+		{
+			UID:      "lesson-1@webproject",
+			Summary:  "Math, algebra",
+			Location: "Room 101; 1st floor",
+			Start:    start,
+			End:      start.Add(45 * time.Minute),
+			RRule:    "FREQ=WEEKLY;BYDAY=" + ICalWeekday(1) + ";UNTIL=20250530T235959",
+			ExDates:  []time.Time{start.AddDate(0, 0, 7), start.AddDate(0, 0, 21)},
+		},
+		{
+			UID:          "lesson-1@webproject",
+			Summary:      "Math",
+			Description:  "Substitute teacher\nBring textbooks",
+			Start:        moved,
+			End:          moved.Add(45 * time.Minute),
+			RecurrenceID: &moved,
+			Status:       "CONFIRMED",
+		},
+	}
+
+	var buf bytes.Buffer
+	err := WriteICal(&buf, "Class 5A", events)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Fatalf("calendar is not wrapped in VCALENDAR:\n%s", out)
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Errorf("output contains bare LF line endings")
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+	has := func(line string) bool {
+		for _, l := range lines {
+			if l == line {
+				return true
+			}
+		}
+		return false
+	}
+	for _, want := range []string{
+		"X-WR-CALNAME:Class 5A",
+		"DTSTART:20250106T083000",
+		"DTEND:20250106T091500",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO;UNTIL=20250530T235959",
+		"EXDATE:20250113T083000,20250127T083000",
+		`SUMMARY:Math\, algebra`,
+		`LOCATION:Room 101\; 1st floor`,
+		"RECURRENCE-ID:20250120T083000",
+		`DESCRIPTION:Substitute teacher\nBring textbooks`,
+		"STATUS:CONFIRMED",
+	} {
+		if !has(want) {
+			t.Errorf("missing line %q in:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "BEGIN:VEVENT\r\n"); n != len(events) {
+		t.Errorf("got %d VEVENTs, want %d", n, len(events))
+	}
+}
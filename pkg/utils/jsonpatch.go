@@ -0,0 +1,378 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeJSON       = "application/json"
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJSONPatch  = "application/json-patch+json"
+)
+
+// Patch — тело PATCH-запроса вместе с его Content-Type
+type Patch struct {
+	ContentType string
+	Body        []byte
+}
+
+// PatchOperation — операция JSON Patch (RFC 6902)
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// NewPatch — определяет формат патча по Content-Type; пустой и application/json трактуются как merge patch
+func NewPatch(contentType string, body []byte) (Patch, error) {
+	mediaType := ContentTypeJSON
+	if contentType != "" {
+		mt, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return Patch{}, ErrorHandler(ErrUnsupportedMediaType, "Invalid Content-Type")
+		}
+		mediaType = mt
+	}
+	switch mediaType {
+	case ContentTypeJSON, ContentTypeMergePatch, ContentTypeJSONPatch:
+		return Patch{ContentType: mediaType, Body: body}, nil
+	}
+	return Patch{}, ErrorHandler(ErrUnsupportedMediaType, "Unsupported Content-Type "+mediaType+", use "+ContentTypeMergePatch+" or "+ContentTypeJSONPatch)
+}
+
+// IsJSONPatch — патч задан операциями RFC 6902, а не документом RFC 7396
+func (p Patch) IsJSONPatch() bool {
+	return p.ContentType == ContentTypeJSONPatch
+}
+
+// Apply — применяет патч к JSON-документу
+func (p Patch) Apply(doc []byte) ([]byte, error) {
+	if p.IsJSONPatch() {
+		return ApplyJSONPatch(doc, p.Body)
+	}
+	return MergePatch(doc, p.Body)
+}
+
+// MergePatch — JSON Merge Patch (RFC 7396): null удаляет поле, объекты сливаются рекурсивно
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, patchValue interface{}
+	if err := decodeJSON(doc, &target); err != nil {
+		return nil, ErrorHandler(err, "Invalid target document")
+	}
+	if err := decodeJSON(patch, &patchValue); err != nil {
+		return nil, ErrorHandler(ErrInvalidPatch, "Invalid merge patch: "+err.Error())
+	}
+	return json.Marshal(mergeValue(target, patchValue))
+}
+
+func mergeValue(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeValue(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// ApplyJSONPatch — JSON Patch (RFC 6902): add, remove, replace, move, copy, test
+// Операции применяются последовательно; при любой ошибке документ не меняется
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []PatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, ErrorHandler(ErrInvalidPatch, "JSON Patch must be an array of operations")
+	}
+	var root interface{}
+	if err := decodeJSON(doc, &root); err != nil {
+		return nil, ErrorHandler(err, "Invalid target document")
+	}
+
+	for i, op := range ops {
+		var err error
+		root, err = applyOperation(root, op)
+		if err != nil {
+			if errors.Is(err, ErrPatchTestFailed) {
+				return nil, ErrorHandler(err, fmt.Sprintf("Operation %d (test %s) failed", i, op.Path))
+			}
+			return nil, ErrorHandler(ErrInvalidPatch, fmt.Sprintf("Operation %d (%s %s): %s", i, op.Op, op.Path, err))
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOperation(root interface{}, op PatchOperation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, errors.New("value is required")
+		}
+		var value interface{}
+		if err := decodeJSON(op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case "add":
+			return pointerAdd(root, path, value)
+		case "replace":
+			if _, err := pointerGet(root, path); err != nil {
+				return nil, err
+			}
+			if root, err = pointerRemove(root, path); err != nil {
+				return nil, err
+			}
+			return pointerAdd(root, path, value)
+		default:
+			current, err := pointerGet(root, path)
+			if err != nil || !jsonEqual(current, value) {
+				return nil, ErrPatchTestFailed
+			}
+			return root, nil
+		}
+	case "remove":
+		return pointerRemove(root, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(root, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "move" {
+			if strings.HasPrefix(op.Path+"/", op.From+"/") && op.Path != op.From {
+				return nil, errors.New("cannot move a value into its own child")
+			}
+			if root, err = pointerRemove(root, from); err != nil {
+				return nil, err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+		return pointerAdd(root, path, value)
+	}
+	return nil, errors.New("unknown operation " + strconv.Quote(op.Op))
+}
+
+// parsePointer — разбирает JSON Pointer (RFC 6901)
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errors.New("path must start with /")
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func pointerGet(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			v, ok := n[token]
+			if !ok {
+				return nil, errors.New("path not found")
+			}
+			node = v
+		case []interface{}:
+			idx, err := arrayIndex(token, len(n)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = n[idx]
+		default:
+			return nil, errors.New("path not found")
+		}
+	}
+	return node, nil
+}
+
+func pointerAdd(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+		return root, nil
+	case []interface{}:
+		idx := len(p)
+		if last != "-" {
+			if idx, err = arrayIndex(last, len(p)); err != nil {
+				return nil, err
+			}
+		}
+		updated := append(p[:idx:idx], append([]interface{}{value}, p[idx:]...)...)
+		return replaceAt(root, path[:len(path)-1], updated)
+	}
+	return nil, errors.New("path not found")
+}
+
+func pointerRemove(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[last]; !ok {
+			return nil, errors.New("path not found")
+		}
+		delete(p, last)
+		return root, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated := append(p[:idx:idx], p[idx+1:]...)
+		return replaceAt(root, path[:len(path)-1], updated)
+	}
+	return nil, errors.New("path not found")
+}
+
+// replaceAt — подменяет массив по пути (append мог создать новый слайс)
+func replaceAt(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[last] = value
+	case []interface{}:
+		idx, err := arrayIndex(last, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+		p[idx] = value
+	}
+	return root, nil
+}
+
+func arrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || idx > max || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.New("invalid array index " + token)
+	}
+	return idx, nil
+}
+
+func decodeJSON(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	var av, bv interface{}
+	json.Unmarshal(ab, &av)
+	json.Unmarshal(bb, &bv)
+	return reflect.DeepEqual(av, bv)
+}
+
+func deepCopy(v interface{}) interface{} {
+	data, _ := json.Marshal(v)
+	var c interface{}
+	decodeJSON(data, &c)
+	return c
+}
+
+// DecodePatched — строго декодирует результат патча в модель и проверяет, что id и служебные поля не изменены
+// Несовпадение типов и неизвестные поля возвращаются как ошибки валидации конкретного поля
+func DecodePatched(data []byte, existing interface{}, target interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(target)
+	if err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return &ValidationError{Errors: []FieldError{{
+				Field:   typeErr.Field,
+				Message: "must be of type " + jsonTypeName(typeErr.Type) + ", got " + typeErr.Value,
+			}}}
+		}
+		if strings.HasPrefix(err.Error(), "json: unknown field ") {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return &ValidationError{Errors: []FieldError{{Field: field, Message: "is not a known field"}}}
+		}
+		return ErrorHandler(ErrInvalidPatch, "Patched document is not a valid object")
+	}
+
+	ev := reflect.ValueOf(existing)
+	tv := reflect.ValueOf(target).Elem()
+	t := tv.Type()
+	var errs []FieldError
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if dbTag != "id" && !isReadOnly(t.Field(i)) {
+			continue
+		}
+		if !reflect.DeepEqual(ev.Field(i).Interface(), tv.Field(i).Interface()) {
+			name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+			errs = append(errs, FieldError{Field: name, Message: "is read-only"})
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Ptr:
+		return jsonTypeName(t.Elem()) + " or null"
+	}
+	return t.Kind().String()
+}
@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"github.com/go-mail/mail/v2"
+)
+
+// SendMail — письмо через локальный SMTP (localhost:1025); каждый адресат получает отдельное письмо,
+// все письма уходят за одно соединение
+func SendMail(subject, body string, to ...string) error {
+	messages := make([]*mail.Message, 0, len(to))
+	for _, addr := range to {
+		m := mail.NewMessage()
+		m.SetHeader("From", "school.admin@example.com")
+		m.SetHeader("To", addr)
+		m.SetHeader("Subject", subject)
+		m.SetBody("text/html", body)
+		messages = append(messages, m)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	d := mail.NewDialer("localhost", 1025, "", "")
+	return d.DialAndSend(messages...)
+}
@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const defaultPageSize = 20
+
+// SortField — поле сортировки из параметра ?sortBy=field:asc
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// PageLinks — ссылки на соседние страницы, null если страницы нет
+type PageLinks struct {
+	Next *string `json:"next"`
+	Prev *string `json:"prev"`
+}
+
+// PageInfo — мета-данные страницы для ответа
+type PageInfo struct {
+	Limit int       `json:"limit"`
+	Total *int      `json:"total,omitempty"`
+	Links PageLinks `json:"links"`
+}
+
+// Pagination — разобранные параметры keyset-пагинации текущего запроса
+type Pagination struct {
+	Limit     int
+	Sort      []SortField
+	WithTotal bool
+	cursor    *pageCursor
+}
+
+// pageCursor — содержимое непрозрачного курсора: значения полей сортировки и id граничной строки
+type pageCursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	ID     int           `json:"id"`
+	Prev   bool          `json:"p,omitempty"`
+}
+
+// MaxPageSize — максимальный размер страницы, задаётся через MAX_PAGE_SIZE
+func MaxPageSize() int {
+	maxSize, err := strconv.Atoi(os.Getenv("MAX_PAGE_SIZE"))
+	if err != nil || maxSize <= 0 {
+		return 100
+	}
+	return maxSize
+}
+
+// ParseSortBy — разбирает ?sortBy=field:asc; сортировать можно только по полям модели с тегом sort
+func ParseSortBy(r *http.Request, model interface{}) ([]SortField, error) {
+	fields := getSortFields(model)
+	var sortFields []SortField
+	for _, param := range r.URL.Query()["sortBy"] {
+		parts := strings.Split(param, ":")
+		if len(parts) != 2 || !IsValidSortOrder(parts[1]) {
+			return nil, ErrorHandler(ErrInvalidPagination, "sortBy must look like field:asc or field:desc")
+		}
+		column, ok := fields[parts[0]]
+		if !ok {
+			return nil, ErrorHandler(ErrInvalidPagination, "Sorting by "+parts[0]+" is not allowed")
+		}
+		sortFields = append(sortFields, SortField{Field: column, Desc: parts[1] == "desc"})
+	}
+	return sortFields, nil
+}
+
+// AddPagination — добавляет условие по курсору, ORDER BY (с id для стабильности) и LIMIT
+// Параметры: ?limit=20&cursor=...&includeTotal=true
+func AddPagination(r *http.Request, model interface{}, query string, args []interface{}) (string, []interface{}, *Pagination, error) {
+	sortFields, err := ParseSortBy(r, model)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	params := r.URL.Query()
+	p := &Pagination{
+		Limit:     defaultPageSize,
+		Sort:      sortFields,
+		WithTotal: params.Get("includeTotal") == "true",
+	}
+
+	if l := params.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			return "", nil, nil, ErrorHandler(ErrInvalidPagination, "Invalid limit")
+		}
+		p.Limit = limit
+	}
+	if p.Limit > MaxPageSize() {
+		p.Limit = MaxPageSize()
+	}
+
+	if c := params.Get("cursor"); c != "" {
+		cur, err := decodeCursor(c)
+		if err != nil || cur.Sort != sortSignature(p.Sort) || len(cur.Values) != len(p.Sort) {
+			return "", nil, nil, ErrorHandler(ErrInvalidPagination, "Invalid cursor")
+		}
+		p.cursor = cur
+	}
+
+	backward := p.cursor != nil && p.cursor.Prev
+
+	if p.cursor != nil {
+		// (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND id > ?)
+		var or []string
+		for i := 0; i <= len(p.Sort); i++ {
+			var and []string
+			for j := 0; j < i; j++ {
+				and = append(and, p.Sort[j].Field+" = ?")
+				args = append(args, p.cursor.Values[j])
+			}
+			field, desc, value := "id", false, interface{}(p.cursor.ID)
+			if i < len(p.Sort) {
+				field, desc, value = p.Sort[i].Field, p.Sort[i].Desc, p.cursor.Values[i]
+			}
+			op := ">"
+			if desc != backward {
+				op = "<"
+			}
+			and = append(and, field+" "+op+" ?")
+			args = append(args, value)
+			or = append(or, "("+strings.Join(and, " AND ")+")")
+		}
+		query += " AND (" + strings.Join(or, " OR ") + ")"
+	}
+
+	var order []string
+	for _, s := range p.Sort {
+		order = append(order, s.Field+" "+sortDirection(s.Desc, backward))
+	}
+	order = append(order, "id "+sortDirection(false, backward))
+	query += " ORDER BY " + strings.Join(order, ", ")
+	query += " LIMIT " + strconv.Itoa(p.Limit+1)
+
+	return query, args, p, nil
+}
+
+// Paginate — обрезает лишнюю строку, восстанавливает порядок и строит ссылки next/prev
+func Paginate[T any](r *http.Request, p *Pagination, items []T) ([]T, PageInfo) {
+	hasMore := len(items) > p.Limit
+	if hasMore {
+		items = items[:p.Limit]
+	}
+
+	backward := p.cursor != nil && p.cursor.Prev
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	info := PageInfo{Limit: p.Limit}
+	if len(items) == 0 {
+		return items, info
+	}
+
+	hasNext := hasMore || backward
+	hasPrev := (backward && hasMore) || (!backward && p.cursor != nil)
+
+	if hasNext {
+		link := pageLink(r, p.cursorFor(items[len(items)-1], false))
+		info.Links.Next = &link
+	}
+	if hasPrev {
+		link := pageLink(r, p.cursorFor(items[0], true))
+		info.Links.Prev = &link
+	}
+	return items, info
+}
+
+func (p *Pagination) cursorFor(item interface{}, prev bool) string {
+	values := make(map[string]interface{})
+	v := reflect.ValueOf(item)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if dbTag != "" {
+			values[strings.ToLower(dbTag)] = v.Field(i).Interface()
+		}
+	}
+
+	cur := pageCursor{Sort: sortSignature(p.Sort), Prev: prev}
+	for _, s := range p.Sort {
+		cur.Values = append(cur.Values, values[strings.ToLower(s.Field)])
+	}
+	if id, ok := values["id"].(int); ok {
+		cur.ID = id
+	}
+
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(c string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, err
+	}
+	var cur pageCursor
+	err = json.Unmarshal(data, &cur)
+	if err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+func pageLink(r *http.Request, cursor string) string {
+	q := r.URL.Query()
+	q.Set("cursor", cursor)
+	return r.URL.Path + "?" + q.Encode()
+}
+
+func sortSignature(sortFields []SortField) string {
+	parts := make([]string, 0, len(sortFields))
+	for _, s := range sortFields {
+		parts = append(parts, s.Field+":"+sortDirection(s.Desc, false))
+	}
+	return strings.Join(parts, ",")
+}
+
+func sortDirection(desc, reverse bool) string {
+	if desc != reverse {
+		return "desc"
+	}
+	return "asc"
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// urlSecret — ключ подписи ссылок: FILE_URL_SECRET, по умолчанию JWT_SECRET
+func urlSecret() []byte {
+	secret := os.Getenv("FILE_URL_SECRET")
+	if secret == "" {
+		secret = os.Getenv("JWT_SECRET")
+	}
+	return []byte(secret)
+}
+
+func urlSignature(secret []byte, path string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignURL — подпись ссылки path, действующей до expires (HMAC-SHA256); проверяется VerifyURL
+func SignURL(path string, expires time.Time) (string, error) {
+	secret := urlSecret()
+	if len(secret) == 0 {
+		return "", ErrorHandler(errors.New("FILE_URL_SECRET is empty"), "Signed links are not configured")
+	}
+	return urlSignature(secret, path, expires.Unix()), nil
+}
+
+// VerifyURL — подпись ссылки совпадает и срок её действия (unix-время expires) не истёк; иначе ErrForbidden
+func VerifyURL(path, expires, signature string) error {
+	secret := urlSecret()
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || len(secret) == 0 || !hmac.Equal([]byte(signature), []byte(urlSignature(secret, path, exp))) {
+		return ErrorHandler(ErrForbidden, "Invalid link signature")
+	}
+	if time.Now().Unix() > exp {
+		return ErrorHandler(ErrForbidden, "Link has expired")
+	}
+	return nil
+}
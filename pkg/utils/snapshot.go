@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// FieldChange — значение поля до и после изменения
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// Snapshot — поля записи по json именам для истории изменений; поля export:"-" (пароли, токены) не сохраняются
+func Snapshot(model interface{}) map[string]interface{} {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	t := v.Type()
+
+	snap := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || f.Tag.Get("export") == "-" {
+			continue
+		}
+		snap[name] = exportValue(v.Field(i))
+	}
+	return snap
+}
+
+// Diff — изменившиеся поля между двумя состояниями записи (nil — записи не было);
+// служебные readonly поля (version, updatedAt) в diff не попадают
+func Diff(before, after interface{}) map[string]FieldChange {
+	var t reflect.Type
+	for _, m := range []interface{}{before, after} {
+		if m != nil {
+			t = reflect.TypeOf(m)
+		}
+	}
+	diff := make(map[string]FieldChange)
+	if t == nil {
+		return diff
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var from, to map[string]interface{}
+	if before != nil {
+		from = Snapshot(before)
+	}
+	if after != nil {
+		to = Snapshot(after)
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if isReadOnly(f) {
+			continue
+		}
+		a, aok := from[name]
+		b, bok := to[name]
+		if !aok && !bok {
+			continue
+		}
+		ja, _ := json.Marshal(a)
+		jb, _ := json.Marshal(b)
+		if string(ja) != string(jb) {
+			diff[name] = FieldChange{From: a, To: b}
+		}
+	}
+	return diff
+}
+
+// RestoreSnapshot — обратное к Snapshot: заполняет модель из сохранённого снимка;
+// sql.Null* поля восстанавливаются из простых значений, null оставляет поле пустым
+func RestoreSnapshot(data []byte, model interface{}) error {
+	var snap map[string]json.RawMessage
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	v := reflect.ValueOf(model).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		raw, ok := snap[name]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		field := v.Field(i)
+		target := field.Addr().Interface()
+		if _, isScanner := target.(sql.Scanner); isScanner && field.Kind() == reflect.Struct {
+			var value interface{}
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return err
+			}
+			if err := target.(sql.Scanner).Scan(value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseAsOf — момент времени из ?asOf= (RFC3339, дата или "2006-01-02 15:04:05"); ok=false, если параметра нет
+func ParseAsOf(r *http.Request) (t time.Time, ok bool, err error) {
+	raw := r.URL.Query().Get("asOf")
+	if raw == "" {
+		return time.Time{}, false, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		t, err = time.ParseInLocation(layout, raw, time.Local)
+		if err == nil {
+			if layout == time.DateOnly {
+				// дата целиком: состояние на конец дня
+				t = t.AddDate(0, 0, 1).Add(-time.Microsecond)
+			}
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, ErrorHandler(ErrInvalidAsOf, "asOf must be RFC3339, YYYY-MM-DD or YYYY-MM-DD HH:MM:SS")
+}
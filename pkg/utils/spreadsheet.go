@@ -0,0 +1,264 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeCSV  = "text/csv"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// MaxSpreadsheetSize — наибольший файл импорта
+	MaxSpreadsheetSize = 20 << 20
+	// xlsxMaxUnpacked — сколько XML всего можно распаковать из одного XLSX
+	xlsxMaxUnpacked = 5 * MaxSpreadsheetSize
+	// xlsxMaxRows и xlsxMaxColumns — размеры листа Excel; ссылки за их пределами считаются повреждёнными
+	xlsxMaxRows    = 1 << 20
+	xlsxMaxColumns = 1 << 14
+)
+
+// ReadSpreadsheet — читает CSV или XLSX (первый лист) в строки; формат определяется по содержимому
+func ReadSpreadsheet(data []byte) ([][]string, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return readXLSX(data)
+	}
+	return readCSV(data)
+}
+
+// readCSV — CSV с запятой или точкой с запятой (Excel в русской локали), BOM отбрасывается
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, ErrorHandler(ErrUnsupportedFile, "Invalid CSV: "+err.Error())
+	}
+	return rows, nil
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxText — текст ячейки: обычный <t> или набор фрагментов форматированного текста <r><t>
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.R {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Index int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX — минимальный разбор Office Open XML: общие строки, inline-строки, числа и булевы значения
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, ErrorHandler(ErrUnsupportedFile, "Invalid XLSX archive")
+	}
+	x := &xlsxArchive{files: make(map[string]*zip.File, len(archive.File)), remaining: xlsxMaxUnpacked}
+	for _, f := range archive.File {
+		x.files[f.Name] = f
+	}
+
+	sheetPath, err := x.firstSheet()
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := x.files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := x.decode(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, item := range sst.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	f, ok := x.files[sheetPath]
+	if !ok {
+		return nil, ErrorHandler(ErrUnsupportedFile, "XLSX worksheet "+sheetPath+" not found")
+	}
+	var sheet xlsxSheet
+	if err := x.decode(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		if row.Index > xlsxMaxRows {
+			return nil, ErrorHandler(ErrUnsupportedFile, "XLSX row "+strconv.Itoa(row.Index)+" is beyond the sheet limit")
+		}
+		// пустые строки в XLSX не хранятся — восстанавливаем нумерацию, чтобы номера строк совпадали с Excel
+		for row.Index > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+		var cells []string
+		for i, c := range row.Cells {
+			col := i
+			if n := xlsxColumn(c.Ref); n >= 0 {
+				col = n
+			}
+			if col >= xlsxMaxColumns {
+				return nil, ErrorHandler(ErrUnsupportedFile, "XLSX cell "+c.Ref+" is beyond the sheet limit")
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			switch c.Type {
+			case "s":
+				idx, err := strconv.Atoi(c.Value)
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return nil, ErrorHandler(ErrUnsupportedFile, "Invalid shared string in cell "+c.Ref)
+				}
+				cells[col] = shared[idx]
+			case "inlineStr":
+				cells[col] = c.Inline.String()
+			case "b":
+				cells[col] = strconv.FormatBool(c.Value == "1")
+			default:
+				cells[col] = c.Value
+			}
+		}
+		rows = append(rows, cells)
+	}
+	return rows, nil
+}
+
+// xlsxArchive — части XLSX и остаток лимита на распакованный XML, общий для всех частей
+type xlsxArchive struct {
+	files     map[string]*zip.File
+	remaining int64
+}
+
+// firstSheet — путь к первому листу книги через workbook.xml и его связи
+func (x *xlsxArchive) firstSheet() (string, error) {
+	wbFile, ok := x.files["xl/workbook.xml"]
+	relsFile, relsOk := x.files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relsOk {
+		return "", ErrorHandler(ErrUnsupportedFile, "XLSX workbook not found")
+	}
+	var wb xlsxWorkbook
+	if err := x.decode(wbFile, &wb); err != nil {
+		return "", err
+	}
+	var rels xlsxRelationships
+	if err := x.decode(relsFile, &rels); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", ErrorHandler(ErrUnsupportedFile, "XLSX workbook has no sheets")
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID == wb.Sheets[0].RelID {
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+	return "", ErrorHandler(ErrUnsupportedFile, "XLSX sheet relationship not found")
+}
+
+// decode — разбирает XML части архива; распакованные данные всех частей вместе не больше xlsxMaxUnpacked
+func (x *xlsxArchive) decode(f *zip.File, v interface{}) error {
+	if f.UncompressedSize64 > uint64(x.remaining) {
+		return xlsxTooLarge()
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ErrorHandler(err, "Error opening "+f.Name)
+	}
+	defer rc.Close()
+	counter := &countingReader{r: io.LimitReader(rc, x.remaining+1)}
+	err = xml.NewDecoder(counter).Decode(v)
+	x.remaining -= counter.n
+	if x.remaining < 0 {
+		return xlsxTooLarge()
+	}
+	if err != nil {
+		return ErrorHandler(ErrUnsupportedFile, "Invalid XLSX part "+f.Name)
+	}
+	return nil
+}
+
+func xlsxTooLarge() error {
+	return ErrorHandler(ErrUnsupportedFile, fmt.Sprintf("XLSX unpacks to more than %d MB", xlsxMaxUnpacked>>20))
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// xlsxColumn — номер столбца (с нуля) по ссылке на ячейку: "C7" -> 2
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		if col > xlsxMaxColumns {
+			// дальше только переполнение; столбец всё равно за пределами листа
+			break
+		}
+	}
+	return col - 1
+}
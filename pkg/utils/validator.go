@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError — ошибка валидации одного поля; Index (или ID записи) заполняется для элементов bulk-запросов
+type FieldError struct {
+	Index   *int   `json:"index,omitempty"`
+	ID      *int   `json:"id,omitempty"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError — набор ошибок валидации, возвращается клиенту целиком
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		if fe.Index != nil {
+			msgs = append(msgs, fmt.Sprintf("[%d].%s: %s", *fe.Index, fe.Field, fe.Message))
+		} else if fe.ID != nil {
+			msgs = append(msgs, fmt.Sprintf("id %d.%s: %s", *fe.ID, fe.Field, fe.Message))
+		} else {
+			msgs = append(msgs, fe.Field+": "+fe.Message)
+		}
+	}
+	return "Validation failed: " + strings.Join(msgs, "; ")
+}
+
+var emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+var (
+	patternMu    sync.Mutex
+	patternCache = map[string]*regexp.Regexp{}
+)
+
+// ValidateStruct — проверяет поля структуры по тегам validate:"required,email,min=2,max=50,oneof=a b,pattern=^...$"
+// Пустое значение без required остальные правила не проверяет
+func ValidateStruct(model interface{}) []FieldError {
+	v := reflect.ValueOf(model)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	t := v.Type()
+
+	var errs []FieldError
+	for i := 0; i < t.NumField(); i++ {
+		rules := t.Field(i).Tag.Get("validate")
+		if rules == "" || rules == "-" {
+			continue
+		}
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		if msg := checkRules(v.Field(i), rules); msg != "" {
+			errs = append(errs, FieldError{Field: name, Message: msg})
+		}
+	}
+	return errs
+}
+
+// ValidateSlice — валидирует каждый элемент bulk-запроса и проставляет индекс элемента в ошибках
+func ValidateSlice(items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return Validate(items)
+	}
+	var errs []FieldError
+	for i := 0; i < v.Len(); i++ {
+		for _, fe := range ValidateStruct(v.Index(i).Interface()) {
+			idx := i
+			fe.Index = &idx
+			errs = append(errs, fe)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+// Validate — валидирует одну структуру, возвращает *ValidationError или nil
+func Validate(model interface{}) error {
+	errs := ValidateStruct(model)
+	if len(errs) > 0 {
+		return &ValidationError{Errors: errs}
+	}
+	return nil
+}
+
+func checkRules(field reflect.Value, rules string) string {
+	ruleList := strings.Split(rules, ",")
+	if n, isNumber, set := fieldNumber(field); isNumber {
+		return checkNumberRules(n, set, ruleList)
+	}
+	value, isString := fieldString(field)
+	if !isString {
+		return ""
+	}
+
+	required := false
+	for _, rule := range ruleList {
+		if rule == "required" {
+			required = true
+		}
+	}
+	if strings.TrimSpace(value) == "" {
+		if required {
+			return "is required"
+		}
+		return ""
+	}
+
+	for _, rule := range ruleList {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "email":
+			if !emailRegexp.MatchString(value) {
+				return "must be a valid email address"
+			}
+		case "min":
+			n, _ := strconv.Atoi(arg)
+			if utf8.RuneCountInString(value) < n {
+				return fmt.Sprintf("must be at least %d characters", n)
+			}
+		case "max":
+			n, _ := strconv.Atoi(arg)
+			if utf8.RuneCountInString(value) > n {
+				return fmt.Sprintf("must be at most %d characters", n)
+			}
+		case "oneof":
+			allowed := strings.Fields(arg)
+			ok := false
+			for _, a := range allowed {
+				if a == value {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return "must be one of: " + strings.Join(allowed, ", ")
+			}
+		case "pattern":
+			if !compilePattern(arg).MatchString(value) {
+				return "has invalid format"
+			}
+		}
+	}
+	return ""
+}
+
+// checkNumberRules — для чисел required значит «не ноль», а min/max задают границы значения, а не длину
+func checkNumberRules(n float64, set bool, ruleList []string) string {
+	for _, rule := range ruleList {
+		if rule == "required" && (!set || n == 0) {
+			return "is required"
+		}
+	}
+	if !set {
+		return ""
+	}
+	for _, rule := range ruleList {
+		name, arg, _ := strings.Cut(rule, "=")
+		limit, _ := strconv.ParseFloat(arg, 64)
+		switch name {
+		case "min":
+			if n < limit {
+				return "must be at least " + arg
+			}
+		case "max":
+			if n > limit {
+				return "must be at most " + arg
+			}
+		}
+	}
+	return ""
+}
+
+// fieldNumber — значение числового поля (int или float); set=false для nil указателя
+func fieldNumber(field reflect.Value) (n float64, isNumber bool, set bool) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), true, true
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), true, true
+	case reflect.Ptr:
+		if _, isNumber, _ := fieldNumber(reflect.Zero(field.Type().Elem())); !isNumber {
+			return 0, false, false
+		}
+		if field.IsNil() {
+			return 0, true, false
+		}
+		return fieldNumber(field.Elem())
+	}
+	return 0, false, false
+}
+
+func fieldString(field reflect.Value) (string, bool) {
+	switch field.Kind() {
+	case reflect.String:
+		return field.String(), true
+	case reflect.Ptr:
+		if field.IsNil() {
+			return "", true
+		}
+		return fieldString(field.Elem())
+	}
+	return "", false
+}
+
+func compilePattern(pattern string) *regexp.Regexp {
+	patternMu.Lock()
+	defer patternMu.Unlock()
+	re, ok := patternCache[pattern]
+	if !ok {
+		re = regexp.MustCompile(pattern)
+		patternCache[pattern] = re
+	}
+	return re
+}